@@ -8,12 +8,16 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
@@ -21,8 +25,14 @@ import (
 	"github.com/yusuke-hoguro/BlogApi/internal/app"
 	"github.com/yusuke-hoguro/BlogApi/internal/config"
 	"github.com/yusuke-hoguro/BlogApi/internal/db"
+	"github.com/yusuke-hoguro/BlogApi/internal/errreport"
+	"github.com/yusuke-hoguro/BlogApi/internal/handler"
+	"github.com/yusuke-hoguro/BlogApi/internal/lifecycle"
 	"github.com/yusuke-hoguro/BlogApi/internal/middleware"
+	"github.com/yusuke-hoguro/BlogApi/internal/oidc"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository"
 	"github.com/yusuke-hoguro/BlogApi/internal/router"
+	"github.com/yusuke-hoguro/BlogApi/internal/service"
 	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
 	"golang.org/x/sync/errgroup"
 
@@ -37,59 +47,182 @@ func main() {
 }
 
 func runServer() error {
-	// DB接続を実施
-	conn, err := db.ConnectDB()
+	// 設定を読み込む(既定値 < 設定ファイル < 環境変数 < コマンドライン引数)
+	cfg, printConfig, err := loadConfig()
 	if err != nil {
-		return fmt.Errorf("DB接続失敗: %w", err)
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return fmt.Errorf("設定の読み込み失敗: %w", err)
+	}
+	if printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			return err
+		}
+		return cfg.Validate()
 	}
-	defer conn.Close()
-	// ポート取得
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("設定が不正: %w", err)
 	}
+	middleware.SetJWTKey([]byte(cfg.Auth.JWTSecret))
+
+	// 起動したコンポーネントを登録し、シャットダウン時に登録と逆の順に停止する
+	lc := lifecycle.NewManager()
+	// 起動の途中でエラーになった場合も登録済みのコンポーネントを停止する(シャットダウン済みの場合は何もしない)
+	defer lc.Shutdown(context.Background())
 
 	// シグナルを受け取るためのコンテキストを作成
 	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
+	// SIGHUPの既定の動作はプロセスの終了のため、起動の途中で受け取っても終了しないように最初に登録する
+	// (起動が完了するまでに受け取ったSIGHUPは、再読み込みのgoroutineの開始後に1回だけ処理する)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// DB接続を実施(DBの起動を待つ間もシグナルで中断できるようにする)
+	conn, err := db.ConnectDB(sigCtx, cfg.Database)
+	if err != nil {
+		return fmt.Errorf("DB接続失敗: %w", err)
+	}
+	lc.Register(lifecycle.Hook{Name: "database", Timeout: cfg.Server.ShutdownPhases.DB, Stop: func(ctx context.Context) error {
+		return conn.Close()
+	}})
+	// migration.on_startup が有効な場合はリクエストを受け付ける前にマイグレーションを適用する
+	if cfg.Migration.OnStartup {
+		if err := migrateOnStartup(conn, cfg.Migration); err != nil {
+			return err
+		}
+	}
 
 	// errgroupでgoroutineのエラー管理とキャンセル伝播を行う
 	g, ctx := errgroup.WithContext(sigCtx)
 
-	// 監視ワーカープールの作成と起動
-	auditPool := workerpool.NewAuditWorkerPool(config.WorkerCount, config.QueueSize)
+	// リードレプリカが設定されている場合は読み取りをレプリカに振り分ける
+	dbRouter, err := newReplicaRouter(conn, cfg.Database)
+	if err != nil {
+		return err
+	}
+	dbRouter.Start()
+	lc.Register(lifecycle.Hook{Name: "read_replicas", Timeout: cfg.Server.ShutdownPhases.DB, Stop: lifecycle.StopFunc(dbRouter.Close)})
+
+	// サービスのインスタンスを作成
+	services := app.NewServices(dbRouter, cfg)
+
+	// Webhookの配信ワーカープールを起動する(アウトボックスのリレーより後に停止させる)
+	// 停止の期限を過ぎてキューに残った配信は、次回の起動後に再送の定期処理で配信する
+	services.Webhook.Start()
+	lc.Register(lifecycle.Hook{Name: "webhook_pool", Timeout: cfg.Server.ShutdownPhases.Workers, Stop: lifecycle.StopFunc(services.Webhook.Stop)})
+
+	// アウトボックスのリレーを起動する(いいね・コメント・フォローは通知に、投稿・コメントはWebhookにする)
+	services.Outbox.RegisterConsumer("notifications", services.Notification.HandleOutboxEvent)
+	services.Outbox.RegisterConsumer("webhooks", services.Webhook.HandleOutboxEvent)
+	services.Outbox.Start()
+	lc.Register(lifecycle.Hook{Name: "outbox_relay", Timeout: cfg.Server.ShutdownPhases.Workers, Stop: lifecycle.StopFunc(services.Outbox.Stop)})
+
+	// 監視ワーカープールの作成と起動(監視イベントはDBにも保存する)
+	auditPool := workerpool.NewAuditWorkerPool(cfg.Audit.WorkerCount, cfg.Audit.QueueSize)
+	auditPool.AddHandler(services.Audit.Record)
 	auditPool.Start()
-	// サーバーがシャットダウンする際にワーカープールも停止するようにする
-	defer auditPool.Stop()
+	// サーバーがシャットダウンする際にワーカープールも停止するようにする(期限までに処理できなかったイベントは破棄して件数を報告する)
+	lc.Register(lifecycle.Hook{Name: "audit_pool", Timeout: cfg.Server.ShutdownPhases.Audit, Stop: func(ctx context.Context) error {
+		if dropped, err := auditPool.Shutdown(ctx); err != nil {
+			return fmt.Errorf("%d queued audit events dropped: %w", dropped, err)
+		}
+		return nil
+	}})
+
+	// SIGHUPで再読み込みした設定を反映する(reload タグの付いた項目のみ)
+	// 入力の検証に使う文字数の上限・下限を設定する
+	handler.SetValidationLimits(cfg.Validation)
+	// WebSocketの接続元はCORSと同じ許可リストで確認する
+	handler.SetWebSocketAllowedOrigins(cfg.CORS.AllowedOrigins)
+	store := config.NewStore(cfg)
+	store.OnReload(func(old, current *config.Config) {
+		if old.Audit.WorkerCount != current.Audit.WorkerCount {
+			auditPool.Resize(current.Audit.WorkerCount)
+		}
+		if old.Validation != current.Validation {
+			handler.SetValidationLimits(current.Validation)
+		}
+		if !slices.Equal(old.CORS.AllowedOrigins, current.CORS.AllowedOrigins) {
+			handler.SetWebSocketAllowedOrigins(current.CORS.AllowedOrigins)
+		}
+	})
+
+	// アカウント削除予約・データエクスポート・ライブイベントの履歴・Webhookの再送・配信済みのアウトボックスを定期的に処理する
+	scheduler := workerpool.NewScheduler(
+		workerpool.PeriodicJob{Name: "account_deletions", Interval: config.AccountJobInterval, Run: services.Account.ProcessDueDeletions},
+		workerpool.PeriodicJob{Name: "data_exports", Interval: config.AccountJobInterval, Run: services.Account.ProcessPendingExports},
+		workerpool.PeriodicJob{Name: "post_event_history", Interval: cfg.Events.HistoryTTL, Run: services.PostEvent.PruneHistory},
+		workerpool.PeriodicJob{Name: "webhook_retries", Interval: cfg.Webhook.RetryInterval, Run: services.Webhook.ProcessDueDeliveries},
+		workerpool.PeriodicJob{Name: "outbox_cleanup", Interval: cfg.Outbox.CleanupInterval, Run: services.Outbox.PrunePublished},
+	)
+	// シグナルで ctx がキャンセルされても実行中のジョブを止めず、停止は lifecycle の scheduler フェーズ(HTTPサーバーの停止後)で行う
+	scheduler.Start(context.WithoutCancel(ctx))
+	lc.Register(lifecycle.Hook{Name: "scheduler", Timeout: cfg.Server.ShutdownPhases.Workers, Stop: lifecycle.StopFunc(scheduler.Stop)})
 
 	// ルーターの設定
 	r := mux.NewRouter()
-	// サービスのインスタンスを作成
-	services := app.NewServices(conn)
+	// 外部IDプロバイダーを登録する
+	registerOIDCProviders(services.OAuth, cfg.OIDC)
+	// readinessで確認する依存先(DB・スキーマのバージョン・監視ワーカープール)
+	health := newHealthService(conn, cfg, auditPool)
 	// ルートの登録(監視ワーカープールを渡す)
-	router.RegisterRoutes(r, conn, auditPool, services)
+	router.RegisterRoutes(r, conn, auditPool, services, health, cfg)
+	if cfg.Server.DebugEndpoints {
+		router.RegisterDebugRoutes(r, conn)
+	}
+	// panicから復帰したリクエストをエラー監視サービスに報告する(HTTPサーバーの停止後に送信中の報告を待つ)
+	reporter, err := newErrorReporter(cfg.ErrorReporting)
+	if err != nil {
+		return err
+	}
+	lc.Register(lifecycle.Hook{Name: "error_reporter", Timeout: cfg.Server.ShutdownPhases.Report, Stop: reporter.Flush})
+	// AuthMiddlewareでAPIキーを検証できるようにする
+	handler := middleware.WithAPIKeyAuthenticator(services.APIKey)(r)
+	// 削除済みのユーザーのJWTを拒否できるようにする
+	handler = middleware.WithUserVerifier(services.User)(handler)
 	// CORSミドルウェアを適用
-	handler := middleware.CorsMiddleware(r)
+	handler = middleware.CorsMiddleware(r, func() middleware.CORSOptions {
+		cors := store.Current().CORS
+		return middleware.CORSOptions{AllowedOrigins: cors.AllowedOrigins, ExposedHeaders: cors.ExposedHeaders, MaxAge: cors.MaxAge}
+	})(handler)
 	// タイムアウトミドルウェアを適用(戻り値が関数なので（handler）をつけて実行する)
-	handler = middleware.TimeoutMiddleware(10 * time.Second)(handler)
+	handler = middleware.DynamicTimeoutMiddleware(r, func() time.Duration { return store.Current().Server.RequestTimeout })(handler)
+	// panicから復帰して500を返すミドルウェアを適用(CORS・タイムアウトを含むすべての処理のpanicを対象にする)
+	handler = middleware.RecoverMiddleware(reporter, auditPool)(handler)
+	// リクエストIDを設定するミドルウェアを適用(ログ・エラーの報告で使うため最も外側にする)
+	handler = middleware.RequestIDMiddleware(handler)
 	// HTTPサーバーの設定
 	srv := &http.Server{
-		Addr:              ":" + port,
+		Addr:              ":" + cfg.Server.Port,
 		Handler:           handler,
-		ReadTimeout:       10 * time.Second,
-		WriteTimeout:      10 * time.Second,
-		ReadHeaderTimeout: 5 * time.Second,
-		IdleTimeout:       60 * time.Second,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
 	}
+	// シャットダウン時は接続中のライブイベントのストリームとWebSocketを終了させる
+	// (WebSocketはhijackされた接続のためShutdownの完了待ちの対象にならない)
+	srv.RegisterOnShutdown(services.PostEvent.Close)
+	srv.RegisterOnShutdown(services.Realtime.Close)
+	// 最初に新しいリクエストの受け付けを止め、処理中のリクエストが終わるのを待つ
+	lc.Register(lifecycle.Hook{Name: "http_server", Timeout: cfg.Server.ShutdownTimeout, Stop: srv.Shutdown})
 
 	// サーバー起動を起動するgoroutine
 	g.Go(func() error {
 		return runHTTPServer(srv)
 	})
 
+	// SIGHUPを受け取ったら設定を再読み込みするgoroutine
+	g.Go(func() error {
+		return reloadOnSignal(ctx, hup, store)
+	})
+
 	// コンテキストがキャンセルされたらサーバーをシャットダウンするgoroutine
 	g.Go(func() error {
-		return shutdownOnContextDone(ctx, srv)
+		return shutdownOnContextDone(ctx, lc, health, cfg.Server)
 	})
 
 	// いずれかのgoroutineがエラーを返すのを待つ
@@ -100,6 +233,119 @@ func runServer() error {
 	return nil
 }
 
+// コマンドライン引数を解析して設定を読み込む(設定の再読み込みでも同じ引数で読み込み直す)
+func loadConfig() (*config.Config, bool, error) {
+	flags := flag.NewFlagSet("api", flag.ContinueOnError)
+	printConfig := flags.Bool("print-config", false, "秘密の値を伏せた設定を表示して終了する")
+	cfg, err := config.Load(flags, os.Args[1:])
+	if err != nil {
+		return nil, false, err
+	}
+	return cfg, *printConfig, nil
+}
+
+// SIGHUPを受け取るたびに設定ファイルを読み込み直して反映する
+// 読み込めない・不正な設定の場合はログに出して実行中の設定のまま処理を続ける
+func reloadOnSignal(ctx context.Context, hup <-chan os.Signal, store *config.Store) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			reloadConfig(store)
+		}
+	}
+}
+
+// 設定を読み込み直して反映する
+func reloadConfig(store *config.Store) {
+	next, _, err := loadConfig()
+	if err != nil {
+		log.Printf("config reload rejected: %v", err)
+		return
+	}
+	applied, ignored, err := store.Reload(next)
+	if err != nil {
+		log.Printf("config reload rejected: %v", err)
+		return
+	}
+	for _, change := range ignored {
+		log.Printf("config reload: %s requires restart (not applied)", change)
+	}
+	for _, change := range applied {
+		log.Printf("config reloaded: %s", change)
+	}
+	if len(applied) == 0 {
+		log.Printf("config reload: no runtime settings changed")
+	}
+}
+
+// リードレプリカに振り分けるDBを作成する
+// 書き込んだユーザーの読み取りは database.read_after_write_window の間プライマリで行う
+func newReplicaRouter(primary *sql.DB, cfg config.DatabaseConfig) (*repository.ReplicaRouter, error) {
+	var replicas []*sql.DB
+	for _, replicaCfg := range cfg.Replicas() {
+		replica, err := db.OpenDB(replicaCfg)
+		if err != nil {
+			for _, opened := range replicas {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("リードレプリカの設定が不正: %w", err)
+		}
+		replicas = append(replicas, replica)
+	}
+	if len(replicas) > 0 {
+		log.Printf("read replicas configured: %d", len(replicas))
+	}
+	userID := func(ctx context.Context) (int, bool) {
+		id, ok := ctx.Value(middleware.UserIDKey).(int)
+		return id, ok
+	}
+	return repository.NewReplicaRouter(primary, replicas, userID, cfg.ReadAfterWriteWindow, cfg.ReplicaHealthInterval), nil
+}
+
+// 起動時にマイグレーションを適用する
+// 複数のレプリカが同時に起動しても cmd/migrate と同じアドバイザリロックで1つずつ実行される
+func migrateOnStartup(conn *sql.DB, cfg config.MigrationConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	migrator := db.NewMigrator(conn, db.MigrationSource(cfg.Dir), os.Stdout, false).WithLock(db.NewLockOptions(cfg))
+	if err := migrator.Up(ctx, 0); err != nil {
+		return fmt.Errorf("startup migration failed: %w", err)
+	}
+	return nil
+}
+
+// エラー監視サービスへ報告する Reporter を作成する(DSNが設定されていない場合は報告しない)
+func newErrorReporter(cfg config.ErrorReportingConfig) (errreport.Reporter, error) {
+	if cfg.DSN == "" {
+		return errreport.NopReporter{}, nil
+	}
+	reporter, err := errreport.NewSentryReporter(cfg.DSN, cfg.Environment, nil)
+	if err != nil {
+		return nil, fmt.Errorf("エラー報告の設定が不正: %w", err)
+	}
+	log.Printf("error reporting enabled")
+	return reporter, nil
+}
+
+// readinessで確認する依存先を設定したヘルスチェック用サービスを作成する
+// スキーマのバージョンはバイナリに含まれる最新のマイグレーションまで適用されているか確認する
+// 依存先はそれぞれ health.check_timeout で打ち切る
+func newHealthService(conn *sql.DB, cfg *config.Config, auditPool *workerpool.AuditWorkerPool) *service.HealthService {
+	migrator := db.NewMigrator(conn, db.MigrationSource(cfg.Migration.Dir), io.Discard, false)
+	return service.NewHealthService(cfg.Health.CheckTimeout,
+		service.HealthCheck{Name: "database", Check: conn.PingContext},
+		service.HealthCheck{Name: "migrations", Check: migrator.CheckVersion},
+		service.HealthCheck{Name: "audit_pool", Check: func(ctx context.Context) error {
+			if !auditPool.Running() {
+				return errors.New("audit worker pool is not running")
+			}
+			return nil
+		}},
+	)
+}
+
 // HTTPサーバーを起動する
 func runHTTPServer(srv *http.Server) error {
 	log.Printf("Server started at %s", srv.Addr)
@@ -110,19 +356,37 @@ func runHTTPServer(srv *http.Server) error {
 }
 
 // コンテキストがキャンセルされたらサーバーをシャットダウンする
-func shutdownOnContextDone(ctx context.Context, srv *http.Server) error {
+// 先にreadinessを失敗させ、server.drain_delay の間はロードバランサーが振り分けを止めるのを待ってからリクエストの受け付けを止める
+// その後、HTTPサーバー・定期処理・ワーカー・DBの順にそれぞれの期限で停止する
+func shutdownOnContextDone(ctx context.Context, lc *lifecycle.Manager, health *service.HealthService, cfg config.ServerConfig) error {
 	<-ctx.Done()
 	log.Printf("Shutdown signal received: %v", ctx.Err())
-
-	// shutdownのタイムアウトを設定
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	health.SetDraining()
+	if cfg.DrainDelay > 0 {
+		log.Printf("Draining for %s before shutdown", cfg.DrainDelay)
+		time.Sleep(cfg.DrainDelay)
+	}
 
 	// サーバーをシャットダウン
 	log.Printf("Server shutting down...")
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		return fmt.Errorf("server shutdown failed: %w", err)
+	if err := lc.Shutdown(context.Background()).Err(); err != nil {
+		return fmt.Errorf("server shutdown incomplete: %w", err)
 	}
 	log.Println("Server shutdown complete")
 	return nil
 }
+
+// 設定の外部IDプロバイダーを登録する
+func registerOIDCProviders(oauthService *service.OAuthService, providers []config.OIDCProvider) {
+	for _, provider := range providers {
+		oauthService.RegisterProvider(oidc.NewClient(oidc.ProviderConfig{
+			Name:         provider.Name,
+			Issuer:       provider.Issuer,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  provider.RedirectURL,
+			Scopes:       provider.Scopes,
+		}, nil))
+		log.Printf("OIDC provider registered: %s", provider.Name)
+	}
+}
@@ -2,13 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"text/tabwriter"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
 	"github.com/yusuke-hoguro/BlogApi/internal/db"
 
 	_ "github.com/lib/pq"
@@ -21,52 +25,157 @@ func main() {
 	}
 }
 
+// 使い方
+const usage = `usage: migrate [-config FILE] [-dir DIR] [-dry-run] [-lock-timeout DURATION] [-lock-policy wait|skip] <command> [args]
+
+commands:
+  up [N]         未適用のマイグレーションを適用する(Nを指定した場合はN件まで)
+  down [N]       適用済みのマイグレーションを新しい順にロールバックする(既定は1件)
+  status         マイグレーションの適用状況を表示する
+  redo           最後に適用したマイグレーションをロールバックして適用し直す
+  verify         DBのスキーマを適用済みのマイグレーションから期待されるスキーマと比較して差分を表示する
+  create <name>  新しいマイグレーションファイル(up/down)を作成する(-dir 省略時は sql/migrations)
+
+コマンドを省略した場合は up を実行する
+マイグレーションはバイナリに埋め込まれたものを使う(-dir を指定した場合はそのディレクトリから読み込む)
+up/down/redo は他のプロセスと同時に実行しないようにアドバイザリロックを取得してから実行する
+DB接続・タイムアウト・ロックの設定はAPIサーバーと同じ設定ファイル・環境変数から読み込む
+`
+
 func run() error {
 	// .envファイルを読み込む
 	if err := godotenv.Load(); err != nil {
 		log.Printf("failed to load .env: %v", err)
 	}
 
-	// DBに接続する
-	conn, err := db.ConnectDB()
+	// コマンドライン引数を解析して設定を読み込む
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dir := flags.String("dir", "", "マイグレーションファイルのディレクトリ(省略時は migration.dir、未設定ならバイナリに埋め込まれたマイグレーション)")
+	dryRun := flags.Bool("dry-run", false, "実行するSQLを表示するだけでDBを変更しない")
+	flags.Usage = func() { fmt.Fprint(flags.Output(), usage) }
+	cfg, err := config.Load(flags, os.Args[1:])
 	if err != nil {
-		return fmt.Errorf("DB接続に失敗: %w", err)
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	command, args := "up", flags.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
 	}
-	defer conn.Close()
 
-	// マイグレーション実行時のタイムアウト時間を取得
-	timeout, err := migrationTimeout()
-	if err != nil {
-		return err
+	// ファイルの作成はDBに接続せずに実行する
+	if command == "create" {
+		if len(args) != 1 {
+			return fmt.Errorf("create requires a migration name\n%s", usage)
+		}
+		createDir := *dir
+		if createDir == "" {
+			createDir = "sql/migrations"
+		}
+		upPath, downPath, err := db.CreateMigration(createDir, args[0])
+		if err != nil {
+			return err
+		}
+		log.Printf("created %s and %s", upPath, downPath)
+		return nil
 	}
 
-	// 設定されたタイムアウトで自動キャンセルするコンテキストを作成
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	// マイグレーションに必要な設定だけを検証する
+	if err := errors.Join(cfg.Database.Validate(), cfg.Migration.Validate()); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if *dir != "" {
+		cfg.Migration.Dir = *dir
+	}
+
+	// 設定されたタイムアウトで自動キャンセルするコンテキストを作成(DBの起動を待つ時間も含める)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Migration.Timeout)
 	defer cancel()
 
-	// DBマイグレーションを実行する
-	if err := db.RunMigrations(ctx, conn, "sql/migrations"); err != nil {
-		return fmt.Errorf("migration failed: %w", err)
+	// DBに接続する
+	conn, err := db.ConnectDB(ctx, cfg.Database)
+	if err != nil {
+		return fmt.Errorf("DB接続に失敗: %w", err)
 	}
-	log.Println("migration completed")
+	defer conn.Close()
+
+	// 指定されたコマンドを実行する
+	migrator := db.NewMigrator(conn, db.MigrationSource(cfg.Migration.Dir), os.Stdout, *dryRun).WithLock(db.NewLockOptions(cfg.Migration))
+	switch command {
+	case "up":
+		limit, err := countArg(args, 0)
+		if err != nil {
+			return err
+		}
+		if err := migrator.Up(ctx, limit); err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+	case "down":
+		n, err := countArg(args, 1)
+		if err != nil {
+			return err
+		}
+		if err := migrator.Down(ctx, n); err != nil {
+			return fmt.Errorf("rollback failed: %w", err)
+		}
+	case "redo":
+		if err := migrator.Redo(ctx); err != nil {
+			return fmt.Errorf("redo failed: %w", err)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("status failed: %w", err)
+		}
+		printStatus(statuses)
+		return nil
+	case "verify":
+		drifts, err := migrator.VerifySchema(ctx)
+		if err != nil {
+			return fmt.Errorf("verify failed: %w", err)
+		}
+		for _, drift := range drifts {
+			fmt.Println(drift)
+		}
+		if len(drifts) > 0 {
+			return fmt.Errorf("schema drift detected: %d differences", len(drifts))
+		}
+		log.Printf("schema matches the applied migrations")
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%s", command, usage)
+	}
+	log.Printf("migration %s completed", command)
 	return nil
 }
 
-// DBマイグレーションのタイムアウト時間を取得する
-func migrationTimeout() (time.Duration, error) {
-	const defaultTimeoutSeconds = 300
-	// 環境変数からマイグレーション実行のタイムアウト時間を取得する
-	value := os.Getenv("MIGRATION_TIMEOUT_SECONDS")
-	if value == "" {
-		return time.Duration(defaultTimeoutSeconds) * time.Second, nil
+// 件数の引数を取得する(省略した場合は既定値を返す)
+func countArg(args []string, defaultValue int) (int, error) {
+	if len(args) == 0 {
+		return defaultValue, nil
 	}
-	// 取得した文字列を秒数に変換する
-	seconds, err := strconv.Atoi(value)
-	if err != nil {
-		return 0, fmt.Errorf("invalid MIGRATION_TIMEOUT_SECONDS: %w", err)
+	if len(args) > 1 {
+		return 0, fmt.Errorf("too many arguments: %v", args)
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid number of migrations: %s", args[0])
 	}
-	if seconds <= 0 {
-		return 0, fmt.Errorf("MIGRATION_TIMEOUT_SECONDS must be positive")
+	return n, nil
+}
+
+// マイグレーションの適用状況を表形式で出力する
+func printStatus(statuses []db.MigrationStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "-"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.Version, status.Name, status.State, appliedAt)
 	}
-	return time.Duration(seconds) * time.Second, nil
+	w.Flush()
 }
@@ -10,10 +10,12 @@ require (
 require (
 	github.com/golang-jwt/jwt v3.2.2+incompatible
 	github.com/gorilla/mux v1.8.1
+	github.com/gorilla/websocket v1.5.3
 	github.com/joho/godotenv v1.5.1
 	github.com/swaggo/http-swagger v1.3.4
 	github.com/swaggo/swag v1.16.6
 	golang.org/x/sync v0.22.0
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
@@ -28,5 +30,4 @@ require (
 	golang.org/x/mod v0.17.0 // indirect
 	golang.org/x/net v0.56.0 // indirect
 	golang.org/x/tools v0.21.1-0.20240508182429-e35e4ccd0d2d // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
@@ -1,31 +1,73 @@
 package app
 
 import (
-	"database/sql"
-
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
+	"github.com/yusuke-hoguro/BlogApi/internal/pubsub"
 	"github.com/yusuke-hoguro/BlogApi/internal/repository"
 	"github.com/yusuke-hoguro/BlogApi/internal/service"
+	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
 )
 
 // サービスをまとめる構造体
 type Services struct {
-	Post    *service.PostService
-	Comment *service.CommentService
-	Like    *service.LikeService
-	User    *service.UserService
+	Post         *service.PostService
+	Comment      *service.CommentService
+	Like         *service.LikeService
+	User         *service.UserService
+	OAuth        *service.OAuthService
+	APIKey       *service.APIKeyService
+	Account      *service.AccountService
+	Audit        *service.AuditLogService
+	Follow       *service.FollowService
+	Notification *service.NotificationService
+	PostEvent    *service.PostEventService
+	Realtime     *service.RealtimeService
+	Webhook      *service.WebhookService
+	Outbox       *service.OutboxService
 }
 
 // サービスの初期化を行う関数
-func NewServices(db *sql.DB) *Services {
+// db にはプライマリの *sql.DB か、リードレプリカに振り分ける repository.ReplicaRouter を渡す
+func NewServices(db repository.DBExecutor, cfg *config.Config) *Services {
+	// 複数のリポジトリにまたがる処理は TxManager で1つのトランザクションにする
+	txManager := repository.NewTxManager(db)
 	postRepo := repository.NewPostRepository(db)
 	commentRepo := repository.NewCommentRepository(db)
 	likeRepo := repository.NewLikeRepository(db)
 	userRepo := repository.NewUserRepository(db)
+	identityRepo := repository.NewIdentityRepository(db)
+	oauthStateRepo := repository.NewOAuthStateRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	dataExportRepo := repository.NewDataExportRepository(db)
+	auditLogRepo := repository.NewAuditLogRepository(db)
+	followRepo := repository.NewFollowRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+
+	// 投稿のライブイベントとユーザーごとのWebSocketはプロセス内のHubで配信する
+	postEvent := service.NewPostEventService(pubsub.NewHub(cfg.Events.HistorySize, cfg.Events.HistoryTTL, cfg.Events.BufferSize), postRepo, likeRepo)
+	realtime := service.NewRealtimeService(pubsub.NewUserHub(cfg.WebSocket.BufferSize), followRepo)
+
+	// Webhookは配信ワーカープールで送信する(webhook.allow_private_networks が有効な場合のみプライベートアドレスに送信できる)
+	webhookPool := workerpool.NewWebhookWorkerPool(cfg.Webhook.WorkerCount, cfg.Webhook.QueueSize)
+	webhookClient := service.NewWebhookHTTPClient(cfg.Webhook)
 
 	return &Services{
-		Post:    service.NewPostService(postRepo),
-		Comment: service.NewCommentService(commentRepo),
-		Like:    service.NewLikeService(likeRepo),
-		User:    service.NewUserService(userRepo),
+		Post:         service.NewPostService(postRepo),
+		Comment:      service.NewCommentService(commentRepo, postEvent),
+		Like:         service.NewLikeService(likeRepo, postEvent),
+		User:         service.NewUserService(userRepo),
+		OAuth:        service.NewOAuthService(txManager, userRepo, identityRepo, oauthStateRepo),
+		APIKey:       service.NewAPIKeyService(apiKeyRepo),
+		Account:      service.NewAccountService(txManager, accountRepo, dataExportRepo, userRepo, postRepo, commentRepo, likeRepo, auditLogRepo),
+		Audit:        service.NewAuditLogService(auditLogRepo),
+		Follow:       service.NewFollowService(followRepo, postRepo),
+		Notification: service.NewNotificationService(notificationRepo, postRepo, realtime),
+		PostEvent:    postEvent,
+		Realtime:     realtime,
+		Webhook:      service.NewWebhookService(webhookRepo, postRepo, webhookPool, webhookClient, cfg.Webhook),
+		Outbox:       service.NewOutboxService(outboxRepo, cfg.Outbox),
 	}
 }
@@ -1,7 +1,13 @@
 package apperror
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
 
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// エラーの種類(レスポンスの code としてクライアントに返すため、値を変更しない)
 type Type string
 
 // エラーの種類を定義
@@ -17,10 +23,15 @@ const (
 )
 
 // エラー構造体
+// Message はログに出す内部向けのメッセージで、"公開してよい説明 : 調査用の情報(PostID=5 など)" の形式で書く
+// クライアントには Detail(空の場合は Message の " : " より前)を返し、内部エラーの場合はメッセージを返さない
 type AppError struct {
-	Type    Type   `json:"type"`
-	Message string `json:"message"`
-	Err     error  `json:"err,omitempty"`
+	Type    Type                `json:"type"`
+	Message string              `json:"message"`
+	Detail  string              `json:"detail,omitempty"`
+	SubCode string              `json:"sub_code,omitempty"`
+	Fields  []models.FieldError `json:"errors,omitempty"`
+	Err     error               `json:"err,omitempty"`
 }
 
 // エラーインターフェースを実装
@@ -45,6 +56,59 @@ func NewAppError(errType Type, message string, err error) *AppError {
 	}
 }
 
+// エラーを細かく分けたコードを設定する(codes.go の定数を使う)
+func (e *AppError) WithSubCode(code string) *AppError {
+	e.SubCode = code
+	return e
+}
+
+// クライアントに返すメッセージを設定する
+func (e *AppError) WithDetail(detail string) *AppError {
+	e.Detail = detail
+	return e
+}
+
+// 入力項目ごとの検証エラーを追加する
+func (e *AppError) WithFields(fields ...models.FieldError) *AppError {
+	e.Fields = append(e.Fields, fields...)
+	return e
+}
+
+// クライアントに返すメッセージ
+// Detail を設定した場合はその値を返す。設定していない場合、内部エラーでは原因を推測されないように空を返し、それ以外は Message から調査用の情報を除いたものを返す
+func (e *AppError) PublicMessage() string {
+	if GetStatusCode(e.Type) >= http.StatusInternalServerError {
+		return e.Detail
+	}
+	if e.Detail != "" {
+		return e.Detail
+	}
+	public, _, _ := strings.Cut(e.Message, " : ")
+	return public
+}
+
+// HTTPステータスコードに対応するエラーの種類を返す(ミドルウェアなどステータスコードでエラーを扱う場合に使う)
+func TypeFromStatus(status int) Type {
+	switch status {
+	case http.StatusBadRequest:
+		return TypeBadRequest
+	case http.StatusUnauthorized:
+		return TypeUnauthorized
+	case http.StatusForbidden:
+		return TypeForbidden
+	case http.StatusNotFound:
+		return TypeNotFound
+	case http.StatusConflict:
+		return TypeConflict
+	case http.StatusRequestTimeout:
+		return TypeTimeout
+	case http.StatusMethodNotAllowed:
+		return TypeMethodNotAllowed
+	default:
+		return TypeInternalServer
+	}
+}
+
 // エラーのHTTPステータスコードを取得する関数
 func GetStatusCode(errType Type) int {
 	switch errType {
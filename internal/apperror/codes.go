@@ -0,0 +1,44 @@
+package apperror
+
+// エラーを細かく分けたコード(レスポンスの sub_code としてクライアントに返すため、値を変更しない)
+const (
+	// 入力
+	CodeInvalidID        = "invalid_id"        // パスのIDが数値でない
+	CodeInvalidBody      = "invalid_body"      // リクエストボディのJSONを解析できない
+	CodeInvalidQuery     = "invalid_query"     // クエリパラメーターが不正(limit・cursor など)
+	CodeValidationFailed = "validation_failed" // 入力項目の検証エラー(errors に項目ごとの内容を返す)
+
+	// 認証・認可
+	CodeMissingToken       = "missing_token"       // 認証情報が無い
+	CodeInvalidToken       = "invalid_token"       // JWTが不正・期限切れ
+	CodeInvalidAPIKey      = "invalid_api_key"     // APIキーが不正・失効済み
+	CodeInsufficientScope  = "insufficient_scope"  // APIキーのスコープが足りない
+	CodeAPIKeyNotAllowed   = "api_key_not_allowed" // APIキーでは行えない操作
+	CodeInvalidCredentials = "invalid_credentials" // ユーザー名またはパスワードが違う
+	CodeNotOwner           = "not_owner"           // 他のユーザーのリソースを操作しようとした
+	CodeOAuthFailed        = "oauth_failed"        // 外部IDプロバイダーとの連携に失敗した
+
+	// リソース
+	CodePostNotFound         = "post_not_found"
+	CodeCommentNotFound      = "comment_not_found"
+	CodeUserNotFound         = "user_not_found"
+	CodeNotificationNotFound = "notification_not_found"
+	CodeAPIKeyNotFound       = "api_key_not_found"
+	CodeWebhookNotFound      = "webhook_not_found"
+	CodeDeliveryNotFound     = "webhook_delivery_not_found"
+	CodeExportNotFound       = "export_not_found"
+	CodeExportNotReady       = "export_not_ready"
+	CodeDeletionNotScheduled = "deletion_not_scheduled"
+	CodeProviderNotFound     = "provider_not_found"
+	CodeUsernameTaken        = "username_taken"
+	CodeIdentityLinked       = "identity_already_linked"
+	CodeCannotFollowSelf     = "cannot_follow_self"
+)
+
+// 入力項目ごとの検証エラーの種類(errors[].code としてクライアントに返す)
+const (
+	FieldRequired = "required"  // 必須項目が空
+	FieldTooLong  = "too_long"  // 最大長を超えている
+	FieldTooShort = "too_short" // 最小長に満たない
+	FieldInvalid  = "invalid"   // 形式や値が不正
+)
@@ -0,0 +1,48 @@
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// problem+json の type に使うURIの接頭辞(エラーの種類ごとのコードを付ける)
+const ProblemTypePrefix = "urn:blogapi:problem:"
+
+// エラーをRFC 7807の形式に変換する(AppError でないエラーは内部エラーとして扱う)
+// クライアントには公開用のメッセージだけを返し、内部向けのメッセージと原因のエラーは含めない
+func NewProblem(err error, instance string) models.ProblemDetails {
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		appErr = NewAppError(TypeInternalServer, "", err)
+	}
+	status := GetStatusCode(appErr.Type)
+	problem := models.ProblemDetails{
+		Type:     ProblemTypePrefix + string(appErr.Type),
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   appErr.PublicMessage(),
+		Instance: instance,
+		Code:     string(appErr.Type),
+		SubCode:  appErr.SubCode,
+		Errors:   appErr.Fields,
+		Message:  appErr.PublicMessage(),
+	}
+	if problem.Message == "" {
+		problem.Message = problem.Title
+	}
+	return problem
+}
+
+// エラーを application/problem+json で返す(instance にはリクエストのパスを設定する)
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	problem := NewProblem(err, r.URL.Path)
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package apperror_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// クライアントに返すメッセージから内部向けの情報が除かれることを確認する
+func TestPublicMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *apperror.AppError
+		want string
+	}{
+		{"調査用の情報を除く", apperror.NewAppError(apperror.TypeNotFound, "Post not found : PostID=5", nil), "Post not found"},
+		{"区切りが無い場合はそのまま", apperror.NewAppError(apperror.TypeBadRequest, "Invalid request body", nil), "Invalid request body"},
+		{"Detail を優先する", apperror.NewAppError(apperror.TypeConflict, "User already exists : Username=a", nil).WithDetail("Username is already taken"), "Username is already taken"},
+		{"内部エラーは返さない", apperror.NewAppError(apperror.TypeInternalServer, "Database error : PostID=5", nil), ""},
+		{"内部エラーでも Detail は返す", apperror.NewAppError(apperror.TypeInternalServer, "Failed to fetch posts", nil).WithDetail("Please retry later"), "Please retry later"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.PublicMessage(); got != tt.want {
+				t.Errorf("PublicMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// AppError が problem+json の各項目に変換されることを確認する
+func TestNewProblem(t *testing.T) {
+	appErr := apperror.NewAppError(apperror.TypeBadRequest, "Title must not be empty", nil).
+		WithSubCode(apperror.CodeValidationFailed).
+		WithFields(models.FieldError{Field: "title", Code: apperror.FieldRequired, Message: "Title must not be empty"})
+	// ラップされていても AppError として扱う
+	problem := apperror.NewProblem(fmt.Errorf("create post: %w", appErr), "/api/posts")
+
+	if problem.Type != apperror.ProblemTypePrefix+"bad_request" || problem.Title != "Bad Request" || problem.Status != http.StatusBadRequest {
+		t.Errorf("type/title/status = %q/%q/%d", problem.Type, problem.Title, problem.Status)
+	}
+	if problem.Code != "bad_request" || problem.SubCode != apperror.CodeValidationFailed {
+		t.Errorf("code/sub_code = %q/%q", problem.Code, problem.SubCode)
+	}
+	if problem.Detail != "Title must not be empty" || problem.Message != problem.Detail || problem.Instance != "/api/posts" {
+		t.Errorf("detail/message/instance = %q/%q/%q", problem.Detail, problem.Message, problem.Instance)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].Field != "title" || problem.Errors[0].Code != apperror.FieldRequired {
+		t.Errorf("errors = %+v", problem.Errors)
+	}
+}
+
+// AppError でないエラーは原因を返さずに内部エラーとして扱うことを確認する
+func TestNewProblemUnexpectedError(t *testing.T) {
+	problem := apperror.NewProblem(errors.New("pq: connection refused"), "/api/posts")
+	if problem.Status != http.StatusInternalServerError || problem.Code != "internal_server_error" {
+		t.Errorf("status/code = %d/%q", problem.Status, problem.Code)
+	}
+	if problem.Detail != "" || problem.Message != "Internal Server Error" {
+		t.Errorf("detail/message = %q/%q", problem.Detail, problem.Message)
+	}
+}
+
+// application/problem+json で書き込まれることを確認する
+func TestWriteProblem(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/posts/5?x=1", nil)
+	rec := httptest.NewRecorder()
+	apperror.WriteProblem(rec, req, apperror.NewAppError(apperror.TypeNotFound, "Post not found : PostID=5", nil).WithSubCode(apperror.CodePostNotFound))
+
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("status=%d content-type=%s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["sub_code"] != "post_not_found" || body["detail"] != "Post not found" || body["instance"] != "/api/posts/5" {
+		t.Errorf("body = %v", body)
+	}
+}
@@ -1,6 +1,17 @@
 package config
 
+import "time"
+
+// アカウント削除・データエクスポートの設定
+const (
+	AccountDeletionGracePeriod = 30 * 24 * time.Hour // アカウント削除の猶予期間
+	AccountJobInterval         = 10 * time.Second    // 削除予約・エクスポートを処理する間隔
+	DataExportTTL              = 7 * 24 * time.Hour  // エクスポートしたデータのダウンロード期限
+	DataExportStaleAfter       = 10 * time.Minute    // 処理中のまま止まったエクスポートを再処理するまでの時間
+)
+
+// トランザクションの設定
 const (
-	WorkerCount = 3
-	QueueSize   = 100
+	TxMaxAttempts = 3                     // シリアライゼーション失敗・デッドロック時の試行回数の上限
+	TxRetryDelay  = 20 * time.Millisecond // 再試行までの待ち時間(試行回数に比例して増やす)
 )
@@ -0,0 +1,234 @@
+package config_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
+)
+
+// テスト用の設定ファイルを作成する
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal("設定ファイルの作成失敗:", err)
+	}
+	return path
+}
+
+// 既定値 < 設定ファイル < 環境変数 < コマンドライン引数 の順に上書きされることを確認する
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `
+server:
+  port: "9000"
+  read_timeout: 3s
+  shutdown_phases:
+    workers: 20s
+database:
+  host: file-host
+  user: file-user
+  name: blog
+audit:
+  worker_count: 5
+outbox:
+  relay_interval: 2s
+migration:
+  lock_policy: skip
+`)
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("PORT", "9100")
+	t.Setenv("DB_HOST", "env-host")
+	t.Setenv("MIGRATION_LOCK_TIMEOUT_SECONDS", "30")
+	t.Setenv("WEBHOOK_WORKER_COUNT", "7")
+	t.Setenv("SERVER_SHUTDOWN_DB_TIMEOUT", "8s")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "http://localhost:3000, https://*.example.com")
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg, err := config.Load(flags, []string{"-config", path, "-db-host", "flag-host", "-migrate-on-startup"})
+	if err != nil {
+		t.Fatal("設定の読み込み失敗:", err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"既定値", cfg.Server.WriteTimeout, 10 * time.Second},
+		{"設定ファイル", cfg.Server.ReadTimeout, 3 * time.Second},
+		{"設定ファイル", cfg.Database.User, "file-user"},
+		{"設定ファイル", cfg.Audit.WorkerCount, 5},
+		{"設定ファイル", cfg.Migration.LockPolicy, "skip"},
+		{"設定ファイル", cfg.Outbox.RelayInterval, 2 * time.Second},
+		{"設定ファイル(入れ子)", cfg.Server.ShutdownPhases.Workers, 20 * time.Second},
+		{"既定値(入れ子)", cfg.Server.ShutdownPhases.Audit, 5 * time.Second},
+		{"環境変数", cfg.Server.Port, "9100"},
+		{"環境変数(秒数)", cfg.Migration.LockTimeout, 30 * time.Second},
+		{"環境変数", cfg.Webhook.WorkerCount, 7},
+		{"環境変数(入れ子)", cfg.Server.ShutdownPhases.DB, 8 * time.Second},
+		{"環境変数(リスト)", strings.Join(cfg.CORS.AllowedOrigins, " "), "http://localhost:3000 https://*.example.com"},
+		{"コマンドライン引数", cfg.Database.Host, "flag-host"},
+		{"コマンドライン引数", cfg.Migration.OnStartup, true},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: get %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+// 形式の誤りがエラーになることを確認する
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+	}{
+		{name: "未知のキー", file: "server:\n  unknown: 1\n"},
+		{name: "時間の形式", file: "server:\n  read_timeout: soon\n"},
+		{name: "環境変数の秒数", env: map[string]string{"MIGRATION_TIMEOUT_SECONDS": "5m"}},
+		{name: "環境変数の真偽値", env: map[string]string{"MIGRATE_ON_STARTUP": "maybe"}},
+		{name: "コマンドライン引数の時間", args: []string{"-lock-timeout", "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIG_FILE", "")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeConfigFile(t, tt.file)}, args...)
+			}
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags.SetOutput(&bytes.Buffer{})
+			if _, err := config.Load(flags, args); err == nil {
+				t.Error("エラーにならない")
+			}
+		})
+	}
+}
+
+// 不正な項目がすべてエラーとして返されることを確認する
+func TestValidate(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Host, cfg.Database.User, cfg.Database.Name = "localhost", "postgres", "blog"
+	cfg.Auth.JWTSecret = "secret"
+	cfg.CORS.AllowedOrigins = []string{"http://localhost:3000", "https://*.example.com"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal("正しい設定がエラーになる:", err)
+	}
+
+	cfg.Server.Port = "http"
+	cfg.Auth.JWTSecret = ""
+	cfg.Migration.LockPolicy = "retry"
+	cfg.OIDC = []config.OIDCProvider{{Name: "google"}}
+	cfg.CORS.AllowedOrigins = []string{"*", "https://app.example.com/", "https://app.*.com"}
+	cfg.ErrorReporting.DSN = "https://sentry.example.com/1"
+	cfg.Events.Retry = 0
+	cfg.WebSocket.PongWait = cfg.WebSocket.PingInterval
+	cfg.Webhook.WorkerCount = 0
+	cfg.Health.CheckTimeout = 0
+	cfg.Server.ShutdownPhases.Report = 0
+	cfg.Outbox.RetryMaxDelay = cfg.Outbox.RetryBaseDelay - 1
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("不正な設定がエラーにならない")
+	}
+	for _, want := range []string{"server.port", "auth.jwt_secret", "migration.lock_policy", `OIDC provider "google"`, `"*"`, `"https://app.example.com/"`, `"https://app.*.com"`, "error_reporting.dsn", "events.retry", "websocket.pong_wait", "webhook.worker_count", "outbox.retry_max_delay", "health.check_timeout", "server.shutdown_phases.report"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("エラーに %s が含まれない: %v", want, err)
+		}
+	}
+}
+
+// 秘密の値が伏せられ、時間が設定ファイルと同じ形式で出力されることを確認する
+func TestPrintRedactsSecrets(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Password = "db-password"
+	cfg.Auth.JWTSecret = "jwt-secret"
+	cfg.OIDC = []config.OIDCProvider{{Name: "google", ClientSecret: "client-secret"}}
+
+	var out bytes.Buffer
+	if err := cfg.Print(&out); err != nil {
+		t.Fatal("設定の出力失敗:", err)
+	}
+	printed := out.String()
+	for _, secret := range []string{"db-password", "jwt-secret", "client-secret"} {
+		if strings.Contains(printed, secret) {
+			t.Errorf("秘密の値 %s が出力されている:\n%s", secret, printed)
+		}
+	}
+	for _, want := range []string{"password: '******'", "jwt_secret: '******'", "client_secret: '******'", "read_timeout: 10s"} {
+		if !strings.Contains(printed, want) {
+			t.Errorf("%s が出力されない:\n%s", want, printed)
+		}
+	}
+
+	// 出力した設定はそのまま設定ファイルとして読み込める
+	t.Setenv("CONFIG_FILE", "")
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	loaded, err := config.Load(flags, []string{"-config", writeConfigFile(t, printed)})
+	if err != nil {
+		t.Fatal("出力した設定の読み込み失敗:", err)
+	}
+	if loaded.Server.ReadTimeout != cfg.Server.ReadTimeout {
+		t.Errorf("時間が一致しない: get %v, want %v", loaded.Server.ReadTimeout, cfg.Server.ReadTimeout)
+	}
+}
+
+// DATABASE_URL と個別の設定から接続文字列を作ることを確認する
+func TestDatabaseDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.DatabaseConfig
+		want string
+	}{
+		{
+			name: "個別の設定",
+			cfg:  config.DatabaseConfig{Host: "db", Port: "5432", User: "postgres", Password: "p@ss", Name: "blog", SSLMode: "disable"},
+			want: "postgres://postgres:p%40ss@db:5432/blog?sslmode=disable",
+		},
+		{
+			name: "URLのsslmodeを優先する",
+			cfg:  config.DatabaseConfig{URL: "postgres://u:p@db.example.com/blog?sslmode=verify-full", SSLMode: "disable", SSLRootCert: "/certs/ca.pem"},
+			want: "postgres://u:p@db.example.com/blog?sslmode=verify-full&sslrootcert=%2Fcerts%2Fca.pem",
+		},
+		{
+			name: "URLにsslmodeが無い場合は設定の値",
+			cfg:  config.DatabaseConfig{URL: "postgresql://u:p@db.example.com:6432/blog", SSLMode: "require"},
+			want: "postgresql://u:p@db.example.com:6432/blog?sslmode=require",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.DSN(); got != tt.want {
+			t.Errorf("%s: get %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	// DATABASE_URL を指定した場合は個別の設定は不要で、不正な場合は値を含めずにエラーにする
+	cfg := config.Default().Database
+	cfg.URL = "postgres://u:secret@db/blog"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("正しい設定がエラーになる: %v", err)
+	}
+	cfg.URL = "mysql://u:secret@db/blog"
+	cfg.SSLMode = "prefer"
+	cfg.MaxOpenConns = -1
+	err := cfg.Validate()
+	if err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("エラーが想定と異なる: %v", err)
+	}
+	for _, want := range []string{"database.url", "database.sslmode", "pool"} {
+		if err != nil && !strings.Contains(err.Error(), want) {
+			t.Errorf("エラーに %s が含まれない: %v", want, err)
+		}
+	}
+}
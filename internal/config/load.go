@@ -0,0 +1,272 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// --print-config で秘密の値の代わりに出力する文字列
+const redacted = "******"
+
+// コマンドライン引数を解析して設定を読み込む
+// flagsには -config(設定ファイルのパス、省略時は環境変数 CONFIG_FILE)と flag タグの付いた項目の引数を登録する
+// 設定ファイル・環境変数・引数の形式の誤りはエラーにするが、値の検証は行わない(Validate で行う)
+func Load(flags *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "設定ファイル(YAML)のパス")
+	fieldFlags, err := registerFlags(flags, reflect.ValueOf(cfg).Elem())
+	if err != nil {
+		return nil, err
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(cfg, *configFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := loadEnv(reflect.ValueOf(cfg).Elem()); err != nil {
+		return nil, err
+	}
+	loadOIDCEnv(cfg)
+	// 指定された引数だけを最後に反映する
+	var flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		if field, ok := fieldFlags[f.Name]; ok && flagErr == nil {
+			if err := setField(field.value, field.raw); err != nil {
+				flagErr = fmt.Errorf("invalid -%s: %w", f.Name, err)
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+	return cfg, nil
+}
+
+// 設定ファイル(YAML)を読み込む(未知のキーは誤りとしてエラーにする)
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// env タグの付いた項目を環境変数で上書きする(設定されていない環境変数は無視する)
+func loadEnv(v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		if value.Kind() == reflect.Struct {
+			if err := loadEnv(value); err != nil {
+				return err
+			}
+			continue
+		}
+		name, option, _ := strings.Cut(field.Tag.Get("env"), ",")
+		if name == "" {
+			continue
+		}
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		// 秒数で指定する環境変数(既存の MIGRATION_TIMEOUT_SECONDS など)
+		if option == "seconds" {
+			seconds, err := strconv.Atoi(raw)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			raw = (time.Duration(seconds) * time.Second).String()
+		}
+		if err := setField(value, raw); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// 環境変数から外部IDプロバイダーを読み込む
+// OIDC_PROVIDERS=google,keycloak のように指定し、プロバイダーごとに OIDC_<NAME>_ISSUER などを設定する(設定ファイルの指定を置き換える)
+func loadOIDCEnv(cfg *Config) {
+	names, ok := os.LookupEnv("OIDC_PROVIDERS")
+	if !ok {
+		return
+	}
+	providers := []OIDCProvider{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, OIDCProvider{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		})
+	}
+	cfg.OIDC = providers
+}
+
+// flag タグの付いた項目の引数(解析後に Load で反映する)
+type fieldFlag struct {
+	value  reflect.Value
+	raw    string
+	isBool bool
+}
+
+func (f *fieldFlag) String() string {
+	if f == nil || !f.value.IsValid() {
+		return ""
+	}
+	return formatValue(f.value)
+}
+
+func (f *fieldFlag) Set(raw string) error {
+	f.raw = raw
+	// 形式の誤りは解析時に返す
+	return setField(reflect.New(f.value.Type()).Elem(), raw)
+}
+
+func (f *fieldFlag) IsBoolFlag() bool {
+	return f.isBool
+}
+
+// flag タグの付いた項目の引数を登録する
+func registerFlags(flags *flag.FlagSet, v reflect.Value) (map[string]*fieldFlag, error) {
+	fieldFlags := map[string]*fieldFlag{}
+	var register func(v reflect.Value, path string) error
+	register = func(v reflect.Value, path string) error {
+		for i := 0; i < v.NumField(); i++ {
+			field, value := v.Type().Field(i), v.Field(i)
+			key := path + strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if value.Kind() == reflect.Struct {
+				if err := register(value, key+"."); err != nil {
+					return err
+				}
+				continue
+			}
+			name := field.Tag.Get("flag")
+			if name == "" {
+				continue
+			}
+			if flags.Lookup(name) != nil {
+				return fmt.Errorf("flag -%s is already defined", name)
+			}
+			f := &fieldFlag{value: value, isBool: value.Kind() == reflect.Bool}
+			usage := key
+			if env, _, _ := strings.Cut(field.Tag.Get("env"), ","); env != "" {
+				usage += " (" + env + ")"
+			}
+			flags.Var(f, name, usage)
+			fieldFlags[name] = f
+		}
+		return nil
+	}
+	return fieldFlags, register(v, "")
+}
+
+// 文字列を項目の型に変換して設定する
+func setField(value reflect.Value, raw string) error {
+	switch {
+	case value.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+	case value.Kind() == reflect.String:
+		value.SetString(raw)
+	case value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(n))
+	case value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case value.Type() == reflect.TypeOf([]string(nil)):
+		// カンマ区切りのリスト(空の場合は空のリストにする)
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return errors.New("unsupported config type " + value.Type().String())
+	}
+	return nil
+}
+
+// 項目の値を設定ファイルと同じ形式の文字列にする
+func formatValue(value reflect.Value) string {
+	if d, ok := value.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	if items, ok := value.Interface().([]string); ok {
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// 設定をYAMLで出力する(secret タグの付いた項目は値を伏せる)
+func (c *Config) Print(w io.Writer) error {
+	data, err := yaml.Marshal(redact(reflect.ValueOf(*c)))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// 出力用に秘密の値を伏せた値に変換する(時間は "10s" のような文字列にする)
+func redact(v reflect.Value) any {
+	switch {
+	case v.Kind() == reflect.Struct:
+		out := yaml.MapSlice{}
+		for i := 0; i < v.NumField(); i++ {
+			field, value := v.Type().Field(i), v.Field(i)
+			key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if field.Tag.Get("secret") == "true" && !value.IsZero() {
+				out = append(out, yaml.MapItem{Key: key, Value: redacted})
+				continue
+			}
+			out = append(out, yaml.MapItem{Key: key, Value: redact(value)})
+		}
+		return out
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = redact(v.Index(i))
+		}
+		return items
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		return formatValue(v)
+	default:
+		return v.Interface()
+	}
+}
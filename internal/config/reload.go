@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 実行中の設定を保持する(SIGHUPで再読み込みした設定を反映する)
+// reload タグの付いた項目だけを再起動せずに変更でき、それ以外の項目の変更は再起動まで反映しない
+type Store struct {
+	current   atomic.Pointer[Config]
+	mu        sync.Mutex
+	listeners []func(old, current *Config)
+}
+
+// 設定の変更内容
+type Change struct {
+	Key      string // 設定ファイルのキー(例: audit.worker_count)
+	Old, New string
+}
+
+// 変更内容を1行の文字列にする
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Key, c.Old, c.New)
+}
+
+// 設定を保持するStoreを作成する
+func NewStore(cfg *Config) *Store {
+	s := &Store{}
+	s.current.Store(cfg)
+	return s
+}
+
+// 実行中の設定を返す(返した設定は変更しない)
+func (s *Store) Current() *Config {
+	return s.current.Load()
+}
+
+// 設定が再読み込みされた場合に呼び出す関数を登録する(変更を反映する処理を登録する)
+func (s *Store) OnReload(fn func(old, current *Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// 再読み込みした設定を反映する
+// 設定が不正な場合は何も変更せずにエラーを返す。reload タグの付いた項目の変更を一度に反映して applied に、
+// 再起動が必要な項目の変更を ignored に返す
+func (s *Store) Reload(next *Config) (applied []Change, ignored []Change, err error) {
+	if err := next.Validate(); err != nil {
+		return nil, nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.current.Load()
+	merged := *old
+	applied, ignored = mergeReloadable(reflect.ValueOf(&merged).Elem(), reflect.ValueOf(next).Elem(), "")
+	if len(applied) == 0 {
+		return nil, ignored, nil
+	}
+	s.current.Store(&merged)
+	for _, fn := range s.listeners {
+		fn(old, &merged)
+	}
+	return applied, ignored, nil
+}
+
+// reload タグの付いた項目を dst に反映し、変更された項目を返す
+func mergeReloadable(dst reflect.Value, src reflect.Value, path string) (applied []Change, ignored []Change) {
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		key := path + strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if field.Type.Kind() == reflect.Struct {
+			a, ig := mergeReloadable(dst.Field(i), src.Field(i), key+".")
+			applied, ignored = append(applied, a...), append(ignored, ig...)
+			continue
+		}
+		if reflect.DeepEqual(dst.Field(i).Interface(), src.Field(i).Interface()) {
+			continue
+		}
+		change := Change{Key: key, Old: changeValue(field, dst.Field(i)), New: changeValue(field, src.Field(i))}
+		if field.Tag.Get("reload") != "true" {
+			ignored = append(ignored, change)
+			continue
+		}
+		dst.Field(i).Set(src.Field(i))
+		applied = append(applied, change)
+	}
+	return applied, ignored
+}
+
+// ログに出す変更前後の値(秘密の値は伏せる)
+func changeValue(field reflect.StructField, value reflect.Value) string {
+	if field.Tag.Get("secret") == "true" {
+		return redacted
+	}
+	if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct {
+		return fmt.Sprintf("%d items", value.Len())
+	}
+	return formatValue(value)
+}
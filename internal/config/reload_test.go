@@ -0,0 +1,77 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
+)
+
+// 再読み込みのテスト用の正しい設定
+func validConfig() *config.Config {
+	cfg := config.Default()
+	cfg.Database.Host, cfg.Database.User, cfg.Database.Name = "localhost", "postgres", "blog"
+	cfg.Auth.JWTSecret = "secret"
+	return cfg
+}
+
+// reload タグの付いた項目だけが反映され、それ以外の変更は再起動まで反映されないことを確認する
+func TestStoreReload(t *testing.T) {
+	store := config.NewStore(validConfig())
+	var notified []int
+	store.OnReload(func(old, current *config.Config) {
+		notified = append(notified, old.Audit.WorkerCount, current.Audit.WorkerCount)
+	})
+
+	next := validConfig()
+	next.Audit.WorkerCount = 8
+	next.Server.RequestTimeout = 30 * time.Second
+	next.Server.Port = "9000"
+	next.Auth.JWTSecret = "rotated"
+	applied, ignored, err := store.Reload(next)
+	if err != nil {
+		t.Fatal("設定の再読み込み失敗:", err)
+	}
+
+	if len(applied) != 2 || applied[0].Key != "server.request_timeout" || applied[1].Key != "audit.worker_count" {
+		t.Errorf("反映された項目が想定と異なる: %v", applied)
+	}
+	if len(ignored) != 2 || ignored[0].Key != "server.port" || ignored[1].String() != "auth.jwt_secret: ****** -> ******" {
+		t.Errorf("反映されない項目が想定と異なる: %v", ignored)
+	}
+	current := store.Current()
+	if current.Audit.WorkerCount != 8 || current.Server.RequestTimeout != 30*time.Second {
+		t.Errorf("変更が反映されない: %+v", current)
+	}
+	if current.Server.Port != "8080" || current.Auth.JWTSecret != "secret" {
+		t.Errorf("再起動が必要な項目が変更されている: %+v", current)
+	}
+	if len(notified) != 2 || notified[0] != 3 || notified[1] != 8 {
+		t.Errorf("変更の通知が想定と異なる: %v", notified)
+	}
+
+	// 変更が無い場合は通知しない
+	if applied, _, err := store.Reload(next); err != nil || len(applied) != 0 {
+		t.Errorf("変更が無い再読み込みの結果が想定と異なる: applied=%v err=%v", applied, err)
+	}
+	if len(notified) != 2 {
+		t.Errorf("変更が無いのに通知された: %v", notified)
+	}
+}
+
+// 不正な設定は反映されないことを確認する
+func TestStoreReloadInvalid(t *testing.T) {
+	store := config.NewStore(validConfig())
+	store.OnReload(func(old, current *config.Config) {
+		t.Error("不正な設定で通知された")
+	})
+
+	next := validConfig()
+	next.Audit.WorkerCount = 0
+	if _, _, err := store.Reload(next); err == nil {
+		t.Fatal("不正な設定がエラーにならない")
+	}
+	if got := store.Current().Audit.WorkerCount; got != 3 {
+		t.Errorf("不正な設定が反映されている: %d", got)
+	}
+}
@@ -1,33 +1,84 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"os"
-)
+	"log"
+	"time"
 
-// DBへの接続処理
-func ConnectDB() (*sql.DB, error) {
-	// DB接続を実施する
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
+)
 
-	// Data Souce Nameの設定
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", dbHost, dbPort, dbUser, dbPassword, dbName)
+// DBへの接続を再試行する間隔(1回目の待ち時間、以降は2倍ずつ増やす)
+const (
+	connectRetryBaseDelay = 500 * time.Millisecond
+	connectRetryMaxDelay  = 5 * time.Second
+)
 
-	// Postgress SQLに接続
-	DB, err := sql.Open("postgres", dsn)
+// DBへの接続処理
+// DBが起動するまで connect_timeout の間(ctxがキャンセルされた場合はそれまで)間隔を空けて接続を再試行する
+func ConnectDB(ctx context.Context, cfg config.DatabaseConfig) (*sql.DB, error) {
+	DB, err := OpenDB(cfg)
 	if err != nil {
 		return nil, err
 	}
+
 	// 接続確認
-	err = DB.Ping()
-	if err != nil {
+	if err := pingWithRetry(ctx, DB, cfg.ConnectTimeout); err != nil {
+		DB.Close()
 		return nil, err
 	}
 
 	return DB, nil
 }
+
+// コネクションプールを設定したDBを作成する(接続の確認は行わない)
+// リードレプリカのように起動時に接続できなくても処理を続ける場合に使う
+func OpenDB(cfg config.DatabaseConfig) (*sql.DB, error) {
+	// Postgress SQLに接続
+	DB, err := sql.Open("postgres", cfg.DSN())
+	if err != nil {
+		return nil, err
+	}
+	// コネクションプールの設定
+	DB.SetMaxOpenConns(cfg.MaxOpenConns)
+	DB.SetMaxIdleConns(cfg.MaxIdleConns)
+	DB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	DB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	return DB, nil
+}
+
+// 接続できるまで指数バックオフでPingを再試行する
+func pingWithRetry(parent context.Context, db *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	giveUp := func(err error) error {
+		if parent.Err() != nil {
+			return fmt.Errorf("database connection canceled: %w", err)
+		}
+		return fmt.Errorf("database not reachable within %s: %w", timeout, err)
+	}
+
+	delay := connectRetryBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("database connection established after %d attempts", attempt)
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return giveUp(err)
+		}
+		log.Printf("database not ready (attempt %d): %v; retrying in %s", attempt, err, delay)
+
+		select {
+		case <-ctx.Done():
+			return giveUp(err)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, connectRetryMaxDelay)
+	}
+}
@@ -2,147 +2,721 @@ package db
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/sql/migrations"
 )
 
 // 実行するSQL文を定数で定義する
+// 旧形式(バージョンにファイル名を記録していた)の行はバージョン番号と名前に分けて記録し直す
 const createSchemaMigrationsTable = `
 CREATE TABLE IF NOT EXISTS schema_migrations (
 	version TEXT PRIMARY KEY,
 	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
+ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS name TEXT;
+ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS checksum TEXT;
+UPDATE schema_migrations
+SET name = substring(version from '^[0-9]+_(.*)\.sql$'), version = substring(version from '^[0-9]+')
+WHERE version LIKE '%.sql';
 `
 
+// トランザクションの外で実行するマイグレーションの指定(CREATE INDEX CONCURRENTLY などで使う)
+// ファイルの先頭行に書くと、SQLを行末の ; で文ごとに分けて1文ずつ実行する
+const noTransactionDirective = "-- migrate:no-transaction"
+
+// これより前のマイグレーションをまとめたベースラインの指定(例: -- migrate:baseline 000008)
+// ファイルの先頭行に書くと、空のDBではそのまま実行し、まとめたマイグレーションを適用済みのDBでは実行せずに適用済みとして記録する
+const baselineDirective = "-- migrate:baseline"
+
+// マイグレーションファイル名の形式(例: 000001_create_post_stats.up.sql / 000001_create_post_stats.down.sql)
+var migrationFilePattern = regexp.MustCompile(`^([0-9]+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// マイグレーション名に使える形式
+var migrationNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// マイグレーションの状態
+type MigrationState string
+
+const (
+	MigrationApplied  MigrationState = "applied"  // 適用済み
+	MigrationPending  MigrationState = "pending"  // 未適用
+	MigrationModified MigrationState = "modified" // 適用後にファイルが変更された
+	MigrationMissing  MigrationState = "missing"  // 適用済みだがファイルが無い
+)
+
+// マイグレーションファイルの組(upとdown)
+type Migration struct {
+	Version  string
+	Name     string
+	UpSQL    string
+	DownSQL  string // 空の場合はロールバックできない
+	Checksum string // up SQLのSHA-256(適用後の変更を検知する)
+	Baseline string // ベースラインの場合はまとめた最後のマイグレーションのバージョン
+}
+
+// マイグレーションの識別名を返す(例: 000001_create_post_stats)
+func (m Migration) ID() string {
+	return m.Version + "_" + m.Name
+}
+
+// 適用済みのマイグレーション
+type appliedMigration struct {
+	Version   string
+	Name      string
+	Checksum  string // 旧形式で適用された場合は空
+	AppliedAt time.Time
+}
+
+// マイグレーションの状態(statusコマンドの出力)
+type MigrationStatus struct {
+	Version   string
+	Name      string
+	State     MigrationState
+	AppliedAt *time.Time
+}
+
+// マイグレーションの実行に使う sql.DB / sql.Conn の共通部分
+type migrationConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// マイグレーションの適用・ロールバックを行う構造体
+type Migrator struct {
+	db     *sql.DB
+	conn   migrationConn // ロック中はロックを取得した接続で実行する
+	fsys   fs.FS
+	out    io.Writer
+	dryRun bool
+	lock   *LockOptions // nilの場合はロックを取得しない
+}
+
+// マイグレーションを行う構造体のインスタンスを生成する(dryRunの場合は実行するSQLを出力するだけでDBを変更しない)
+// fsysは通常は埋め込み済みの migrations.FS を渡し、ディレクトリから読み込む場合は os.DirFS を渡す
+func NewMigrator(conn *sql.DB, fsys fs.FS, out io.Writer, dryRun bool) *Migrator {
+	return &Migrator{db: conn, conn: conn, fsys: fsys, out: out, dryRun: dryRun}
+}
+
+// 適用・ロールバックをアドバイザリロックで保護する(複数のプロセスから同時に実行しても1つずつ実行される)
+func (m *Migrator) WithLock(opts LockOptions) *Migrator {
+	m.lock = &opts
+	return m
+}
+
 // すべてのマイグレーションを実行する関数
-func RunMigrations(ctx context.Context, conn *sql.DB, migrationsDir string) error {
-	// マイグレーション管理用のテーブルを作成する
-	if _, err := conn.ExecContext(ctx, createSchemaMigrationsTable); err != nil {
-		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+func RunMigrations(ctx context.Context, conn *sql.DB, fsys fs.FS) error {
+	return NewMigrator(conn, fsys, os.Stdout, false).Up(ctx, 0)
+}
+
+// マイグレーションの読み込み元を返す(dirが空の場合はバイナリに埋め込まれたマイグレーション)
+func MigrationSource(dir string) fs.FS {
+	if dir == "" {
+		return migrations.FS
 	}
+	return os.DirFS(dir)
+}
 
-	// マイグレーションファイルのリストを取得する
-	files, err := migrationFiles(migrationsDir)
+// 未適用のマイグレーションを古い順に最大limit件適用する(0の場合はすべて適用する)
+func (m *Migrator) Up(ctx context.Context, limit int) error {
+	return m.locked(ctx, func(m *Migrator) error { return m.up(ctx, limit) })
+}
+
+// 未適用のマイグレーションを適用する(ロック取得後に呼び出す)
+func (m *Migrator) up(ctx context.Context, limit int) error {
+	migrations, applied, err := m.load(ctx, !m.dryRun)
 	if err != nil {
 		return err
 	}
+	// 適用済みのファイルが変更されていないか確認する
+	if err := m.verify(ctx, migrations, applied); err != nil {
+		return err
+	}
 
-	// マイグレーションファイルを順番に実行する
-	for _, file := range files {
-		if err := runMigration(ctx, conn, file); err != nil {
+	count := 0
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			fmt.Fprintf(m.out, "skip migration: %s\n", migration.ID())
+			continue
+		}
+		if limit > 0 && count >= limit {
+			break
+		}
+		// まとめたマイグレーションを適用済みのDBではベースラインを実行せずに記録だけ置き換える
+		if migration.Baseline != "" && len(applied) > 0 {
+			if err := m.adoptBaseline(ctx, migration, applied); err != nil {
+				return err
+			}
+			count++
+			continue
+		}
+		if err := m.apply(ctx, migration); err != nil {
 			return err
 		}
+		count++
 	}
 	return nil
 }
 
-// マイグレーションファイルを実行する関数
-func runMigration(ctx context.Context, conn *sql.DB, file string) error {
-	// マイグレーションファイルのバージョンを取得する
-	version := filepath.Base(file)
+// 適用済みのマイグレーションを新しい順にn件ロールバックする
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	if n <= 0 {
+		return errors.New("number of migrations to roll back must be positive")
+	}
+	return m.locked(ctx, func(m *Migrator) error { return m.down(ctx, n) })
+}
 
-	// マイグレーションが既に適用されているかどうかを確認する
-	applied, err := isMigrationApplied(ctx, conn, version)
+// 適用済みのマイグレーションをロールバックする(ロック取得後に呼び出す)
+func (m *Migrator) down(ctx context.Context, n int) error {
+	migrations, applied, err := m.load(ctx, !m.dryRun)
 	if err != nil {
 		return err
 	}
+	if err := m.verify(ctx, migrations, applied); err != nil {
+		return err
+	}
 
-	if applied {
-		fmt.Printf("skip migration: %s\n", version)
-		return nil
+	targets, err := latestApplied(migrations, applied, n)
+	if err != nil {
+		return err
 	}
+	for _, migration := range targets {
+		if err := m.rollback(ctx, migration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	sqlText, err := readMigrationSQL(file)
+// 最後に適用したマイグレーションをロールバックして適用し直す
+func (m *Migrator) Redo(ctx context.Context) error {
+	return m.locked(ctx, func(m *Migrator) error { return m.redo(ctx) })
+}
+
+// 最後に適用したマイグレーションを適用し直す(ロック取得後に呼び出す)
+func (m *Migrator) redo(ctx context.Context) error {
+	migrations, applied, err := m.load(ctx, !m.dryRun)
 	if err != nil {
 		return err
 	}
+	if err := m.verify(ctx, migrations, applied); err != nil {
+		return err
+	}
 
-	if err := applyMigration(ctx, conn, version, sqlText); err != nil {
+	targets, err := latestApplied(migrations, applied, 1)
+	if err != nil {
 		return err
 	}
+	if err := m.rollback(ctx, targets[0]); err != nil {
+		return err
+	}
+	return m.apply(ctx, targets[0])
+}
+
+// すべてのマイグレーションの状態をバージョン順に取得する
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, applied, err := m.load(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	files := map[string]bool{}
+	for _, migration := range migrations {
+		files[migration.Version] = true
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name, State: MigrationPending}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+			status.State = MigrationApplied
+			if record.Checksum != "" && record.Checksum != migration.Checksum {
+				status.State = MigrationModified
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	for version, record := range applied {
+		if files[version] {
+			continue
+		}
+		appliedAt := record.AppliedAt
+		statuses = append(statuses, MigrationStatus{Version: version, Name: record.Name, State: MigrationMissing, AppliedAt: &appliedAt})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return versionLess(statuses[i].Version, statuses[j].Version) })
+	return statuses, nil
+}
 
-	fmt.Printf("applied migration: %s\n", version)
+// DBのスキーマがマイグレーションファイルの最新のバージョンまで適用されているか確認する(readinessの確認に使う)
+// 新しいバージョンのデプロイ中などでDBの方が新しい場合はエラーにしない
+func (m *Migrator) CheckVersion(ctx context.Context) error {
+	migrations, applied, err := m.load(ctx, false)
+	if err != nil {
+		return err
+	}
+	if len(migrations) == 0 {
+		return nil
+	}
+	expected := migrations[len(migrations)-1].Version
+	current := ""
+	for version := range applied {
+		if current == "" || versionLess(current, version) {
+			current = version
+		}
+	}
+	if current == "" {
+		return fmt.Errorf("no migrations applied, expected version %s", expected)
+	}
+	if versionLess(current, expected) {
+		return fmt.Errorf("database schema version %s is behind the expected version %s", current, expected)
+	}
 	return nil
 }
 
-// マイグレーションファイルの内容を読み込む関数
-func readMigrationSQL(file string) (string, error) {
-	sqlBytes, err := os.ReadFile(file)
+// マイグレーションファイルと適用済みのマイグレーションを読み込む(prepareの場合はマイグレーション管理用のテーブルを作成・更新する)
+func (m *Migrator) load(ctx context.Context, prepare bool) ([]Migration, map[string]appliedMigration, error) {
+	migrations, err := LoadMigrations(m.fsys)
+	if err != nil {
+		return nil, nil, err
+	}
+	applied, err := m.loadApplied(ctx, prepare)
 	if err != nil {
-		return "", fmt.Errorf("failed to read migration file %s: %w", file, err)
+		return nil, nil, err
 	}
+	return migrations, applied, nil
+}
 
-	sqlText := strings.TrimSpace(string(sqlBytes))
-	if sqlText == "" {
-		return "", fmt.Errorf("migration %s is empty", filepath.Base(file))
+// 適用済みのマイグレーションを取得する(prepareでない場合はテーブルを変更せずに読み取るだけにする)
+func (m *Migrator) loadApplied(ctx context.Context, prepare bool) (map[string]appliedMigration, error) {
+	if !prepare {
+		var exists bool
+		if err := m.conn.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check schema_migrations table: %w", err)
+		}
+		if !exists {
+			return map[string]appliedMigration{}, nil
+		}
+		var hasChecksum bool
+		if err := m.conn.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM information_schema.columns WHERE table_name = 'schema_migrations' AND column_name = 'checksum')").Scan(&hasChecksum); err != nil {
+			return nil, fmt.Errorf("failed to check schema_migrations table: %w", err)
+		}
+		if !hasChecksum {
+			return m.queryApplied(ctx, "SELECT version, '', '', applied_at FROM schema_migrations")
+		}
+	} else if _, err := m.conn.ExecContext(ctx, createSchemaMigrationsTable); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
 	}
+	return m.queryApplied(ctx, "SELECT version, COALESCE(name, ''), COALESCE(checksum, ''), applied_at FROM schema_migrations")
+}
 
-	return sqlText, nil
+// 適用済みのマイグレーションをバージョンごとに取得する
+func (m *Migrator) queryApplied(ctx context.Context, query string) (map[string]appliedMigration, error) {
+	rows, err := m.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[string]appliedMigration{}
+	for rows.Next() {
+		var record appliedMigration
+		if err := rows.Scan(&record.Version, &record.Name, &record.Checksum, &record.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to parse applied migration: %w", err)
+		}
+		// 旧形式(ファイル名)のバージョンはバージョン番号と名前に分ける(テーブルを更新せずに読み取った場合)
+		if version, name, ok := strings.Cut(strings.TrimSuffix(record.Version, ".sql"), "_"); ok {
+			record.Version, record.Name = version, name
+		}
+		applied[record.Version] = record
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch applied migrations: %w", err)
+	}
+	return applied, nil
 }
 
-// マイグレーションSQLを実行して適用済みとして記録する関数
-func applyMigration(ctx context.Context, conn *sql.DB, version string, sqlText string) error {
+// 適用済みのマイグレーションのファイルが変更されていないか確認する
+// チェックサムが無い(旧形式で適用された)場合は現在のファイルのチェックサムを記録する
+func (m *Migrator) verify(ctx context.Context, migrations []Migration, applied map[string]appliedMigration) error {
+	for _, migration := range migrations {
+		record, ok := applied[migration.Version]
+		if !ok {
+			continue
+		}
+		if record.Checksum == "" {
+			if m.dryRun {
+				continue
+			}
+			if _, err := m.conn.ExecContext(ctx, "UPDATE schema_migrations SET name = $1, checksum = $2 WHERE version = $3", migration.Name, migration.Checksum, migration.Version); err != nil {
+				return fmt.Errorf("failed to record checksum %s: %w", migration.ID(), err)
+			}
+			continue
+		}
+		if record.Checksum != migration.Checksum {
+			return fmt.Errorf("migration %s has been modified after it was applied (checksum mismatch)", migration.ID())
+		}
+	}
+	return nil
+}
+
+// マイグレーションを適用して適用済みとして記録する
+func (m *Migrator) apply(ctx context.Context, migration Migration) error {
+	if m.dryRun {
+		fmt.Fprintf(m.out, "-- dry-run: apply migration %s\n%s\n\n", migration.ID(), migration.UpSQL)
+		return nil
+	}
+	record := func(ctx context.Context, exec execer) error {
+		_, err := exec.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)", migration.Version, migration.Name, migration.Checksum)
+		return err
+	}
+	if err := m.execute(ctx, migration.ID(), migration.UpSQL, record); err != nil {
+		return err
+	}
+	fmt.Fprintf(m.out, "applied migration: %s\n", migration.ID())
+	return nil
+}
+
+// 既存のDBでベースラインを適用済みとして記録し、ベースラインにまとめたマイグレーションの記録を削除する
+// まとめた最後のマイグレーションまで適用済みでない場合はスキーマが一致しないためエラーにする
+func (m *Migrator) adoptBaseline(ctx context.Context, baseline Migration, applied map[string]appliedMigration) error {
+	var latest string
+	var squashed []string
+	for version := range applied {
+		if !versionLess(version, baseline.Version) {
+			continue
+		}
+		squashed = append(squashed, version)
+		if latest == "" || versionLess(latest, version) {
+			latest = version
+		}
+	}
+	if latest != baseline.Baseline {
+		return fmt.Errorf("cannot adopt baseline %s: database is at version %q but the baseline replaces migrations up to %s (apply them with an older release first)", baseline.ID(), latest, baseline.Baseline)
+	}
+	if m.dryRun {
+		fmt.Fprintf(m.out, "-- dry-run: adopt baseline %s (replaces %d applied migrations)\n\n", baseline.ID(), len(squashed))
+		return nil
+	}
+
+	tx, err := m.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction for baseline %s: %w", baseline.ID(), err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			fmt.Fprintf(m.out, "failed to rollback baseline %s: %v\n", baseline.ID(), err)
+		}
+	}()
+	for _, version := range squashed {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", version); err != nil {
+			return fmt.Errorf("failed to replace migration %s with baseline: %w", version, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)", baseline.Version, baseline.Name, baseline.Checksum); err != nil {
+		return fmt.Errorf("failed to record baseline %s: %w", baseline.ID(), err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit baseline %s: %w", baseline.ID(), err)
+	}
+	fmt.Fprintf(m.out, "adopted baseline migration: %s (replaces %d applied migrations, run `migrate verify` to check the schema)\n", baseline.ID(), len(squashed))
+	return nil
+}
+
+// マイグレーションをロールバックして適用済みの記録を削除する
+func (m *Migrator) rollback(ctx context.Context, migration Migration) error {
+	if migration.DownSQL == "" {
+		return fmt.Errorf("migration %s has no down migration", migration.ID())
+	}
+	if m.dryRun {
+		fmt.Fprintf(m.out, "-- dry-run: roll back migration %s\n%s\n\n", migration.ID(), migration.DownSQL)
+		return nil
+	}
+	record := func(ctx context.Context, exec execer) error {
+		_, err := exec.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		return err
+	}
+	if err := m.execute(ctx, migration.ID(), migration.DownSQL, record); err != nil {
+		return err
+	}
+	fmt.Fprintf(m.out, "rolled back migration: %s\n", migration.ID())
+	return nil
+}
+
+// SQLの実行に使う sql.DB / sql.Tx の共通部分
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// マイグレーションのSQLを実行して記録を更新する
+// トランザクションの外で実行する指定がある場合は1文ずつ実行し、すべて成功した後に記録を更新する
+func (m *Migrator) execute(ctx context.Context, id string, sqlText string, record func(context.Context, execer) error) error {
+	if isNoTransaction(sqlText) {
+		for _, statement := range splitStatements(sqlText) {
+			if _, err := m.conn.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("failed to execute migration %s: %w", id, err)
+			}
+		}
+		if err := record(ctx, m.conn); err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", id, err)
+		}
+		return nil
+	}
+
 	// トランザクションを開始する
-	tx, err := conn.BeginTx(ctx, nil)
+	tx, err := m.conn.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to start migration transaction %s: %w", version, err)
+		return fmt.Errorf("failed to start migration transaction %s: %w", id, err)
 	}
 	defer func() {
 		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
-			fmt.Printf("failed to rollback migration %s: %v\n", version, err)
+			fmt.Fprintf(m.out, "failed to rollback migration %s: %v\n", id, err)
 		}
 	}()
 
 	// マイグレーションを実行する
 	if _, err := tx.ExecContext(ctx, sqlText); err != nil {
-		return fmt.Errorf("failed to execute migration %s: %w", version, err)
+		return fmt.Errorf("failed to execute migration %s: %w", id, err)
 	}
 
-	// マイグレーションのバージョンを記録する
-	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
-		return fmt.Errorf("failed to record migration %s: %w", version, err)
+	// マイグレーションの記録を更新する
+	if err := record(ctx, tx); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", id, err)
 	}
 
 	// トランザクションをコミットする
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit migration %s: %w", version, err)
+		return fmt.Errorf("failed to commit migration %s: %w", id, err)
 	}
-
 	return nil
 }
 
-// マイグレーションファイルのリストを取得する関数
-func migrationFiles(migrationsDir string) ([]string, error) {
-	entries, err := os.ReadDir(migrationsDir)
+// 適用済みのマイグレーションを新しい順にn件取得する(ファイルが無いものはロールバックできない)
+func latestApplied(migrations []Migration, applied map[string]appliedMigration, n int) ([]Migration, error) {
+	versions := make([]string, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versionLess(versions[j], versions[i]) })
+	if len(versions) == 0 {
+		return nil, errors.New("no applied migrations")
+	}
+	if n > len(versions) {
+		n = len(versions)
+	}
+
+	byVersion := map[string]Migration{}
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+	targets := make([]Migration, 0, n)
+	for _, version := range versions[:n] {
+		migration, ok := byVersion[version]
+		if !ok {
+			return nil, fmt.Errorf("cannot roll back migration %s: migration file not found", version)
+		}
+		targets = append(targets, migration)
+	}
+	return targets, nil
+}
+
+// マイグレーションファイルをバージョン順に読み込む関数
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
 	if err != nil {
 		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
 	}
 
-	files := make([]string, 0, len(entries))
+	byVersion := map[string]*Migration{}
 	for _, entry := range entries {
 		// ディレクトリやSQL以外のファイルはスキップする
-		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
 			continue
 		}
-		// マイグレーションファイルのパスをリストに追加する
-		files = append(files, filepath.Join(migrationsDir, entry.Name()))
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("invalid migration file name %s (expected NNNNNN_name.up.sql or NNNNNN_name.down.sql)", entry.Name())
+		}
+		version, name, direction := matches[1], matches[2], matches[3]
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("duplicate migration version %s (%s and %s)", version, migration.Name, name)
+		}
+
+		sqlText, err := readMigrationSQL(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if direction == "up" {
+			migration.UpSQL = sqlText
+			migration.Checksum = checksum(sqlText)
+			if migration.Baseline, err = baselineOf(sqlText); err != nil {
+				return nil, fmt.Errorf("migration %s: %w", migration.ID(), err)
+			}
+		} else {
+			migration.DownSQL = sqlText
+		}
 	}
-	// ファイル名でソートする（マイグレーションの順序を保証するため）
-	sort.Strings(files)
-	return files, nil
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.UpSQL == "" {
+			return nil, fmt.Errorf("migration %s has no up migration", migration.ID())
+		}
+		migrations = append(migrations, *migration)
+	}
+	// バージョン順にソートする（マイグレーションの順序を保証するため）
+	sort.Slice(migrations, func(i, j int) bool { return versionLess(migrations[i].Version, migrations[j].Version) })
+
+	// ベースラインにまとめたマイグレーションのファイルが残っていないか確認する
+	for _, migration := range migrations {
+		if migration.Baseline == "" {
+			continue
+		}
+		for _, older := range migrations {
+			if !versionLess(migration.Baseline, older.Version) {
+				return nil, fmt.Errorf("migration %s is replaced by baseline %s (remove the file)", older.ID(), migration.ID())
+			}
+		}
+	}
+	return migrations, nil
 }
 
-// マイグレーションが既に適用されているかどうかを確認する関数
-func isMigrationApplied(ctx context.Context, conn *sql.DB, version string) (bool, error) {
-	var exists bool
-	err := conn.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version = $1)", version).Scan(&exists)
+// 新しいマイグレーションファイルの組を作成して作成したファイルのパスを返す関数
+// バージョンは既存のマイグレーションの最大値の次の番号にする
+func CreateMigration(migrationsDir string, name string) (string, string, error) {
+	name = strings.ToLower(strings.NewReplacer("-", "_", " ", "_").Replace(strings.TrimSpace(name)))
+	if !migrationNamePattern.MatchString(name) {
+		return "", "", fmt.Errorf("invalid migration name %q (use lowercase letters, digits and underscores)", name)
+	}
+
+	migrations, err := LoadMigrations(os.DirFS(migrationsDir))
 	if err != nil {
-		return false, fmt.Errorf("failed to check migration %s: %w", version, err)
+		return "", "", err
+	}
+	next := 1
+	if len(migrations) > 0 {
+		last, err := strconv.Atoi(migrations[len(migrations)-1].Version)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid migration version %s: %w", migrations[len(migrations)-1].Version, err)
+		}
+		next = last + 1
+	}
+
+	base := filepath.Join(migrationsDir, fmt.Sprintf("%06d_%s", next, name))
+	upPath, downPath := base+".up.sql", base+".down.sql"
+	files := map[string]string{
+		upPath:   fmt.Sprintf("-- %s の適用\n", name),
+		downPath: fmt.Sprintf("-- %s のロールバック\n", name),
+	}
+	for path, content := range files {
+		// 既存のファイルを上書きしない
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to create migration file: %w", err)
+		}
+		if _, err := file.WriteString(content); err != nil {
+			file.Close()
+			return "", "", fmt.Errorf("failed to write migration file %s: %w", path, err)
+		}
+		if err := file.Close(); err != nil {
+			return "", "", fmt.Errorf("failed to write migration file %s: %w", path, err)
+		}
+	}
+	return upPath, downPath, nil
+}
+
+// マイグレーションファイルの内容を読み込む関数
+func readMigrationSQL(fsys fs.FS, name string) (string, error) {
+	sqlBytes, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read migration file %s: %w", name, err)
+	}
+
+	sqlText := strings.TrimSpace(string(sqlBytes))
+	if sqlText == "" {
+		return "", fmt.Errorf("migration %s is empty", name)
+	}
+
+	return sqlText, nil
+}
+
+// マイグレーションSQLのチェックサムを計算する
+func checksum(sqlText string) string {
+	sum := sha256.Sum256([]byte(sqlText))
+	return hex.EncodeToString(sum[:])
+}
+
+// ベースラインの指定からまとめた最後のマイグレーションのバージョンを取得する(ベースラインでない場合は空)
+func baselineOf(sqlText string) (string, error) {
+	firstLine, _, _ := strings.Cut(sqlText, "\n")
+	rest, ok := strings.CutPrefix(strings.TrimSpace(firstLine), baselineDirective)
+	if !ok {
+		return "", nil
+	}
+	version := strings.TrimSpace(rest)
+	if _, err := strconv.ParseUint(version, 10, 64); err != nil {
+		return "", fmt.Errorf("invalid baseline directive %q (expected %s NNNNNN)", firstLine, baselineDirective)
+	}
+	return version, nil
+}
+
+// トランザクションの外で実行する指定があるか確認する
+func isNoTransaction(sqlText string) bool {
+	firstLine, _, _ := strings.Cut(sqlText, "\n")
+	return strings.TrimSpace(firstLine) == noTransactionDirective
+}
+
+// SQLを行末の ; で文ごとに分ける(コメントだけの部分は除く)
+func splitStatements(sqlText string) []string {
+	var statements []string
+	var current strings.Builder
+	flush := func() {
+		statement := strings.TrimSpace(current.String())
+		current.Reset()
+		for _, line := range strings.Split(statement, "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "--") {
+				statements = append(statements, statement)
+				return
+			}
+		}
+	}
+	for _, line := range strings.Split(sqlText, "\n") {
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			flush()
+		}
+	}
+	flush()
+	return statements
+}
+
+// バージョン番号の大小を比較する(桁数が異なる場合も数値として比較する)
+func versionLess(a string, b string) bool {
+	x, errA := strconv.ParseUint(a, 10, 64)
+	y, errB := strconv.ParseUint(b, 10, 64)
+	if errA != nil || errB != nil {
+		return a < b
 	}
-	return exists, nil
+	return x < y
 }
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
+)
+
+// マイグレーションの排他に使うアドバイザリロックのキー(同じDBを使うすべてのプロセスで共通、pg_locksのobjidで確認できる)
+const MigrationLockKey int64 = 0x426c6f67417069 // "BlogApi"
+
+// ロックの取得を再試行する間隔
+const migrationLockRetryInterval = 500 * time.Millisecond
+
+// 他のプロセスがマイグレーションを実行中だった場合の動作
+type LockPolicy string
+
+const (
+	// ロックが解放されるまで待ってから実行する(待っている間に適用されたマイグレーションは実行しない)
+	LockWait LockPolicy = "wait"
+	// 待たずにマイグレーションを実行せずに終了する
+	LockSkip LockPolicy = "skip"
+)
+
+// マイグレーションのロックの設定
+type LockOptions struct {
+	Timeout time.Duration // LockWaitの場合に待つ最大の時間
+	Policy  LockPolicy
+}
+
+// マイグレーションの設定からロックの設定を作る
+func NewLockOptions(cfg config.MigrationConfig) LockOptions {
+	return LockOptions{Timeout: cfg.LockTimeout, Policy: LockPolicy(cfg.LockPolicy)}
+}
+
+// ロックの取得に失敗した場合のエラー
+var ErrMigrationLocked = errors.New("migration lock is held by another process")
+
+// アドバイザリロックを取得した接続でマイグレーションを実行する
+// アドバイザリロックはセッション単位のため、ロックの取得から解放までを同じ接続で行う
+// dry-runやロックが設定されていない場合はそのまま実行する
+func (m *Migrator) locked(ctx context.Context, fn func(*Migrator) error) error {
+	if m.lock == nil || m.dryRun {
+		return fn(m)
+	}
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection for migration lock: %w", err)
+	}
+	defer conn.Close()
+
+	acquired, err := m.acquireLock(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		fmt.Fprintln(m.out, "skip migrations: locked by another process")
+		return nil
+	}
+	defer func() {
+		// 実行がキャンセルされていても解放できるように元のコンテキストは使わない
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		var released bool
+		if err := conn.QueryRowContext(unlockCtx, "SELECT pg_advisory_unlock($1)", MigrationLockKey).Scan(&released); err != nil || !released {
+			// 解放できなかった場合は接続を破棄してセッションごとロックを解放させる
+			fmt.Fprintf(m.out, "failed to release migration lock: %v\n", err)
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+	}()
+
+	locked := *m
+	locked.conn = conn
+	return fn(&locked)
+}
+
+// アドバイザリロックを取得する(LockSkipの場合は取得できなければfalseを返す)
+func (m *Migrator) acquireLock(parent context.Context, conn migrationConn) (bool, error) {
+	ctx, cancel := context.WithTimeout(parent, m.lock.Timeout)
+	defer cancel()
+	timeoutErr := func() error {
+		if err := parent.Err(); err != nil {
+			return fmt.Errorf("waiting for migration lock: %w", err)
+		}
+		return fmt.Errorf("%w: timed out after %s", ErrMigrationLocked, m.lock.Timeout)
+	}
+
+	ticker := time.NewTicker(migrationLockRetryInterval)
+	defer ticker.Stop()
+	waiting := false
+	for {
+		var acquired bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", MigrationLockKey).Scan(&acquired); err != nil {
+			if ctx.Err() != nil {
+				return false, timeoutErr()
+			}
+			return false, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if acquired {
+			return true, nil
+		}
+		if m.lock.Policy == LockSkip {
+			return false, nil
+		}
+		if !waiting {
+			fmt.Fprintln(m.out, "waiting for migration lock held by another process")
+			waiting = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, timeoutErr()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package db_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/db"
+	"github.com/yusuke-hoguro/BlogApi/sql/migrations"
+	"github.com/yusuke-hoguro/BlogApi/testutils"
+)
+
+// テスト用のマイグレーションファイルを作成する
+func writeMigration(t *testing.T, dir string, name string, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal("マイグレーションファイルの作成失敗:", err)
+	}
+}
+
+// ファイル名の形式とup/downの組を確認して読み込むことを確認する
+func TestLoadMigrations(t *testing.T) {
+	dir := t.TempDir()
+	writeMigration(t, dir, "000002_second.up.sql", "SELECT 2;")
+	writeMigration(t, dir, "000001_first.up.sql", "SELECT 1;")
+	writeMigration(t, dir, "000001_first.down.sql", "SELECT -1;")
+	writeMigration(t, dir, "README.md", "SQL以外のファイルは無視する")
+
+	migrations, err := db.LoadMigrations(os.DirFS(dir))
+	if err != nil {
+		t.Fatal("マイグレーションの読み込み失敗:", err)
+	}
+	if len(migrations) != 2 || migrations[0].ID() != "000001_first" || migrations[1].ID() != "000002_second" {
+		t.Fatalf("マイグレーションの順序が不正: %+v", migrations)
+	}
+	if migrations[0].DownSQL != "SELECT -1;" || migrations[1].DownSQL != "" || migrations[0].Checksum == "" {
+		t.Errorf("マイグレーションの内容が不正: %+v", migrations)
+	}
+
+	// 形式が不正なファイル・upが無いマイグレーション・ベースラインにまとめたはずのファイルはエラーにする
+	for name, files := range map[string]map[string]string{
+		"invalid name":      {"000003_third.sql": "SELECT 3;"},
+		"down only":         {"000003_third.down.sql": "SELECT 3;"},
+		"invalid baseline":  {"000003_baseline.up.sql": "-- migrate:baseline latest\nSELECT 3;"},
+		"squashed leftover": {"000002_second.up.sql": "SELECT 2;", "000003_baseline.up.sql": "-- migrate:baseline 000002\nSELECT 3;"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			invalidDir := t.TempDir()
+			for file, content := range files {
+				writeMigration(t, invalidDir, file, content)
+			}
+			if _, err := db.LoadMigrations(os.DirFS(invalidDir)); err == nil {
+				t.Errorf("%v がエラーにならない", files)
+			}
+		})
+	}
+}
+
+// 埋め込み済みのマイグレーションがベースラインから始まることを確認する
+func TestEmbeddedMigrations(t *testing.T) {
+	embedded, err := db.LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatal("埋め込み済みのマイグレーションの読み込み失敗:", err)
+	}
+	if len(embedded) == 0 || embedded[0].Baseline == "" || embedded[0].DownSQL == "" {
+		t.Fatalf("最初のマイグレーションがベースラインではない: %+v", embedded)
+	}
+}
+
+// 既存のマイグレーションの次の番号でup/downのファイルが作成されることを確認する
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	writeMigration(t, dir, "000007_existing.up.sql", "SELECT 7;")
+
+	upPath, downPath, err := db.CreateMigration(dir, "Add Widgets-Table")
+	if err != nil {
+		t.Fatal("マイグレーションの作成失敗:", err)
+	}
+	if filepath.Base(upPath) != "000008_add_widgets_table.up.sql" || filepath.Base(downPath) != "000008_add_widgets_table.down.sql" {
+		t.Errorf("作成したファイル名が不正: %s, %s", upPath, downPath)
+	}
+	if _, _, err := db.CreateMigration(dir, "invalid/name"); err == nil {
+		t.Error("不正な名前で作成できてしまう")
+	}
+}
+
+// up・down・redo・status・dry-runと、適用後の変更の検知を確認する
+func TestMigratorLifecycle(t *testing.T) {
+	conn := testutils.SetupTestDB(t)
+	ctx := context.Background()
+
+	// テンプレートDBに適用済みのマイグレーションより後の番号を使う
+	dir := t.TempDir()
+	writeMigration(t, dir, "900001_create_widgets.up.sql", "CREATE TABLE widgets(id SERIAL PRIMARY KEY, name TEXT NOT NULL);")
+	writeMigration(t, dir, "900001_create_widgets.down.sql", "DROP TABLE widgets;")
+	// CREATE INDEX CONCURRENTLY はトランザクションの中で実行できない
+	writeMigration(t, dir, "900002_index_widgets.up.sql", "-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY idx_widgets_name ON widgets(name);\nCREATE INDEX CONCURRENTLY idx_widgets_id_name ON widgets(id, name);")
+	writeMigration(t, dir, "900002_index_widgets.down.sql", "-- migrate:no-transaction\nDROP INDEX CONCURRENTLY idx_widgets_id_name;\nDROP INDEX CONCURRENTLY idx_widgets_name;")
+
+	var out bytes.Buffer
+	migrator := db.NewMigrator(conn, os.DirFS(dir), &out, false)
+	states := func() map[string]db.MigrationState {
+		t.Helper()
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			t.Fatal("状態の取得失敗:", err)
+		}
+		result := map[string]db.MigrationState{}
+		for _, status := range statuses {
+			result[status.Version] = status.State
+		}
+		return result
+	}
+	indexCount := func() int {
+		t.Helper()
+		var count int
+		if err := conn.QueryRow("SELECT COUNT(*) FROM pg_indexes WHERE tablename = 'widgets' AND indexname LIKE 'idx_widgets_%'").Scan(&count); err != nil {
+			t.Fatal("インデックスの確認失敗:", err)
+		}
+		return count
+	}
+
+	// dry-runではDBを変更しない
+	if err := db.NewMigrator(conn, os.DirFS(dir), &out, true).Up(ctx, 0); err != nil {
+		t.Fatal("dry-runの実行失敗:", err)
+	}
+	if !strings.Contains(out.String(), "CREATE TABLE widgets") || states()["900001"] != db.MigrationPending {
+		t.Fatalf("dry-runの結果が不正: %v\n%s", states(), out.String())
+	}
+
+	// 件数を指定すると古い順にその件数だけ適用する
+	if err := migrator.Up(ctx, 1); err != nil {
+		t.Fatal("マイグレーションの適用失敗:", err)
+	}
+	if s := states(); s["900001"] != db.MigrationApplied || s["900002"] != db.MigrationPending {
+		t.Fatalf("1件だけ適用されていない: %v", s)
+	}
+	// 未適用のマイグレーションがある間はスキーマのバージョンが古いとしてエラーにする
+	if err := migrator.CheckVersion(ctx); err == nil || !strings.Contains(err.Error(), "900002") {
+		t.Errorf("スキーマのバージョンの確認結果が想定と異なる: %v", err)
+	}
+	if err := migrator.Up(ctx, 0); err != nil {
+		t.Fatal("マイグレーションの適用失敗:", err)
+	}
+	if err := migrator.CheckVersion(ctx); err != nil {
+		t.Error("最新のスキーマでエラーになる:", err)
+	}
+	if indexCount() != 2 {
+		t.Fatal("トランザクション外のマイグレーションが適用されていない")
+	}
+
+	// 最後のマイグレーションを適用し直す
+	if err := migrator.Redo(ctx); err != nil {
+		t.Fatal("redoの実行失敗:", err)
+	}
+	if s := states(); s["900002"] != db.MigrationApplied || indexCount() != 2 {
+		t.Fatalf("redoの結果が不正: %v", s)
+	}
+
+	// 適用済みのファイルを変更すると検知してエラーにする
+	writeMigration(t, dir, "900001_create_widgets.up.sql", "CREATE TABLE widgets(id SERIAL PRIMARY KEY, name TEXT);")
+	if states()["900001"] != db.MigrationModified {
+		t.Error("変更したマイグレーションが modified にならない")
+	}
+	if err := migrator.Up(ctx, 0); err == nil || !strings.Contains(err.Error(), "modified") {
+		t.Errorf("変更したマイグレーションでエラーにならない: %v", err)
+	}
+	writeMigration(t, dir, "900001_create_widgets.up.sql", "CREATE TABLE widgets(id SERIAL PRIMARY KEY, name TEXT NOT NULL);")
+
+	// 新しい順に2件ロールバックする
+	if err := migrator.Down(ctx, 2); err != nil {
+		t.Fatal("ロールバック失敗:", err)
+	}
+	if s := states(); s["900001"] != db.MigrationPending || s["900002"] != db.MigrationPending {
+		t.Fatalf("ロールバックされていない: %v", s)
+	}
+	var exists bool
+	if err := conn.QueryRow("SELECT to_regclass('widgets') IS NOT NULL").Scan(&exists); err != nil || exists {
+		t.Errorf("ロールバック後もテーブルが残っている: exists=%v, err=%v", exists, err)
+	}
+}
+
+// 他のプロセスがロックを保持している間の動作と、同時に実行しても1回だけ適用されることを確認する
+func TestMigratorLock(t *testing.T) {
+	conn := testutils.SetupTestDB(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	writeMigration(t, dir, "900001_create_widgets.up.sql", "CREATE TABLE widgets(id SERIAL PRIMARY KEY);")
+	writeMigration(t, dir, "900001_create_widgets.down.sql", "DROP TABLE widgets;")
+
+	// 別の接続でロックを保持する
+	holder, err := conn.Conn(ctx)
+	if err != nil {
+		t.Fatal("接続の取得失敗:", err)
+	}
+	defer holder.Close()
+	if _, err := holder.ExecContext(ctx, "SELECT pg_advisory_lock($1)", db.MigrationLockKey); err != nil {
+		t.Fatal("ロックの取得失敗:", err)
+	}
+
+	var out bytes.Buffer
+	// skipの場合は実行せずに正常終了する
+	skip := db.NewMigrator(conn, os.DirFS(dir), &out, false).WithLock(db.LockOptions{Timeout: time.Second, Policy: db.LockSkip})
+	if err := skip.Up(ctx, 0); err != nil {
+		t.Fatal("skipでエラーになった:", err)
+	}
+	if !strings.Contains(out.String(), "skip migrations") {
+		t.Errorf("スキップしたことが出力されていない: %s", out.String())
+	}
+	// waitの場合はタイムアウトまで待ってエラーにする
+	wait := db.NewMigrator(conn, os.DirFS(dir), &out, false).WithLock(db.LockOptions{Timeout: time.Second, Policy: db.LockWait})
+	if err := wait.Up(ctx, 0); !errors.Is(err, db.ErrMigrationLocked) {
+		t.Fatalf("ロックの待機がタイムアウトしない: %v", err)
+	}
+	statuses, err := wait.Status(ctx)
+	if err != nil {
+		t.Fatal("状態の取得失敗:", err)
+	}
+	for _, status := range statuses {
+		if status.Version == "900001" && status.State != db.MigrationPending {
+			t.Fatalf("ロック中に適用された: %s", status.State)
+		}
+	}
+	if _, err := holder.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", db.MigrationLockKey); err != nil {
+		t.Fatal("ロックの解放失敗:", err)
+	}
+
+	// 同時に実行しても後から実行した方は適用済みとしてスキップする
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- db.NewMigrator(conn, os.DirFS(dir), io.Discard, false).WithLock(db.LockOptions{Timeout: 10 * time.Second, Policy: db.LockWait}).Up(ctx, 0)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("同時実行したマイグレーションが失敗: %v", err)
+		}
+	}
+	var count int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE version = '900001'").Scan(&count); err != nil || count != 1 {
+		t.Errorf("適用の記録が1件ではない: count=%d, err=%v", count, err)
+	}
+}
+
+// 既存のDBではベースラインを実行せずに適用済みとして記録し、まとめたマイグレーションの記録を置き換えることを確認する
+func TestMigratorAdoptBaseline(t *testing.T) {
+	conn := testutils.SetupTestDB(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	writeMigration(t, dir, "900001_create_widgets.up.sql", "CREATE TABLE widgets(id SERIAL PRIMARY KEY);")
+	if err := db.NewMigrator(conn, os.DirFS(dir), io.Discard, false).Up(ctx, 0); err != nil {
+		t.Fatal("マイグレーションの適用失敗:", err)
+	}
+
+	// まとめた最後のマイグレーションまで適用済みでない場合は置き換えない
+	baselineDir := t.TempDir()
+	writeMigration(t, baselineDir, "900003_baseline.up.sql", "-- migrate:baseline 900002\nCREATE TABLE widgets(id SERIAL PRIMARY KEY);\nCREATE TABLE gadgets(id SERIAL PRIMARY KEY);")
+	if err := db.NewMigrator(conn, os.DirFS(baselineDir), io.Discard, false).Up(ctx, 0); err == nil {
+		t.Fatal("適用途中のDBでベースラインを記録できてしまう")
+	}
+
+	writeMigration(t, dir, "900002_create_gadgets.up.sql", "CREATE TABLE gadgets(id SERIAL PRIMARY KEY);")
+	if err := db.NewMigrator(conn, os.DirFS(dir), io.Discard, false).Up(ctx, 0); err != nil {
+		t.Fatal("マイグレーションの適用失敗:", err)
+	}
+	var out bytes.Buffer
+	migrator := db.NewMigrator(conn, os.DirFS(baselineDir), &out, false)
+	if err := migrator.Up(ctx, 0); err != nil {
+		t.Fatal("ベースラインの記録失敗:", err)
+	}
+	if !strings.Contains(out.String(), "adopted baseline migration: 900003_baseline") {
+		t.Errorf("ベースラインを記録したことが出力されていない: %s", out.String())
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal("状態の取得失敗:", err)
+	}
+	if len(statuses) != 1 || statuses[0].Version != "900003" || statuses[0].State != db.MigrationApplied {
+		t.Errorf("まとめたマイグレーションの記録が置き換えられていない: %+v", statuses)
+	}
+}
+
+// マイグレーションから期待されるスキーマとの差分を検出することを確認する
+func TestVerifySchema(t *testing.T) {
+	conn := testutils.SetupTestDB(t)
+	ctx := context.Background()
+	migrator := db.NewMigrator(conn, migrations.FS, io.Discard, false)
+
+	drifts, err := migrator.VerifySchema(ctx)
+	if err != nil {
+		t.Fatal("スキーマの比較失敗:", err)
+	}
+	if len(drifts) != 0 {
+		t.Fatalf("マイグレーションだけで作成したDBに差分がある: %v", drifts)
+	}
+
+	// マイグレーションを使わずにスキーマを変更する
+	for _, statement := range []string{
+		"ALTER TABLE posts ADD COLUMN summary TEXT",
+		"ALTER TABLE users ALTER COLUMN password DROP NOT NULL",
+		"DROP INDEX idx_posts_user_created",
+	} {
+		if _, err := conn.Exec(statement); err != nil {
+			t.Fatal("スキーマの変更失敗:", err)
+		}
+	}
+	drifts, err = migrator.VerifySchema(ctx)
+	if err != nil {
+		t.Fatal("スキーマの比較失敗:", err)
+	}
+	got := map[string]db.DriftKind{}
+	for _, drift := range drifts {
+		got[drift.Object] = drift.Kind
+	}
+	want := map[string]db.DriftKind{
+		"column posts.summary":               db.DriftUnexpected,
+		"column users.password":              db.DriftChanged,
+		"index posts.idx_posts_user_created": db.DriftMissing,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("差分が不正: %v", drifts)
+	}
+	for object, kind := range want {
+		if got[object] != kind {
+			t.Errorf("%s の差分が %q ではない: %v", object, kind, drifts)
+		}
+	}
+	// 一時的なスキーマは残さない
+	var scratch int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM information_schema.schemata WHERE schema_name LIKE 'blogapi_verify_%'").Scan(&scratch); err != nil || scratch != 0 {
+		t.Errorf("一時的なスキーマが残っている: count=%d, err=%v", scratch, err)
+	}
+}
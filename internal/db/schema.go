@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// スキーマの差分の種類
+type DriftKind string
+
+const (
+	DriftMissing    DriftKind = "missing"    // マイグレーションにあるがDBに無い
+	DriftUnexpected DriftKind = "unexpected" // DBにあるがマイグレーションに無い
+	DriftChanged    DriftKind = "changed"    // 定義が異なる
+)
+
+// マイグレーションから期待されるスキーマと実際のスキーマの差分
+type SchemaDrift struct {
+	Kind     DriftKind
+	Object   string // 例: column posts.title / index posts.idx_posts_user_created
+	Expected string
+	Actual   string
+}
+
+// 差分を1行の文字列にする
+func (d SchemaDrift) String() string {
+	switch d.Kind {
+	case DriftMissing:
+		return fmt.Sprintf("missing    %s: %s", d.Object, d.Expected)
+	case DriftUnexpected:
+		return fmt.Sprintf("unexpected %s: %s", d.Object, d.Actual)
+	default:
+		return fmt.Sprintf("changed    %s: expected %s, got %s", d.Object, d.Expected, d.Actual)
+	}
+}
+
+// スキーマの比較に使う列・制約・インデックスの定義を取得するSQL(schema_migrations は比較しない)
+const (
+	selectSchemaColumns = `
+SELECT 'column ' || table_name || '.' || column_name,
+	data_type || CASE WHEN is_nullable = 'NO' THEN ' NOT NULL' ELSE '' END || COALESCE(' DEFAULT ' || column_default, '')
+FROM information_schema.columns
+WHERE table_schema = $1 AND table_name <> 'schema_migrations'`
+	selectSchemaConstraints = `
+SELECT 'constraint ' || c.relname || '.' || con.conname, pg_get_constraintdef(con.oid)
+FROM pg_constraint con
+JOIN pg_class c ON c.oid = con.conrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = $1 AND c.relname <> 'schema_migrations' AND con.contype IN ('p', 'u', 'f', 'c', 'x')`
+	selectSchemaIndexes = `
+SELECT 'index ' || tablename || '.' || indexname, indexdef
+FROM pg_indexes
+WHERE schemaname = $1 AND tablename <> 'schema_migrations'`
+)
+
+// 適用済みのマイグレーションから期待されるスキーマと実際のスキーマを比較して差分を返す
+// 期待されるスキーマは、トランザクション内に作った一時的なスキーマへ適用済みのマイグレーションを実行して作り、最後にロールバックする
+func (m *Migrator) VerifySchema(ctx context.Context) ([]SchemaDrift, error) {
+	migrations, applied, err := m.load(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) == 0 {
+		return nil, errors.New("no applied migrations to verify against")
+	}
+	expected := make([]Migration, 0, len(applied))
+	for _, migration := range migrations {
+		record, ok := applied[migration.Version]
+		if !ok {
+			fmt.Fprintf(m.out, "pending migration %s is not included in the expected schema\n", migration.ID())
+			continue
+		}
+		if record.Checksum != "" && record.Checksum != migration.Checksum {
+			return nil, fmt.Errorf("migration %s has been modified after it was applied (checksum mismatch)", migration.ID())
+		}
+		expected = append(expected, migration)
+	}
+	if len(expected) != len(applied) {
+		return nil, errors.New("some applied migrations have no migration file (see `migrate status`)")
+	}
+
+	tx, err := m.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start schema verification: %w", err)
+	}
+	// 一時的なスキーマも含めてすべて取り消す
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			fmt.Fprintf(m.out, "failed to rollback schema verification: %v\n", err)
+		}
+	}()
+
+	var liveSchema string
+	if err := tx.QueryRowContext(ctx, "SELECT current_schema()").Scan(&liveSchema); err != nil {
+		return nil, fmt.Errorf("failed to get current schema: %w", err)
+	}
+	actual, err := readSchema(ctx, tx, liveSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	// 一時的なスキーマに適用済みのマイグレーションを実行する
+	scratch := fmt.Sprintf("blogapi_verify_%d", time.Now().UnixNano())
+	if _, err := tx.ExecContext(ctx, "CREATE SCHEMA "+scratch); err != nil {
+		return nil, fmt.Errorf("failed to create scratch schema: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "SET LOCAL search_path TO "+scratch); err != nil {
+		return nil, fmt.Errorf("failed to switch to scratch schema: %w", err)
+	}
+	for _, migration := range expected {
+		for _, statement := range scratchStatements(migration.UpSQL) {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return nil, fmt.Errorf("failed to build expected schema from %s: %w", migration.ID(), err)
+			}
+		}
+	}
+	want, err := readSchema(ctx, tx, scratch)
+	if err != nil {
+		return nil, err
+	}
+	return diffSchema(want, actual), nil
+}
+
+// 期待されるスキーマを作るために実行するSQL
+// トランザクションの外で実行する指定があるファイルも空のテーブルに対して同じトランザクションで実行するため、CONCURRENTLY を外して1文ずつ実行する
+func scratchStatements(sqlText string) []string {
+	if !isNoTransaction(sqlText) {
+		return []string{sqlText}
+	}
+	statements := splitStatements(sqlText)
+	for i, statement := range statements {
+		statements[i] = strings.ReplaceAll(statement, " CONCURRENTLY ", " ")
+	}
+	return statements
+}
+
+// 指定したスキーマの列・制約・インデックスの定義を取得する
+// 定義に含まれるスキーマ名は取り除いて、スキーマ名が異なっても比較できるようにする
+func readSchema(ctx context.Context, tx *sql.Tx, schema string) (map[string]string, error) {
+	objects := map[string]string{}
+	for _, query := range []string{selectSchemaColumns, selectSchemaConstraints, selectSchemaIndexes} {
+		rows, err := tx.QueryContext(ctx, query, schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema %s: %w", schema, err)
+		}
+		for rows.Next() {
+			var object, definition string
+			if err := rows.Scan(&object, &definition); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to read schema %s: %w", schema, err)
+			}
+			objects[object] = strings.ReplaceAll(definition, schema+".", "")
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema %s: %w", schema, err)
+		}
+	}
+	return objects, nil
+}
+
+// 期待されるスキーマと実際のスキーマの差分を名前順に返す
+func diffSchema(expected map[string]string, actual map[string]string) []SchemaDrift {
+	drifts := []SchemaDrift{}
+	for object, want := range expected {
+		got, ok := actual[object]
+		switch {
+		case !ok:
+			drifts = append(drifts, SchemaDrift{Kind: DriftMissing, Object: object, Expected: want})
+		case got != want:
+			drifts = append(drifts, SchemaDrift{Kind: DriftChanged, Object: object, Expected: want, Actual: got})
+		}
+	}
+	for object, got := range actual {
+		if _, ok := expected[object]; !ok {
+			drifts = append(drifts, SchemaDrift{Kind: DriftUnexpected, Object: object, Actual: got})
+		}
+	}
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i].Object < drifts[j].Object })
+	return drifts
+}
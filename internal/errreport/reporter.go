@@ -0,0 +1,75 @@
+package errreport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// 外部のエラー監視サービスに報告するエラーの情報
+type Event struct {
+	ID        string    // 報告ごとのID(32文字の16進数、ログと報告を突き合わせるために使う)
+	Time      time.Time // 発生した時刻
+	Type      string    // エラーの種類(panic など)
+	Message   string    // エラーの内容
+	Frames    []Frame   // 発生した場所のスタックトレース(発生した関数が先)
+	RequestID string    // リクエストID
+	Method    string    // リクエストのメソッド
+	Path      string    // リクエストのパス(トークンを含む場合があるためクエリは含めない)
+}
+
+// スタックトレースの1つの関数呼び出し
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// エラーを外部のエラー監視サービスに報告するインターフェース
+// Report はリクエストの処理を止めないように送信を待たずに戻り、Flush でシャットダウン時に送信中の報告を待つ
+type Reporter interface {
+	Report(event Event)
+	Flush(ctx context.Context) error
+}
+
+// 報告先が設定されていない場合に使う何もしない Reporter
+type NopReporter struct{}
+
+// 報告しない
+func (NopReporter) Report(Event) {}
+
+// 待つものは無い
+func (NopReporter) Flush(context.Context) error { return nil }
+
+// IDと時刻を設定したイベントを作成する
+func NewEvent(eventType string, message string) Event {
+	id := make([]byte, 16)
+	// crypto/rand.Read はエラーを返さない
+	_, _ = rand.Read(id)
+	return Event{ID: hex.EncodeToString(id), Time: time.Now().UTC(), Type: eventType, Message: message}
+}
+
+// 呼び出し元のスタックトレースを取得する(skip は CaptureFrames の呼び出し元から数えて飛ばす数)
+// recover を呼び出した defer の関数から呼び出した場合は、panic を起こした関数から取得する
+func CaptureFrames(skip int) []Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var result []Frame
+	for {
+		frame, more := frames.Next()
+		if frame.Function == "runtime.gopanic" {
+			result = nil
+		} else if !strings.HasPrefix(frame.Function, "runtime.") {
+			result = append(result, Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	return result
+}
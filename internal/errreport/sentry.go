@@ -0,0 +1,202 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sentry互換のエラー監視サービスへの送信の設定
+const (
+	sentryTimeout    = 5 * time.Second // 1件の送信のタイムアウト
+	sentryMaxPending = 32              // 同時に送信する報告の上限(超えた報告はログに出して破棄する)
+	sentryClient     = "blogapi/1.0"   // 送信元として伝えるクライアント名
+)
+
+// Sentry互換のエラー監視サービス(Sentry・GlitchTip など)に envelope 形式で報告する Reporter
+// DSN(https://公開キー@ホスト/プロジェクトID)から送信先を求める
+type SentryReporter struct {
+	endpoint    string
+	auth        string
+	environment string
+	client      *http.Client
+	pending     chan struct{}
+	wg          sync.WaitGroup
+}
+
+// DSNを解析して Reporter を作成する(client が nil の場合は既定のクライアントを使う)
+func NewSentryReporter(dsn string, environment string, client *http.Client) (*SentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("error reporting DSN must be scheme://public_key@host/project_id")
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	if slash < 0 || path[slash+1:] == "" {
+		return nil, errors.New("error reporting DSN must include a project id")
+	}
+	if client == nil {
+		client = &http.Client{Timeout: sentryTimeout}
+	}
+	return &SentryReporter{
+		endpoint:    fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, path[:slash], path[slash+1:]),
+		auth:        fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", sentryClient, u.User.Username()),
+		environment: environment,
+		client:      client,
+		pending:     make(chan struct{}, sentryMaxPending),
+	}, nil
+}
+
+// 報告を送信する(送信を待たずに戻る)
+func (r *SentryReporter) Report(event Event) {
+	select {
+	case r.pending <- struct{}{}:
+	default:
+		log.Printf("error report dropped (too many pending reports): event_id=%s", event.ID)
+		return
+	}
+	r.wg.Add(1)
+	go func() {
+		defer func() {
+			<-r.pending
+			r.wg.Done()
+		}()
+		if err := r.send(event); err != nil {
+			log.Printf("failed to send error report: event_id=%s: %v", event.ID, err)
+		}
+	}()
+}
+
+// 送信中の報告が終わるのを待つ(ctx の期限を過ぎた場合はエラーを返す)
+func (r *SentryReporter) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d error reports not sent: %w", len(r.pending), ctx.Err())
+	}
+}
+
+// 報告を1件送信する
+func (r *SentryReporter) send(event Event) error {
+	body, err := r.envelope(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sentryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sentryのイベントの形式
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Environment string            `json:"environment,omitempty"`
+	Exception   sentryExceptions  `json:"exception"`
+	Request     *sentryRequest    `json:"request,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+// フレームは呼び出し元が先(発生した関数が最後)
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+}
+
+type sentryRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// イベントを envelope 形式(ヘッダー・アイテムのヘッダー・イベントを改行区切りにしたもの)に変換する
+func (r *SentryReporter) envelope(event Event) ([]byte, error) {
+	payload := sentryEvent{
+		EventID:     event.ID,
+		Timestamp:   event.Time.UTC().Format(time.RFC3339Nano),
+		Platform:    "go",
+		Level:       "error",
+		Environment: r.environment,
+		Exception:   sentryExceptions{Values: []sentryException{{Type: event.Type, Value: event.Message}}},
+	}
+	if len(event.Frames) > 0 {
+		frames := make([]sentryFrame, len(event.Frames))
+		for i, frame := range event.Frames {
+			frames[len(frames)-1-i] = sentryFrame{Function: frame.Function, AbsPath: frame.File, Lineno: frame.Line}
+		}
+		payload.Exception.Values[0].Stacktrace = &sentryStacktrace{Frames: frames}
+	}
+	if event.Method != "" {
+		payload.Request = &sentryRequest{Method: event.Method, URL: event.Path}
+	}
+	if event.RequestID != "" {
+		payload.Tags = map[string]string{"request_id": event.RequestID}
+	}
+
+	item, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	header, err := json.Marshal(map[string]string{"event_id": event.ID, "sent_at": time.Now().UTC().Format(time.RFC3339Nano)})
+	if err != nil {
+		return nil, err
+	}
+	itemHeader, err := json.Marshal(map[string]any{"type": "event", "length": len(item)})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, line := range [][]byte{header, itemHeader, item} {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,102 @@
+package errreport_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/errreport"
+)
+
+// DSNから求めた送信先に envelope 形式で報告することを確認する
+func TestSentryReporter(t *testing.T) {
+	type received struct {
+		path, auth string
+		body       []byte
+	}
+	requests := make(chan received, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{path: r.URL.Path, auth: r.Header.Get("X-Sentry-Auth"), body: body}
+	}))
+	defer srv.Close()
+
+	dsn := strings.Replace(srv.URL, "http://", "http://public-key@", 1) + "/errors/42"
+	reporter, err := errreport.NewSentryReporter(dsn, "test", nil)
+	if err != nil {
+		t.Fatal("Reporterの作成失敗:", err)
+	}
+	event := errreport.NewEvent("panic", "boom")
+	event.RequestID = "req-123"
+	event.Method, event.Path = http.MethodGet, "/api/posts"
+	event.Frames = []errreport.Frame{{Function: "main.crash", File: "main.go", Line: 10}, {Function: "main.handler", File: "main.go", Line: 20}}
+	reporter.Report(event)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := reporter.Flush(ctx); err != nil {
+		t.Fatal("送信の完了待ち失敗:", err)
+	}
+	req := <-requests
+	if req.path != "/errors/api/42/envelope/" || !strings.Contains(req.auth, "sentry_key=public-key") {
+		t.Errorf("送信先が想定と異なる: path=%s auth=%s", req.path, req.auth)
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(req.body, []byte("\n")), []byte("\n"))
+	if len(lines) != 3 {
+		t.Fatalf("envelope の形式が想定と異なる:\n%s", req.body)
+	}
+	var header, item struct {
+		EventID string `json:"event_id"`
+		Type    string `json:"type"`
+		Length  int    `json:"length"`
+	}
+	if err := json.Unmarshal(lines[0], &header); err != nil || header.EventID != event.ID {
+		t.Errorf("envelope のヘッダーが想定と異なる: %s", lines[0])
+	}
+	if err := json.Unmarshal(lines[1], &item); err != nil || item.Type != "event" || item.Length != len(lines[2]) {
+		t.Errorf("アイテムのヘッダーが想定と異なる: %s", lines[1])
+	}
+	var payload struct {
+		Environment string            `json:"environment"`
+		Tags        map[string]string `json:"tags"`
+		Exception   struct {
+			Values []struct {
+				Type       string `json:"type"`
+				Value      string `json:"value"`
+				Stacktrace struct {
+					Frames []struct {
+						Function string `json:"function"`
+					} `json:"frames"`
+				} `json:"stacktrace"`
+			} `json:"values"`
+		} `json:"exception"`
+	}
+	if err := json.Unmarshal(lines[2], &payload); err != nil {
+		t.Fatal("イベントの解析失敗:", err)
+	}
+	if payload.Environment != "test" || payload.Tags["request_id"] != "req-123" || len(payload.Exception.Values) != 1 {
+		t.Fatalf("イベントが想定と異なる: %s", lines[2])
+	}
+	// Sentryのフレームは呼び出し元が先で、panic を起こした関数が最後になる
+	exception := payload.Exception.Values[0]
+	frames := exception.Stacktrace.Frames
+	if exception.Type != "panic" || exception.Value != "boom" || len(frames) != 2 || frames[1].Function != "main.crash" {
+		t.Errorf("例外の内容が想定と異なる: %s", lines[2])
+	}
+}
+
+// 不正なDSNがエラーになることを確認する
+func TestNewSentryReporterInvalidDSN(t *testing.T) {
+	for _, dsn := range []string{"not a url", "https://sentry.example.com/1", "https://key@sentry.example.com", "ftp://key@sentry.example.com/1"} {
+		if _, err := errreport.NewSentryReporter(dsn, "", nil); err == nil {
+			t.Errorf("%s: エラーにならない", dsn)
+		}
+	}
+}
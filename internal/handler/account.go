@@ -0,0 +1,318 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/service"
+	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
+)
+
+// RequestAccountDeletionHandler godoc
+// @Summary アカウント削除を予約する
+// @Description 猶予期間(30日)の経過後にアカウントを削除する。anonymize は投稿・コメント・いいねを残してユーザー情報を匿名化し、purge はすべて削除する
+// @Description 予約済みの場合は削除方式と実行日時を更新する
+// @Description
+// @Description **エラー条件:**
+// @Description - 無効なリクエスト、削除方式が不正 → 400 Bad Request
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - APIキーでの操作 → 403 Forbidden
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param deletion body models.AccountDeletionRequest true "削除方式(anonymize / purge)"
+// @Success 202 {object} models.AccountDeletion
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 403 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/me [delete]
+func RequestAccountDeletionHandler(accountService *service.AccountService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// アカウント削除はJWTでのみ許可する
+		if appErr := requireJWTAuth(ctx); appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// リクエストボディを読み取る
+		var req models.AccountDeletionRequest
+		if appErr := decodeJSON(r, &req); appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// 削除方式のバリデーションを実施する
+		if err := validateAccountDeletionInput(req); err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		// アカウント削除を予約する
+		deletion, err := accountService.RequestDeletion(ctx, userID, req.Mode)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusAccepted, deletion)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "account_deletion_requested", UserID: userID})
+	}
+}
+
+// GetAccountDeletionHandler godoc
+// @Summary アカウント削除の予約を取得する
+// @Description ログインユーザーのアカウント削除の予約状況を取得する
+// @Description
+// @Description **エラー条件:**
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - 削除が予約されていない → 404 Not Found
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags account
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} models.AccountDeletion
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/me/deletion [get]
+func GetAccountDeletionHandler(accountService *service.AccountService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// アカウント削除の予約を取得する
+		deletion, err := accountService.GetDeletion(ctx, userID)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, deletion)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "account_deletion_fetched", UserID: userID})
+	}
+}
+
+// CancelAccountDeletionHandler godoc
+// @Summary アカウント削除の予約を取り消す
+// @Description 猶予期間中のアカウント削除の予約を取り消す
+// @Description
+// @Description **エラー条件:**
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - APIキーでの操作 → 403 Forbidden
+// @Description - 削除が予約されていない → 404 Not Found
+// @Description - データ更新/取得失敗 → 500 ServerError
+// @Tags account
+// @Param Authorization header string true "Bearer Token"
+// @Success 204 "No Content"
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 403 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/me/deletion [delete]
+func CancelAccountDeletionHandler(accountService *service.AccountService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// アカウント削除の取り消しはJWTでのみ許可する
+		if appErr := requireJWTAuth(ctx); appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// アカウント削除の予約を取り消す
+		if err := accountService.CancelDeletion(ctx, userID); err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusNoContent, nil)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "account_deletion_cancelled", UserID: userID})
+	}
+}
+
+// RequestDataExportHandler godoc
+// @Summary データエクスポートを依頼する
+// @Description ログインユーザーの投稿・コメント・いいね・監視イベントのエクスポートを依頼する。エクスポートは非同期で生成される
+// @Description 処理待ち・処理中のエクスポートがある場合はそれを返す
+// @Description
+// @Description **エラー条件:**
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - APIキーでの操作 → 403 Forbidden
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags account
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Success 202 {object} models.DataExport
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 403 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/me/export [post]
+func RequestDataExportHandler(accountService *service.AccountService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// 全データのエクスポートはJWTでのみ許可する
+		if appErr := requireJWTAuth(ctx); appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// データエクスポートを受け付ける
+		export, err := accountService.RequestExport(ctx, userID)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusAccepted, export)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "data_export_requested", UserID: userID})
+	}
+}
+
+// GetDataExportHandler godoc
+// @Summary データエクスポートの状態を取得する
+// @Description 最新のデータエクスポートの状態(pending / running / completed / failed)を取得する。completed になったら /api/me/export/download からダウンロードできる
+// @Description
+// @Description **エラー条件:**
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - エクスポートが依頼されていない → 404 Not Found
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags account
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} models.DataExport
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/me/export [get]
+func GetDataExportHandler(accountService *service.AccountService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// 最新のデータエクスポートを取得する
+		export, err := accountService.GetLatestExport(ctx, userID)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, export)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "data_export_fetched", UserID: userID})
+	}
+}
+
+// DownloadDataExportHandler godoc
+// @Summary データエクスポートをダウンロードする
+// @Description 生成済みのデータエクスポート(JSONファイルをまとめたZIP)をダウンロードする
+// @Description
+// @Description **エラー条件:**
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - APIキーでの操作 → 403 Forbidden
+// @Description - エクスポートが依頼されていない、ダウンロード期限切れ → 404 Not Found
+// @Description - エクスポートが未完了 → 409 Conflict
+// @Description - データ更新/取得失敗 → 500 ServerError
+// @Tags account
+// @Produce application/zip
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {file} file
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 403 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 409 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/me/export/download [get]
+func DownloadDataExportHandler(accountService *service.AccountService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// 全データのダウンロードはJWTでのみ許可する
+		if appErr := requireJWTAuth(ctx); appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// エクスポートしたアーカイブを取得する
+		archive, err := accountService.DownloadExport(ctx, userID)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="blogapi-export-%d.zip"`, userID))
+		w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(archive); err != nil {
+			log.Printf("Failed to write export archive: %v", err)
+			return
+		}
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "data_export_downloaded", UserID: userID})
+	}
+}
@@ -0,0 +1,285 @@
+package handler_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/app"
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
+	"github.com/yusuke-hoguro/BlogApi/internal/handler"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/testutils"
+)
+
+// サービスを直接呼び出せるようにテスト用サーバーを起動する(configureで設定を変更できる)
+func setupAccountTestServer(t *testing.T, configure ...func(*config.Config)) (*sql.DB, *app.Services, *httptest.Server, func()) {
+	t.Helper()
+	db := testutils.SetupTestDB(t)
+	cfg := testutils.TestConfig()
+	for _, fn := range configure {
+		fn(cfg)
+	}
+	services := app.NewServices(db, cfg)
+	h, cleanup := testutils.SetupTestServerWithServices(services)
+	server := httptest.NewServer(h)
+	return db, services, server, func() {
+		server.Close()
+		cleanup()
+		db.Close()
+	}
+}
+
+// 猶予期間を過ぎた状態にしてアカウント削除を実行するヘルパー
+func executeAccountDeletion(t *testing.T, db *sql.DB, services *app.Services, userID int) {
+	t.Helper()
+	if _, err := db.Exec("UPDATE account_deletions SET scheduled_at = NOW() - INTERVAL '1 minute' WHERE user_id = $1", userID); err != nil {
+		t.Fatal("削除予約の更新失敗:", err)
+	}
+	if err := services.Account.ProcessDueDeletions(context.Background()); err != nil {
+		t.Fatal("アカウント削除の実行失敗:", err)
+	}
+}
+
+// アカウント削除の予約・確認・取り消しをテストする
+func TestAccountDeletionScheduleAndCancel(t *testing.T) {
+	_, _, server, cleanup := setupAccountTestServer(t)
+	defer cleanup()
+
+	token, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	bearer := "Bearer " + token
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{name: "削除方式が不正", method: http.MethodDelete, path: "/api/me", body: `{"mode": "soft"}`, want: http.StatusBadRequest},
+		{name: "予約前の確認", method: http.MethodGet, path: "/api/me/deletion", want: http.StatusNotFound},
+		{name: "削除を予約", method: http.MethodDelete, path: "/api/me", body: `{"mode": "anonymize"}`, want: http.StatusAccepted},
+		{name: "予約後の確認", method: http.MethodGet, path: "/api/me/deletion", want: http.StatusOK},
+		{name: "予約を取り消し", method: http.MethodDelete, path: "/api/me/deletion", want: http.StatusNoContent},
+		{name: "取り消し済みの取り消し", method: http.MethodDelete, path: "/api/me/deletion", want: http.StatusNotFound},
+	}
+
+	// 順番に実行して状態の遷移を確認する
+	for _, tt := range tests {
+		if status := doWithAuthorization(t, server, tt.method, tt.path, bearer, tt.body); status != tt.want {
+			t.Errorf("[%s] 期待するステータスコード %d, 実際は %d", tt.name, tt.want, status)
+		}
+	}
+}
+
+// purgeで削除した場合にユーザーと投稿・コメント・いいねが削除されることを確認する
+func TestAccountDeletionPurge(t *testing.T) {
+	db, services, server, cleanup := setupAccountTestServer(t)
+	defer cleanup()
+
+	token, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	if status := doWithAuthorization(t, server, http.MethodDelete, "/api/me", "Bearer "+token, `{"mode": "purge"}`); status != http.StatusAccepted {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusAccepted, status)
+	}
+	var postCount int
+	if err := db.QueryRow("SELECT COUNT(*) FROM posts WHERE user_id = 1").Scan(&postCount); err != nil || postCount == 0 {
+		t.Fatalf("削除前の投稿の件数取得失敗: count=%d, err=%v", postCount, err)
+	}
+	executeAccountDeletion(t, db, services, 1)
+
+	// 削除した投稿ごとに削除イベントがアウトボックスに書き込まれる
+	var events int
+	if err := db.QueryRow("SELECT COUNT(*) FROM outbox_events WHERE event_type = $1", models.DomainEventPostDeleted).Scan(&events); err != nil {
+		t.Fatal("アウトボックスの件数取得失敗:", err)
+	}
+	if events != postCount {
+		t.Errorf("投稿の削除イベントが %d件, 削除した投稿は %d件", events, postCount)
+	}
+
+	queries := map[string]string{
+		"users":    "SELECT COUNT(*) FROM users WHERE id = 1",
+		"posts":    "SELECT COUNT(*) FROM posts WHERE user_id = 1",
+		"comments": "SELECT COUNT(*) FROM comments WHERE user_id = 1",
+		"likes":    "SELECT COUNT(*) FROM likes WHERE user_id = 1",
+	}
+	for table, query := range queries {
+		var count int
+		if err := db.QueryRow(query).Scan(&count); err != nil {
+			t.Fatalf("%s の件数取得失敗: %v", table, err)
+		}
+		if count != 0 {
+			t.Errorf("%s が削除されていない: %d件", table, count)
+		}
+	}
+}
+
+// anonymizeで削除した場合に投稿は残りユーザー情報が匿名化されることを確認する
+func TestAccountDeletionAnonymize(t *testing.T) {
+	db, services, server, cleanup := setupAccountTestServer(t)
+	defer cleanup()
+
+	token, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	if status := doWithAuthorization(t, server, http.MethodDelete, "/api/me", "Bearer "+token, `{"mode": "anonymize"}`); status != http.StatusAccepted {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusAccepted, status)
+	}
+	executeAccountDeletion(t, db, services, 1)
+
+	var username string
+	if err := db.QueryRow("SELECT username FROM users WHERE id = 1").Scan(&username); err != nil {
+		t.Fatal("ユーザーの取得失敗:", err)
+	}
+	if username == "testuser" {
+		t.Error("ユーザー名が匿名化されていない")
+	}
+
+	var posts int
+	if err := db.QueryRow("SELECT COUNT(*) FROM posts WHERE user_id = 1").Scan(&posts); err != nil {
+		t.Fatal("投稿の件数取得失敗:", err)
+	}
+	if posts == 0 {
+		t.Error("匿名化で投稿が削除されている")
+	}
+}
+
+// 削除前に発行したJWTが削除後に使えないことを確認する
+func TestAccountDeletionRevokesToken(t *testing.T) {
+	for _, mode := range []string{models.AccountDeletionModeAnonymize, models.AccountDeletionModePurge} {
+		t.Run(mode, func(t *testing.T) {
+			db, services, server, cleanup := setupAccountTestServer(t)
+			defer cleanup()
+
+			// 削除前にJWTを発行しておく
+			token, err := handler.GenerateJWT(1)
+			if err != nil {
+				t.Fatal("JWTの生成に失敗:", err)
+			}
+			bearer := "Bearer " + token
+			if status := doWithAuthorization(t, server, http.MethodDelete, "/api/me", bearer, `{"mode": "`+mode+`"}`); status != http.StatusAccepted {
+				t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusAccepted, status)
+			}
+			executeAccountDeletion(t, db, services, 1)
+
+			// 投稿・コメント・いいねのいずれも受け付けない
+			requests := []struct {
+				method string
+				path   string
+				body   string
+			}{
+				{http.MethodPost, "/api/posts", `{"title": "削除後の投稿", "content": "本文"}`},
+				{http.MethodPost, "/api/posts/3/comments", `{"content": "削除後のコメント"}`},
+				{http.MethodPost, "/api/posts/3/like", ""},
+			}
+			for _, req := range requests {
+				if status := doWithAuthorization(t, server, req.method, req.path, bearer, req.body); status != http.StatusUnauthorized {
+					t.Errorf("[%s %s] 期待するステータスコード %d, 実際は %d", req.method, req.path, http.StatusUnauthorized, status)
+				}
+			}
+
+			var posts int
+			if err := db.QueryRow("SELECT COUNT(*) FROM posts WHERE title = '削除後の投稿'").Scan(&posts); err != nil {
+				t.Fatal("投稿の件数取得失敗:", err)
+			}
+			if posts != 0 {
+				t.Errorf("削除後のJWTで投稿が作成された: %d件", posts)
+			}
+		})
+	}
+}
+
+// データエクスポートの依頼からダウンロードまでをテストする
+func TestDataExport(t *testing.T) {
+	_, services, server, cleanup := setupAccountTestServer(t)
+	defer cleanup()
+
+	token, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	bearer := "Bearer " + token
+
+	// エクスポートを依頼する(生成前のダウンロードは409になる)
+	if status := doWithAuthorization(t, server, http.MethodPost, "/api/me/export", bearer, ""); status != http.StatusAccepted {
+		t.Fatalf("[依頼] 期待するステータスコード %d, 実際は %d", http.StatusAccepted, status)
+	}
+	if status := doWithAuthorization(t, server, http.MethodGet, "/api/me/export/download", bearer, ""); status != http.StatusConflict {
+		t.Errorf("[生成前] 期待するステータスコード %d, 実際は %d", http.StatusConflict, status)
+	}
+
+	// 定期処理の代わりに直接エクスポートを生成する
+	if err := services.Account.ProcessPendingExports(context.Background()); err != nil {
+		t.Fatal("エクスポートの生成失敗:", err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/me/export", nil)
+	if err != nil {
+		t.Fatal("リクエスト生成失敗:", err)
+	}
+	req.Header.Set("Authorization", bearer)
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal("HTTPリクエスト失敗:", err)
+	}
+	defer resp.Body.Close()
+	var export models.DataExport
+	if err := json.NewDecoder(resp.Body).Decode(&export); err != nil {
+		t.Fatalf("JSONのデコード失敗: %v", err)
+	}
+	if export.Status != models.DataExportStatusCompleted {
+		t.Fatalf("エクスポートが完了していない: %s", export.Status)
+	}
+
+	// ZIPに投稿が含まれていることを確認する
+	req, err = http.NewRequest(http.MethodGet, server.URL+"/api/me/export/download", nil)
+	if err != nil {
+		t.Fatal("リクエスト生成失敗:", err)
+	}
+	req.Header.Set("Authorization", bearer)
+	resp2, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal("HTTPリクエスト失敗:", err)
+	}
+	defer resp2.Body.Close()
+	if resp2.StatusCode != http.StatusOK {
+		t.Fatalf("[ダウンロード] 期待するステータスコード %d, 実際は %d", http.StatusOK, resp2.StatusCode)
+	}
+	body, err := io.ReadAll(resp2.Body)
+	if err != nil {
+		t.Fatal("レスポンスの読み込み失敗:", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal("ZIPの読み込み失敗:", err)
+	}
+	var posts []models.Post
+	for _, file := range zr.File {
+		if file.Name != "posts.json" {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal("posts.jsonの読み込み失敗:", err)
+		}
+		if err := json.NewDecoder(rc).Decode(&posts); err != nil {
+			t.Fatalf("JSONのデコード失敗: %v", err)
+		}
+		rc.Close()
+	}
+	if len(posts) != 1 || posts[0].UserID != 1 {
+		t.Errorf("エクスポートした投稿が想定と異なる: %+v", posts)
+	}
+}
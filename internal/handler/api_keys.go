@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/service"
+	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
+)
+
+// CreateAPIKeyHandler godoc
+// @Summary APIキーを発行する
+// @Description 名前とスコープ(read / write)を指定して個人用APIキーを発行する。キー本体はこのレスポンスでのみ返す
+// @Description
+// @Description **エラー条件:**
+// @Description - 無効なリクエスト、名前が空、名前が100文字より大きい、未知のスコープ → 400 Bad Request
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - APIキーでの操作 → 403 Forbidden
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags apikeys
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param apikey body models.APIKeyRequest true "APIキーの名前とスコープ"
+// @Success 201 {object} models.APIKeyCreatedResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 403 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/apikeys [post]
+func CreateAPIKeyHandler(apiKeyService *service.APIKeyService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// APIキーで新しいAPIキーを発行できないようにする
+		if appErr := requireJWTAuth(ctx); appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// リクエストボディを読み取る
+		var req models.APIKeyRequest
+		if appErr := decodeJSON(r, &req); appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// APIキーのバリデーションを実施する
+		if err := validateAPIKeyInput(&req); err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		// APIキーを発行する
+		created, err := apiKeyService.CreateAPIKey(ctx, userID, req)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusCreated, created)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "api_key_created", UserID: userID})
+	}
+}
+
+// ListAPIKeysHandler godoc
+// @Summary APIキーの一覧を取得する
+// @Description ログインユーザーが発行したAPIキーの一覧(失効済みを含む)を取得する。キー本体は含まない
+// @Description
+// @Description **エラー条件:**
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags apikeys
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {array} models.APIKey
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/apikeys [get]
+func ListAPIKeysHandler(apiKeyService *service.APIKeyService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// APIキーの一覧を取得する
+		keys, err := apiKeyService.ListAPIKeys(ctx, userID)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, keys)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "api_keys_fetched", UserID: userID})
+	}
+}
+
+// RevokeAPIKeyHandler godoc
+// @Summary APIキーを失効させる
+// @Description 指定したIDのAPIキーを失効させる
+// @Description
+// @Description **エラー条件:**
+// @Description - 無効なID → 400 Bad Request
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - APIキーでの操作 → 403 Forbidden
+// @Description - APIキーが存在しない、失効済み、他のユーザーのキー → 404 Not Found
+// @Description - データ更新/取得失敗 → 500 ServerError
+// @Tags apikeys
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "APIキーID"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 403 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/apikeys/{id} [delete]
+func RevokeAPIKeyHandler(apiKeyService *service.APIKeyService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// APIキーの管理はJWTでのみ許可する
+		if appErr := requireJWTAuth(ctx); appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// URIからAPIキーのIDを取得
+		vars := mux.Vars(r)
+		keyID, appErr := parseID(vars["id"])
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// APIキーを失効させる
+		if err := apiKeyService.RevokeAPIKey(ctx, userID, keyID); err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusNoContent, nil)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "api_key_revoked", UserID: userID})
+	}
+}
@@ -0,0 +1,257 @@
+package handler_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/handler"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/testutils"
+)
+
+// APIキーを発行するヘルパー
+func createAPIKey(t *testing.T, server *httptest.Server, token string, body string) models.APIKeyCreatedResponse {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/api/apikeys", strings.NewReader(body))
+	if err != nil {
+		t.Fatal("リクエスト生成失敗:", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal("HTTPリクエスト失敗:", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusCreated, resp.StatusCode)
+	}
+	var created models.APIKeyCreatedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("JSONのデコード失敗: %v", err)
+	}
+	return created
+}
+
+// 指定したAuthorizationヘッダーでリクエストを送信してステータスコードを返すヘルパー
+func doWithAuthorization(t *testing.T, server *httptest.Server, method string, path string, authorization string, body string) int {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal("リクエスト生成失敗:", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal("HTTPリクエスト失敗:", err)
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+// APIキーの発行・利用・失効の一連の流れをテストする
+func TestAPIKeyLifecycle(t *testing.T) {
+	// テスト用DBのセットアップを開始する
+	db := testutils.SetupTestDB(t)
+	defer db.Close()
+
+	// テスト用サーバーのセットアップ
+	h, cleanup := testutils.SetupTestServer(db)
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer cleanup()
+
+	token, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+
+	// 読み取り専用のAPIキーを発行する
+	created := createAPIKey(t, server, token, `{"name": "script"}`)
+	if created.Key == "" || len(created.Scopes) != 1 || created.Scopes[0] != models.APIKeyScopeRead {
+		t.Fatalf("発行したAPIキーが想定と異なる: %+v", created)
+	}
+
+	// APIキーで認証必須のGET APIを呼び出せる
+	if status := doWithAuthorization(t, server, http.MethodGet, "/api/myposts", "ApiKey "+created.Key, ""); status != http.StatusOK {
+		t.Errorf("[read GET] 期待するステータスコード %d, 実際は %d", http.StatusOK, status)
+	}
+
+	// 読み取り専用キーでは投稿できない
+	postJSON := `{"title": "APIキー投稿", "content": "APIキーからの投稿"}`
+	if status := doWithAuthorization(t, server, http.MethodPost, "/api/posts", "ApiKey "+created.Key, postJSON); status != http.StatusForbidden {
+		t.Errorf("[read POST] 期待するステータスコード %d, 実際は %d", http.StatusForbidden, status)
+	}
+
+	// APIキーで新しいAPIキーは発行できない
+	if status := doWithAuthorization(t, server, http.MethodPost, "/api/apikeys", "ApiKey "+created.Key, `{"name": "escalate"}`); status != http.StatusForbidden {
+		t.Errorf("[APIキーで発行] 期待するステータスコード %d, 実際は %d", http.StatusForbidden, status)
+	}
+
+	// 一覧にキー本体は含まれず、最終利用日時が記録されている
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/apikeys", nil)
+	if err != nil {
+		t.Fatal("リクエスト生成失敗:", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal("HTTPリクエスト失敗:", err)
+	}
+	defer resp.Body.Close()
+	var keys []map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&keys); err != nil {
+		t.Fatalf("JSONのデコード失敗: %v", err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("APIキーの件数が一致しない: get %d, want 1", len(keys))
+	}
+	if _, ok := keys[0]["key"]; ok {
+		t.Error("一覧にキー本体が含まれている")
+	}
+	if keys[0]["last_used_at"] == nil {
+		t.Error("最終利用日時が記録されていない")
+	}
+
+	// 失効させると使えなくなる
+	revokePath := fmt.Sprintf("/api/apikeys/%d", created.ID)
+	if status := doWithAuthorization(t, server, http.MethodDelete, revokePath, "Bearer "+token, ""); status != http.StatusNoContent {
+		t.Errorf("[失効] 期待するステータスコード %d, 実際は %d", http.StatusNoContent, status)
+	}
+	if status := doWithAuthorization(t, server, http.MethodGet, "/api/myposts", "ApiKey "+created.Key, ""); status != http.StatusUnauthorized {
+		t.Errorf("[失効後] 期待するステータスコード %d, 実際は %d", http.StatusUnauthorized, status)
+	}
+}
+
+// 書き込みスコープのAPIキーで投稿を作成できることを確認する
+func TestAPIKeyWriteScope(t *testing.T) {
+	// テスト用DBのセットアップを開始する
+	db := testutils.SetupTestDB(t)
+	defer db.Close()
+
+	// テスト用サーバーのセットアップ
+	h, cleanup := testutils.SetupTestServer(db)
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer cleanup()
+
+	token, err := handler.GenerateJWT(2)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	created := createAPIKey(t, server, token, `{"name": "writer", "scopes": ["write"]}`)
+
+	postJSON := `{"title": "APIキー投稿", "content": "APIキーからの投稿"}`
+	if status := doWithAuthorization(t, server, http.MethodPost, "/api/posts", "ApiKey "+created.Key, postJSON); status != http.StatusCreated {
+		t.Errorf("期待するステータスコード %d, 実際は %d", http.StatusCreated, status)
+	}
+}
+
+// 不正なAPIキー・不正な入力を拒否することを確認する
+func TestAPIKeyInvalidInput(t *testing.T) {
+	// テスト用DBのセットアップを開始する
+	db := testutils.SetupTestDB(t)
+	defer db.Close()
+
+	// テスト用サーバーのセットアップ
+	h, cleanup := testutils.SetupTestServer(db)
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer cleanup()
+
+	token, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		body          string
+		want          int
+	}{
+		{name: "存在しないAPIキー", method: http.MethodGet, path: "/api/myposts", authorization: "ApiKey blog_000000000000_invalid", want: http.StatusUnauthorized},
+		{name: "書式が不正なAPIキー", method: http.MethodGet, path: "/api/myposts", authorization: "ApiKey invalid", want: http.StatusUnauthorized},
+		{name: "名前が空", method: http.MethodPost, path: "/api/apikeys", authorization: "Bearer " + token, body: `{"name": ""}`, want: http.StatusBadRequest},
+		{name: "未知のスコープ", method: http.MethodPost, path: "/api/apikeys", authorization: "Bearer " + token, body: `{"name": "x", "scopes": ["admin"]}`, want: http.StatusBadRequest},
+		{name: "存在しないAPIキーの失効", method: http.MethodDelete, path: "/api/apikeys/9999", authorization: "Bearer " + token, want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := doWithAuthorization(t, server, tt.method, tt.path, tt.authorization, tt.body); status != tt.want {
+				t.Errorf("期待するステータスコード %d, 実際は %d", tt.want, status)
+			}
+		})
+	}
+}
+
+// APIキーで認証された書き込みの監視イベントに、使用したキーが記録されることを確認する
+func TestAPIKeyAuditLog(t *testing.T) {
+	db, _, server, cleanup := setupAccountTestServer(t)
+	defer cleanup()
+
+	token, err := handler.GenerateJWT(2)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	created := createAPIKey(t, server, token, `{"name": "writer", "scopes": ["write"]}`)
+
+	postJSON := `{"title": "APIキー投稿", "content": "APIキーからの投稿"}`
+	if status := doWithAuthorization(t, server, http.MethodPost, "/api/posts", "ApiKey "+created.Key, postJSON); status != http.StatusCreated {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusCreated, status)
+	}
+	// JWTでの書き込みにはキーを記録しない
+	if status := doWithAuthorization(t, server, http.MethodPost, "/api/posts", "Bearer "+token, postJSON); status != http.StatusCreated {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusCreated, status)
+	}
+
+	// 監視イベントは非同期で保存されるため、保存されるまで待つ
+	type auditRow struct {
+		apiKeyID     sql.NullInt64
+		apiKeyPrefix sql.NullString
+	}
+	var rows []auditRow
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rows = nil
+		result, err := db.Query("SELECT api_key_id, api_key_prefix FROM audit_logs WHERE action = 'post_created' AND user_id = 2 ORDER BY id")
+		if err != nil {
+			t.Fatal("監視イベントの取得失敗:", err)
+		}
+		for result.Next() {
+			var row auditRow
+			if err := result.Scan(&row.apiKeyID, &row.apiKeyPrefix); err != nil {
+				t.Fatal("監視イベントの読み込み失敗:", err)
+			}
+			rows = append(rows, row)
+		}
+		result.Close()
+		if len(rows) == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("監視イベントの件数が想定と異なる: %+v", rows)
+	}
+	if int(rows[0].apiKeyID.Int64) != created.ID || rows[0].apiKeyPrefix.String != created.Prefix {
+		t.Errorf("APIキーの記録が想定と異なる: %+v, キー: id=%d prefix=%s", rows[0], created.ID, created.Prefix)
+	}
+	if rows[1].apiKeyID.Valid || rows[1].apiKeyPrefix.Valid {
+		t.Errorf("JWTでの書き込みにAPIキーが記録された: %+v", rows[1])
+	}
+}
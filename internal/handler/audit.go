@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 
+	"github.com/yusuke-hoguro/BlogApi/internal/middleware"
 	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
 )
 
@@ -12,6 +13,11 @@ func enqueueAuditEvent(ctx context.Context, auditPool *workerpool.AuditWorkerPoo
 	if auditPool == nil {
 		return
 	}
+	// APIキーで認証されたリクエストはどのキーで操作したかを記録する
+	if id, prefix, ok := middleware.APIKeyFromContext(ctx); ok {
+		event.APIKeyID = id
+		event.APIKeyPrefix = prefix
+	}
 	if err := auditPool.Enqueue(ctx, event); err != nil {
 		log.Printf("Failed to enqueue audit event: %v", err)
 	}
@@ -20,8 +20,8 @@ import (
 // @Produce json
 // @Param id path int true "投稿ID"
 // @Success 200 {array} models.Comment
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
 // @Router /api/posts/{id}/comments [get]
 func GetCommentsByPostIDHandler(commentService *service.CommentService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -32,14 +32,14 @@ func GetCommentsByPostIDHandler(commentService *service.CommentService, auditPoo
 		vars := mux.Vars(r)
 		postID, appErr := parseID(vars["id"])
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
 		// 指定した投稿のコメントをすべて取得する
 		comments, err := commentService.GetCommentsByPostID(ctx, postID)
 		if err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 
@@ -63,9 +63,9 @@ func GetCommentsByPostIDHandler(commentService *service.CommentService, auditPoo
 // @Produce json
 // @Param id path int true "コメントID"
 // @Success 200 {object} models.Comment
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 404 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
 // @Router /api/comments/{id} [get]
 func GetCommentsByIDHandler(commentService *service.CommentService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -76,14 +76,14 @@ func GetCommentsByIDHandler(commentService *service.CommentService, auditPool *w
 		vars := mux.Vars(r)
 		id, appErr := parseID(vars["id"])
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
 		// 指定したIDのコメントを取得する
 		comment, err := commentService.GetCommentByID(ctx, id)
 		if err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 
@@ -110,9 +110,9 @@ func GetCommentsByIDHandler(commentService *service.CommentService, auditPool *w
 // @Param id path int true "投稿ID"
 // @Param post body models.Comment true "コメント内容"
 // @Success 201 {object} models.Comment
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 401 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
 // @Router /api/posts/{id}/comments [post]
 func PostCommentHandler(commentService *service.CommentService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -122,7 +122,7 @@ func PostCommentHandler(commentService *service.CommentService, auditPool *worke
 		// JWTからuser_idを取得
 		userID, appErr := userIDFromContext(ctx)
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
@@ -130,26 +130,26 @@ func PostCommentHandler(commentService *service.CommentService, auditPool *worke
 		vars := mux.Vars(r)
 		postID, appErr := parseID(vars["id"])
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
 		// リクエストボディからコメントを読み取る
 		var comment models.Comment
 		if appErr := decodeJSON(r, &comment); appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
 		// コメントのバリデーションを実施する
 		if err := validateCommentInput(comment, postID); err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 
 		// コメントを挿入する
 		if err := commentService.CreateComment(ctx, postID, userID, &comment); err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 
@@ -157,7 +157,7 @@ func PostCommentHandler(commentService *service.CommentService, auditPool *worke
 		respondJSON(w, http.StatusCreated, comment)
 
 		// 監視ワーカープールにイベントを追加
-		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "comment_created", UserID: comment.UserID, PostID: comment.PostID})
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "comment_created", UserID: comment.UserID, PostID: comment.PostID, CommentID: comment.ID})
 	}
 }
 
@@ -176,11 +176,11 @@ func PostCommentHandler(commentService *service.CommentService, auditPool *worke
 // @Param Authorization header string true "Bearer Token"
 // @Param id path int true "コメントID"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 401 {object} models.ErrorResponse
-// @Failure 403 {object} models.ErrorResponse
-// @Failure 404 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 403 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
 // @Router /api/comments/{id} [delete]
 func DeleteCommentHandler(commentService *service.CommentService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -190,7 +190,7 @@ func DeleteCommentHandler(commentService *service.CommentService, auditPool *wor
 		// JWTからuser_idを取得
 		userID, appErr := userIDFromContext(ctx)
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
@@ -198,20 +198,20 @@ func DeleteCommentHandler(commentService *service.CommentService, auditPool *wor
 		vars := mux.Vars(r)
 		commentID, appErr := parseID(vars["id"])
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
 		// コメントの所有者か確認する
 		postID, err := commentService.EnsureCommentOwner(ctx, userID, commentID)
 		if err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 
 		// コメントを削除する
-		if err := commentService.DeleteComment(ctx, commentID); err != nil {
-			respondAppError(w, err)
+		if err := commentService.DeleteComment(ctx, postID, commentID); err != nil {
+			respondAppError(w, r, err)
 			return
 		}
 
@@ -240,11 +240,11 @@ func DeleteCommentHandler(commentService *service.CommentService, auditPool *wor
 // @Param id path int true "コメントID"
 // @Param post body models.Comment true "コメント内容"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 401 {object} models.ErrorResponse
-// @Failure 403 {object} models.ErrorResponse
-// @Failure 404 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 403 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
 // @Router /api/comments/{id} [put]
 func UpdateCommentHandler(commentService *service.CommentService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -254,7 +254,7 @@ func UpdateCommentHandler(commentService *service.CommentService, auditPool *wor
 		// JWTからuser_idを取得
 		userID, appErr := userIDFromContext(ctx)
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
@@ -262,14 +262,14 @@ func UpdateCommentHandler(commentService *service.CommentService, auditPool *wor
 		vars := mux.Vars(r)
 		commentID, appErr := parseID(vars["id"])
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
 		// コメントの所有者か確認
 		postID, err := commentService.EnsureCommentOwner(ctx, userID, commentID)
 		if err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 
@@ -278,19 +278,19 @@ func UpdateCommentHandler(commentService *service.CommentService, auditPool *wor
 			Content string `json:"content"`
 		}
 		if appErr := decodeJSON(r, &req); appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
 		// コメントのバリデーションを実施する
 		if err := validateCommentUpdateInput(req.Content, commentID); err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 
 		// コメントの更新を実施する
 		if err := commentService.UpdateComment(ctx, commentID, req.Content); err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
 	"github.com/yusuke-hoguro/BlogApi/internal/handler"
 	"github.com/yusuke-hoguro/BlogApi/internal/models"
 	"github.com/yusuke-hoguro/BlogApi/testutils"
@@ -91,7 +92,7 @@ func TestPostCommentHandleValidation(t *testing.T) {
 		wantStatus int
 	}{
 		{"empty content", `{"content": ""}`, http.StatusBadRequest},
-		{"content too long", fmt.Sprintf(`{"content": "%s"}`, strings.Repeat("a", handler.MaxCommentLength+1)), http.StatusBadRequest},
+		{"content too long", fmt.Sprintf(`{"content": "%s"}`, strings.Repeat("a", config.Default().Validation.CommentMaxLength+1)), http.StatusBadRequest},
 	}
 
 	// サブテストを実行する
@@ -417,7 +418,7 @@ func TestGetCommentsByPostIDHandlerMultiple(t *testing.T) {
 	defer server.Close()
 	defer cleanup()
 
-	// 投稿IDを設定(init_test.sqlで複数コメント登録済み)
+	// 投稿IDを設定(seed_test.sqlで複数コメント登録済み)
 	postID := 1
 
 	// リクエストの作成
@@ -480,7 +481,7 @@ func TestGetCommentsByPostIDHandlerEmpty(t *testing.T) {
 	defer server.Close()
 	defer cleanup()
 
-	// 投稿IDを設定(init_test.sqlで複数コメント登録済み)
+	// 投稿IDを設定(seed_test.sqlで複数コメント登録済み)
 	postID := 3
 
 	// リクエストの作成
@@ -823,7 +824,7 @@ func TestUpdateCommentHandlerForbidden(t *testing.T) {
 		return
 	}
 
-	// テスト用のコメントはinit_test.sqlで作成済み
+	// テスト用のコメントはseed_test.sqlで作成済み
 	commentID := 3
 	updateJSON := `{"content":"他人のコメントを不正に更新しようとする"}`
 
@@ -874,7 +875,7 @@ func TestUpdateCommentHandlerValidation(t *testing.T) {
 		return
 	}
 
-	// init_test.sqlでテスト用のコメント作成済み
+	// seed_test.sqlでテスト用のコメント作成済み
 	commentID := 3
 
 	// テスト実施用のテーブル作成
@@ -884,7 +885,7 @@ func TestUpdateCommentHandlerValidation(t *testing.T) {
 		wantStatus int
 	}{
 		{"empty content", `{"content": ""}`, http.StatusBadRequest},
-		{"content too long", fmt.Sprintf(`{"content": "%s"}`, strings.Repeat("a", handler.MaxCommentLength+1)), http.StatusBadRequest},
+		{"content too long", fmt.Sprintf(`{"content": "%s"}`, strings.Repeat("a", config.Default().Validation.CommentMaxLength+1)), http.StatusBadRequest},
 	}
 
 	// サブテストを実行する
@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yusuke-hoguro/BlogApi/internal/service"
+	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
+)
+
+// FollowUserHandler godoc
+// @Summary ユーザーをフォローする
+// @Description 指定したIDのユーザーをフォローする(フォロー済みの場合も成功を返す)
+// @Description
+// @Description **エラー条件:**
+// @Description - 無効なID、自分自身のフォロー → 400 Bad Request
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - ユーザーが存在しない → 404 Not Found
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags follows
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "ユーザーID"
+// @Success 201 {object} map[string]string
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/users/{id}/follow [post]
+func FollowUserHandler(followService *service.FollowService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// URIからフォローするユーザーのIDを取得
+		vars := mux.Vars(r)
+		followeeID, appErr := parseID(vars["id"])
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// フォローを登録する
+		if err := followService.Follow(ctx, userID, followeeID); err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusCreated, map[string]string{"message": "User followed successfully"})
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "user_followed", UserID: userID, TargetUserID: followeeID})
+	}
+}
+
+// UnfollowUserHandler godoc
+// @Summary ユーザーのフォローを解除する
+// @Description 指定したIDのユーザーのフォローを解除する
+// @Description
+// @Description **エラー条件:**
+// @Description - 無効なID → 400 Bad Request
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - データ更新/取得失敗 → 500 ServerError
+// @Tags follows
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "ユーザーID"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/users/{id}/follow [delete]
+func UnfollowUserHandler(followService *service.FollowService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// URIからフォローを解除するユーザーのIDを取得
+		vars := mux.Vars(r)
+		followeeID, appErr := parseID(vars["id"])
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// フォローを解除する
+		if err := followService.Unfollow(ctx, userID, followeeID); err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusNoContent, nil)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "user_unfollowed", UserID: userID, TargetUserID: followeeID})
+	}
+}
+
+// GetFollowersHandler godoc
+// @Summary フォロワー一覧を取得する
+// @Description 指定したIDのユーザーのフォロワーを新しい順に取得する。next_cursor を cursor に指定すると続きを取得できる
+// @Description
+// @Description **エラー条件:**
+// @Description - 無効なID、不正なカーソル、limitが1～100の範囲外 → 400 Bad Request
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags follows
+// @Produce json
+// @Param id path int true "ユーザーID"
+// @Param cursor query string false "前回のレスポンスの next_cursor"
+// @Param limit query int false "取得件数(既定20、最大100)"
+// @Success 200 {object} models.FollowListResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/users/{id}/followers [get]
+func GetFollowersHandler(followService *service.FollowService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// URIからユーザーのIDを取得
+		vars := mux.Vars(r)
+		userID, appErr := parseID(vars["id"])
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// カーソルと取得件数を取得
+		cursor, limit, appErr := pageParamsFromRequest(r)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// フォロワー一覧を取得する
+		followers, err := followService.GetFollowers(ctx, userID, cursor, limit)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, followers)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "followers_fetched", UserID: userID})
+	}
+}
+
+// GetFollowingHandler godoc
+// @Summary フォロー中一覧を取得する
+// @Description 指定したIDのユーザーがフォローしているユーザーを新しい順に取得する。next_cursor を cursor に指定すると続きを取得できる
+// @Description
+// @Description **エラー条件:**
+// @Description - 無効なID、不正なカーソル、limitが1～100の範囲外 → 400 Bad Request
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags follows
+// @Produce json
+// @Param id path int true "ユーザーID"
+// @Param cursor query string false "前回のレスポンスの next_cursor"
+// @Param limit query int false "取得件数(既定20、最大100)"
+// @Success 200 {object} models.FollowListResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/users/{id}/following [get]
+func GetFollowingHandler(followService *service.FollowService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// URIからユーザーのIDを取得
+		vars := mux.Vars(r)
+		userID, appErr := parseID(vars["id"])
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// カーソルと取得件数を取得
+		cursor, limit, appErr := pageParamsFromRequest(r)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// フォロー中一覧を取得する
+		following, err := followService.GetFollowing(ctx, userID, cursor, limit)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, following)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "following_fetched", UserID: userID})
+	}
+}
+
+// GetFeedHandler godoc
+// @Summary ホームフィードを取得する
+// @Description フォロー中のユーザーの投稿を新しい順に取得する。next_cursor を cursor に指定すると続きを取得できる
+// @Description
+// @Description **エラー条件:**
+// @Description - 不正なカーソル、limitが1～100の範囲外 → 400 Bad Request
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags follows
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param cursor query string false "前回のレスポンスの next_cursor"
+// @Param limit query int false "取得件数(既定20、最大100)"
+// @Success 200 {object} models.FeedResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/feed [get]
+func GetFeedHandler(followService *service.FollowService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// カーソルと取得件数を取得
+		cursor, limit, appErr := pageParamsFromRequest(r)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// フィードを取得する
+		feed, err := followService.GetFeed(ctx, userID, cursor, limit)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, feed)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "feed_fetched", UserID: userID})
+	}
+}
@@ -0,0 +1,176 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/handler"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/testutils"
+)
+
+// 認証付きでGETしてJSONをデコードするヘルパー
+func getJSONWithToken(t *testing.T, server *httptest.Server, path string, token string, dst any) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+	if err != nil {
+		t.Fatal("リクエスト生成失敗:", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal("HTTPリクエスト失敗:", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && dst != nil {
+		if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+			t.Fatalf("JSONのデコード失敗: %v", err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// フォローの登録・解除とフォロー一覧をテストする
+func TestFollowAndUnfollow(t *testing.T) {
+	// テスト用DBのセットアップを開始する
+	db := testutils.SetupTestDB(t)
+	defer db.Close()
+
+	// テスト用サーバーのセットアップ
+	h, cleanup := testutils.SetupTestServer(db)
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer cleanup()
+
+	token, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	bearer := "Bearer " + token
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{name: "フォロー", method: http.MethodPost, path: "/api/users/2/follow", want: http.StatusCreated},
+		{name: "フォロー済みのフォロー", method: http.MethodPost, path: "/api/users/2/follow", want: http.StatusCreated},
+		{name: "自分自身のフォロー", method: http.MethodPost, path: "/api/users/1/follow", want: http.StatusBadRequest},
+		{name: "存在しないユーザーのフォロー", method: http.MethodPost, path: "/api/users/9999/follow", want: http.StatusNotFound},
+		{name: "無効なID", method: http.MethodPost, path: "/api/users/abc/follow", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if status := doWithAuthorization(t, server, tt.method, tt.path, bearer, ""); status != tt.want {
+			t.Errorf("[%s] 期待するステータスコード %d, 実際は %d", tt.name, tt.want, status)
+		}
+	}
+
+	// フォロワー一覧とフォロー中一覧に反映されていることを確認する
+	var followers models.FollowListResponse
+	if status := getJSONWithToken(t, server, "/api/users/2/followers", "", &followers); status != http.StatusOK {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusOK, status)
+	}
+	if len(followers.Users) != 1 || followers.Users[0].ID != 1 {
+		t.Errorf("フォロワー一覧が想定と異なる: %+v", followers.Users)
+	}
+	var following models.FollowListResponse
+	if status := getJSONWithToken(t, server, "/api/users/1/following", "", &following); status != http.StatusOK {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusOK, status)
+	}
+	if len(following.Users) != 1 || following.Users[0].ID != 2 {
+		t.Errorf("フォロー中一覧が想定と異なる: %+v", following.Users)
+	}
+
+	// フォロー解除後は一覧から消える
+	if status := doWithAuthorization(t, server, http.MethodDelete, "/api/users/2/follow", bearer, ""); status != http.StatusNoContent {
+		t.Errorf("[フォロー解除] 期待するステータスコード %d, 実際は %d", http.StatusNoContent, status)
+	}
+	followers = models.FollowListResponse{}
+	getJSONWithToken(t, server, "/api/users/2/followers", "", &followers)
+	if len(followers.Users) != 0 {
+		t.Errorf("フォロー解除後もフォロワーに残っている: %+v", followers.Users)
+	}
+}
+
+// フィードがフォロー中のユーザーの投稿だけをカーソルで重複なく返すことを確認する
+func TestFeedPagination(t *testing.T) {
+	// テスト用DBのセットアップを開始する
+	db := testutils.SetupTestDB(t)
+	defer db.Close()
+
+	// テスト用サーバーのセットアップ
+	h, cleanup := testutils.SetupTestServer(db)
+	server := httptest.NewServer(h)
+	defer server.Close()
+	defer cleanup()
+
+	// 同じ作成日時の投稿を含めてフォロー中のユーザーの投稿を追加する
+	_, err := db.Exec(`
+		INSERT INTO posts (user_id, title, content, created_at) VALUES
+		  (2, 'フィード1', '内容', '2030-01-01 00:00:00'),
+		  (2, 'フィード2', '内容', '2030-01-01 00:00:00'),
+		  (3, 'フィード3', '内容', '2030-01-02 00:00:00')
+	`)
+	if err != nil {
+		t.Fatal("投稿の追加失敗:", err)
+	}
+
+	token, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	for _, path := range []string{"/api/users/2/follow", "/api/users/3/follow"} {
+		if status := doWithAuthorization(t, server, http.MethodPost, path, "Bearer "+token, ""); status != http.StatusCreated {
+			t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusCreated, status)
+		}
+	}
+
+	// 2件ずつ最後のページまで取得する
+	seen := map[int]bool{}
+	var posts []models.Post
+	cursor := ""
+	for page := 0; page < 10; page++ {
+		var feed models.FeedResponse
+		path := "/api/feed?limit=2&cursor=" + url.QueryEscape(cursor)
+		if status := getJSONWithToken(t, server, path, token, &feed); status != http.StatusOK {
+			t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusOK, status)
+		}
+		for _, post := range feed.Posts {
+			if seen[post.ID] {
+				t.Errorf("投稿が重複している: PostID=%d", post.ID)
+			}
+			seen[post.ID] = true
+			posts = append(posts, post)
+		}
+		if feed.NextCursor == "" {
+			break
+		}
+		cursor = feed.NextCursor
+	}
+
+	// 初期データの2件と追加した3件が新しい順に並ぶ
+	if len(posts) != 5 {
+		t.Fatalf("フィードの件数が一致しない: get %d, want 5", len(posts))
+	}
+	for i, post := range posts {
+		if post.UserID == 1 {
+			t.Errorf("フォローしていないユーザーの投稿が含まれている: PostID=%d", post.ID)
+		}
+		if i > 0 && post.CreatedAt.After(posts[i-1].CreatedAt) {
+			t.Errorf("フィードが新しい順になっていない: %v", posts)
+		}
+	}
+
+	// 不正なパラメーターは400になる
+	for _, path := range []string{"/api/feed?cursor=invalid", "/api/feed?limit=0", "/api/feed?limit=101"} {
+		if status := getJSONWithToken(t, server, path, token, nil); status != http.StatusBadRequest {
+			t.Errorf("[%s] 期待するステータスコード %d, 実際は %d", path, http.StatusBadRequest, status)
+		}
+	}
+}
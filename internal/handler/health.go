@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"database/sql"
 	"log"
 	"net/http"
 
 	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/service"
 	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
 )
 
@@ -17,14 +20,14 @@ import (
 // @Tags health
 // @Produce plain
 // @Success 200 {string} string "OK"
-// @Failure 405 {object} models.ErrorResponse
+// @Failure 405 {object} models.ProblemDetails
 // @Router /api/healthz [get]
 func HealthzHandler(auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
 		if r.Method != http.MethodGet && r.Method != http.MethodHead {
-			respondAppError(w, apperror.NewAppError(apperror.TypeMethodNotAllowed, "Method Not Allowed", nil))
+			respondAppError(w, r, apperror.NewAppError(apperror.TypeMethodNotAllowed, "Method Not Allowed", nil))
 			return
 		}
 		w.WriteHeader(http.StatusOK)
@@ -38,3 +41,89 @@ func HealthzHandler(auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "health_checked"})
 	}
 }
+
+// LivezHandler godoc
+// @Summary liveness
+// @Description プロセスが応答できる状態か確認します(依存先は確認しないため、失敗した場合はプロセスを再起動する)
+// @Tags health
+// @Produce plain
+// @Success 200 {string} string "OK"
+// @Router /api/livez [get]
+func LivezHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		writePlain(w, http.StatusOK, "OK")
+	}
+}
+
+// ReadyzHandler godoc
+// @Summary readiness
+// @Description DB・スキーマのバージョン・監視ワーカープールを確認し、リクエストを受け付けられる状態か返します
+// @Description シャットダウンが始まると失敗を返します(ロードバランサーはこの間に振り分けを止める)
+// @Description verbose を指定すると依存先ごとの確認結果をJSONで返します
+// @Tags health
+// @Produce plain
+// @Produce json
+// @Param verbose query string false "依存先ごとの確認結果をJSONで返す"
+// @Success 200 {object} models.ReadinessResponse
+// @Failure 503 {object} models.ReadinessResponse
+// @Router /api/readyz [get]
+func ReadyzHandler(health *service.HealthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, ready := health.Ready(r.Context())
+		status := http.StatusOK
+		if !ready {
+			status = http.StatusServiceUnavailable
+			log.Printf("readiness check failed: %+v", report)
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		if r.URL.Query().Has("verbose") {
+			respondJSON(w, status, report)
+			return
+		}
+		if ready {
+			writePlain(w, status, "OK")
+		} else {
+			writePlain(w, status, report.Status)
+		}
+	}
+}
+
+// テキストのレスポンスを返す
+func writePlain(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	if _, err := w.Write([]byte(body)); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+// コネクションプールの状態を返すインターフェース(*sql.DB が実装する)
+type DBStatser interface {
+	Stats() sql.DBStats
+}
+
+// DBStatsHandler godoc
+// @Summary DBコネクションプールの状態
+// @Description DBのコネクションプールの接続数・待ち回数などを返します(server.debug_endpoints が有効な場合のみ公開)
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.DBStatsResponse
+// @Router /api/debug/dbstats [get]
+func DBStatsHandler(db DBStatser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := db.Stats()
+		respondJSON(w, http.StatusOK, models.DBStatsResponse{
+			MaxOpenConnections: stats.MaxOpenConnections,
+			OpenConnections:    stats.OpenConnections,
+			InUse:              stats.InUse,
+			Idle:               stats.Idle,
+			WaitCount:          stats.WaitCount,
+			WaitDurationMS:     stats.WaitDuration.Milliseconds(),
+			MaxIdleClosed:      stats.MaxIdleClosed,
+			MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+			MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+		})
+	}
+}
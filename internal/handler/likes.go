@@ -20,9 +20,9 @@ import (
 // @Produce json
 // @Param id path int true "投稿ID"
 // @Success 201 {object} map[string]string
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 401 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
 // @Router /api/posts/{id}/like [post]
 func LikePostHandler(likeService *service.LikeService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -32,7 +32,7 @@ func LikePostHandler(likeService *service.LikeService, auditPool *workerpool.Aud
 		// 認証情報からユーザーIDを取得
 		userID, appErr := userIDFromContext(ctx)
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
@@ -40,13 +40,13 @@ func LikePostHandler(likeService *service.LikeService, auditPool *workerpool.Aud
 		vars := mux.Vars(r)
 		postID, appErr := parseID(vars["id"])
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
 		// 「いいね」を登録する
 		if err := likeService.LikePost(ctx, userID, postID); err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 
@@ -68,8 +68,8 @@ func LikePostHandler(likeService *service.LikeService, auditPool *workerpool.Aud
 // @Produce json
 // @Param id path int true "投稿ID"
 // @Success 200 {object} models.LikesResponse
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
 // @Router /api/posts/{id}/likes [get]
 func GetLikesHandler(likeService *service.LikeService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -80,14 +80,14 @@ func GetLikesHandler(likeService *service.LikeService, auditPool *workerpool.Aud
 		vars := mux.Vars(r)
 		postID, appErr := parseID(vars["id"])
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
 		// 「いいね」の数とユーザー一覧を取得する
 		likes, err := likeService.GetLikes(ctx, postID)
 		if err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 
@@ -111,9 +111,9 @@ func GetLikesHandler(likeService *service.LikeService, auditPool *workerpool.Aud
 // @Produce json
 // @Param id path int true "投稿ID"
 // @Success 204 "No Content"
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 401 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
 // @Router /api/posts/{id}/like [delete]
 func UnlikePostHandler(likeService *service.LikeService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -123,7 +123,7 @@ func UnlikePostHandler(likeService *service.LikeService, auditPool *workerpool.A
 		// 認証情報からユーザーIDを取得
 		userID, appErr := userIDFromContext(ctx)
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
@@ -131,13 +131,13 @@ func UnlikePostHandler(likeService *service.LikeService, auditPool *workerpool.A
 		vars := mux.Vars(r)
 		postID, appErr := parseID(vars["id"])
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
 		// 「いいね」を削除する
 		if err := likeService.UnlikePost(ctx, userID, postID); err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 
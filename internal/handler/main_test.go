@@ -0,0 +1,13 @@
+package handler_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/yusuke-hoguro/BlogApi/testutils"
+)
+
+// パッケージのテスト終了後にテスト用のDBを片付ける
+func TestMain(m *testing.M) {
+	os.Exit(testutils.RunTests(m))
+}
@@ -0,0 +1,300 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/service"
+	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
+)
+
+// GetNotificationsHandler godoc
+// @Summary 通知一覧を取得する
+// @Description 自分宛ての通知を作成日時の新しい順に取得する。同じ投稿へのいいね・コメントなどは未読の間1件にまとめられる。next_cursor を cursor に指定すると続きを取得できる
+// @Description
+// @Description **エラー条件:**
+// @Description - 不正なカーソル、limitが1～100の範囲外、unreadがtrue/false以外 → 400 Bad Request
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags notifications
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param unread query bool false "trueの場合は未読の通知のみ取得する"
+// @Param cursor query string false "前回のレスポンスの next_cursor"
+// @Param limit query int false "取得件数(既定20、最大100)"
+// @Success 200 {object} models.NotificationListResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/notifications [get]
+func GetNotificationsHandler(notificationService *service.NotificationService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// カーソルと取得件数を取得
+		cursor, limit, appErr := pageParamsFromRequest(r)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// 未読のみ取得するか判定する
+		var unreadOnly bool
+		switch unread := r.URL.Query().Get("unread"); unread {
+		case "", "false":
+		case "true":
+			unreadOnly = true
+		default:
+			respondAppError(w, r, apperror.NewAppError(apperror.TypeBadRequest, "Unread must be true or false : Unread="+unread, nil).WithSubCode(apperror.CodeInvalidQuery))
+			return
+		}
+
+		// 通知一覧を取得する
+		notifications, err := notificationService.GetNotifications(ctx, userID, unreadOnly, cursor, limit)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, notifications)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "notifications_fetched", UserID: userID})
+	}
+}
+
+// GetUnreadNotificationCountHandler godoc
+// @Summary 未読の通知件数を取得する
+// @Description 自分宛ての未読の通知件数を取得する
+// @Description
+// @Description **エラー条件:**
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags notifications
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} models.UnreadCountResponse
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/notifications/unread_count [get]
+func GetUnreadNotificationCountHandler(notificationService *service.NotificationService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// 未読件数を取得する
+		count, err := notificationService.GetUnreadCount(ctx, userID)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, models.UnreadCountResponse{UnreadCount: count})
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "notification_unread_count_fetched", UserID: userID})
+	}
+}
+
+// MarkNotificationReadHandler godoc
+// @Summary 通知を既読にする
+// @Description 指定したIDの自分宛ての通知を既読にする(既読済みの場合も成功を返す)
+// @Description
+// @Description **エラー条件:**
+// @Description - 無効なID → 400 Bad Request
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - 通知が存在しない、他のユーザーの通知 → 404 Not Found
+// @Description - データ更新/取得失敗 → 500 ServerError
+// @Tags notifications
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "通知ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/notifications/{id}/read [post]
+func MarkNotificationReadHandler(notificationService *service.NotificationService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// URIから通知のIDを取得
+		vars := mux.Vars(r)
+		id, appErr := parseID(vars["id"])
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// 通知を既読にする
+		if err := notificationService.MarkRead(ctx, userID, id); err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusNoContent, nil)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "notification_read", UserID: userID})
+	}
+}
+
+// MarkAllNotificationsReadHandler godoc
+// @Summary 通知をすべて既読にする
+// @Description 自分宛ての未読の通知をすべて既読にする
+// @Description
+// @Description **エラー条件:**
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - データ更新/取得失敗 → 500 ServerError
+// @Tags notifications
+// @Param Authorization header string true "Bearer Token"
+// @Success 204 "No Content"
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/notifications/read [post]
+func MarkAllNotificationsReadHandler(notificationService *service.NotificationService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// 通知をすべて既読にする
+		if err := notificationService.MarkAllRead(ctx, userID); err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusNoContent, nil)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "notifications_read_all", UserID: userID})
+	}
+}
+
+// GetNotificationPreferencesHandler godoc
+// @Summary 通知設定を取得する
+// @Description 通知の種類ごとの受け取り設定を取得する(未設定の場合はすべてtrue)
+// @Description
+// @Description **エラー条件:**
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags notifications
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {object} models.NotificationPreferences
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/notifications/preferences [get]
+func GetNotificationPreferencesHandler(notificationService *service.NotificationService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// 通知設定を取得する
+		prefs, err := notificationService.GetPreferences(ctx, userID)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, prefs)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "notification_preferences_fetched", UserID: userID})
+	}
+}
+
+// UpdateNotificationPreferencesHandler godoc
+// @Summary 通知設定を更新する
+// @Description 通知の種類ごとの受け取り設定を更新する(指定しなかった項目は変更しない)
+// @Description
+// @Description **エラー条件:**
+// @Description - JSONの形式不正、変更する項目が無い → 400 Bad Request
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param preferences body models.NotificationPreferencesRequest true "通知設定"
+// @Success 200 {object} models.NotificationPreferences
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/notifications/preferences [put]
+func UpdateNotificationPreferencesHandler(notificationService *service.NotificationService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// リクエストボディのJSONをデコードする
+		var req models.NotificationPreferencesRequest
+		if appErr := decodeJSON(r, &req); appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// 入力値のバリデーションチェック
+		if appErr := validateNotificationPreferencesInput(req); appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// 通知設定を更新する
+		prefs, err := notificationService.UpdatePreferences(ctx, userID, &req)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, prefs)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "notification_preferences_updated", UserID: userID})
+	}
+}
@@ -0,0 +1,195 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/handler"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// 同じ投稿へのいいねが1件の通知にまとめられ、既読にできることを確認する
+func TestNotificationsCoalesceAndMarkRead(t *testing.T) {
+	_, _, server, cleanup := setupAccountTestServer(t)
+	defer cleanup()
+
+	// ユーザー1とユーザー3がユーザー2の投稿(ID=2)にいいねする
+	for _, userID := range []int{1, 3} {
+		token, err := handler.GenerateJWT(userID)
+		if err != nil {
+			t.Fatal("JWTの生成に失敗:", err)
+		}
+		if status := doWithAuthorization(t, server, http.MethodPost, "/api/posts/2/like", "Bearer "+token, ""); status != http.StatusCreated {
+			t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusCreated, status)
+		}
+	}
+
+	ownerToken, err := handler.GenerateJWT(2)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+
+	// 通知は監視ワーカープールで非同期に作成されるため反映されるまで待つ
+	var list models.NotificationListResponse
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		list = models.NotificationListResponse{}
+		if status := getJSONWithToken(t, server, "/api/notifications", ownerToken, &list); status != http.StatusOK {
+			t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusOK, status)
+		}
+		if len(list.Notifications) == 1 && list.Notifications[0].ActorCount == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("通知がまとめられていない: %+v", list)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	notification := list.Notifications[0]
+	if notification.Type != models.NotificationTypeLike || notification.PostID != 2 || list.UnreadCount != 1 {
+		t.Errorf("通知が想定と異なる: %+v unread=%d", notification, list.UnreadCount)
+	}
+	if !strings.HasSuffix(notification.Message, "and 1 other liked your post") {
+		t.Errorf("通知のメッセージが想定と異なる: %s", notification.Message)
+	}
+
+	// 他のユーザーの通知は既読にできない
+	otherToken, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	path := "/api/notifications/" + strconv.Itoa(notification.ID) + "/read"
+	if status := doWithAuthorization(t, server, http.MethodPost, path, "Bearer "+otherToken, ""); status != http.StatusNotFound {
+		t.Errorf("[他のユーザーの通知] 期待するステータスコード %d, 実際は %d", http.StatusNotFound, status)
+	}
+
+	// 既読にすると未読件数が0になる
+	if status := doWithAuthorization(t, server, http.MethodPost, path, "Bearer "+ownerToken, ""); status != http.StatusNoContent {
+		t.Errorf("[既読] 期待するステータスコード %d, 実際は %d", http.StatusNoContent, status)
+	}
+	var count models.UnreadCountResponse
+	if status := getJSONWithToken(t, server, "/api/notifications/unread_count", ownerToken, &count); status != http.StatusOK {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusOK, status)
+	}
+	if count.UnreadCount != 0 {
+		t.Errorf("未読件数が一致しない: get %d, want 0", count.UnreadCount)
+	}
+	var unread models.NotificationListResponse
+	getJSONWithToken(t, server, "/api/notifications?unread=true", ownerToken, &unread)
+	if len(unread.Notifications) != 0 {
+		t.Errorf("既読の通知が未読一覧に含まれている: %+v", unread.Notifications)
+	}
+}
+
+// 通知設定で受け取りを停止した種類の通知が作成されないことを確認する
+func TestNotificationPreferences(t *testing.T) {
+	_, services, server, cleanup := setupAccountTestServer(t)
+	defer cleanup()
+
+	token, err := handler.GenerateJWT(2)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	bearer := "Bearer " + token
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "フォロー通知を停止", body: `{"follows": false}`, want: http.StatusOK},
+		{name: "変更する項目が無い", body: `{}`, want: http.StatusBadRequest},
+		{name: "JSONの形式不正", body: `{`, want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if status := doWithAuthorization(t, server, http.MethodPut, "/api/notifications/preferences", bearer, tt.body); status != tt.want {
+			t.Errorf("[%s] 期待するステータスコード %d, 実際は %d", tt.name, tt.want, status)
+		}
+	}
+
+	var prefs models.NotificationPreferences
+	if status := getJSONWithToken(t, server, "/api/notifications/preferences", token, &prefs); status != http.StatusOK {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusOK, status)
+	}
+	if !prefs.Likes || !prefs.Comments || prefs.Follows {
+		t.Errorf("通知設定が想定と異なる: %+v", prefs)
+	}
+
+	// アウトボックスのイベントを直接処理して、停止した種類と自分自身の操作は通知されないことを確認する
+	ctx := context.Background()
+	events := []models.OutboxEvent{
+		{Type: models.DomainEventUserFollowed, Payload: json.RawMessage(`{"follower_id": 1, "followee_id": 2}`)},
+		{Type: models.DomainEventCommentCreated, Payload: json.RawMessage(`{"post_id": 2, "user_id": 2}`)},
+		{Type: models.DomainEventCommentCreated, Payload: json.RawMessage(`{"post_id": 2, "user_id": 3}`)},
+	}
+	for _, event := range events {
+		if err := services.Notification.HandleOutboxEvent(ctx, event); err != nil {
+			t.Fatal("アウトボックスのイベントの処理失敗:", err)
+		}
+	}
+	list, err := services.Notification.GetNotifications(ctx, 2, false, "", 10)
+	if err != nil {
+		t.Fatal("通知一覧の取得失敗:", err)
+	}
+	if len(list.Notifications) != 1 || list.Notifications[0].Type != models.NotificationTypeComment {
+		t.Fatalf("通知が想定と異なる: %+v", list.Notifications)
+	}
+	if want := "testuser3 commented on your post"; list.Notifications[0].Message != want {
+		t.Errorf("通知のメッセージが一致しない: get %s, want %s", list.Notifications[0].Message, want)
+	}
+}
+
+// ページの取得の間にまとめられて更新された通知が、次のページで重複・欠落しないことを確認する
+func TestNotificationsCursorStableAcrossCoalesce(t *testing.T) {
+	_, services, server, cleanup := setupAccountTestServer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	handle := func(eventType string, payload any) {
+		t.Helper()
+		data, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatal("イベントの作成失敗:", err)
+		}
+		if err := services.Notification.HandleOutboxEvent(ctx, models.OutboxEvent{Type: eventType, Payload: data}); err != nil {
+			t.Fatal("アウトボックスのイベントの処理失敗:", err)
+		}
+		// 作成日時が同じにならないようにする
+		time.Sleep(10 * time.Millisecond)
+	}
+	// ユーザー2宛てに、いいねの通知の後にフォローの通知を作成する
+	handle(models.DomainEventPostLiked, models.PostLikedEvent{PostID: 2, UserID: 1})
+	handle(models.DomainEventUserFollowed, models.UserFollowedEvent{FollowerID: 3, FolloweeID: 2})
+
+	token, err := handler.GenerateJWT(2)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	var first models.NotificationListResponse
+	if status := getJSONWithToken(t, server, "/api/notifications?limit=1", token, &first); status != http.StatusOK {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusOK, status)
+	}
+	if len(first.Notifications) != 1 || first.Notifications[0].Type != models.NotificationTypeFollow || first.NextCursor == "" {
+		t.Fatalf("1ページ目が想定と異なる: %+v", first)
+	}
+
+	// 1ページ目の取得後に、いいねの通知に別のユーザーの操作がまとめられて更新日時が進む
+	handle(models.DomainEventPostLiked, models.PostLikedEvent{PostID: 2, UserID: 3})
+
+	var second models.NotificationListResponse
+	if status := getJSONWithToken(t, server, "/api/notifications?limit=1&cursor="+first.NextCursor, token, &second); status != http.StatusOK {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusOK, status)
+	}
+	if len(second.Notifications) != 1 || second.Notifications[0].Type != models.NotificationTypeLike || second.Notifications[0].ActorCount != 2 {
+		t.Fatalf("2ページ目が想定と異なる: %+v", second)
+	}
+	if second.NextCursor != "" {
+		t.Errorf("3ページ目は無いはず: %s", second.NextCursor)
+	}
+}
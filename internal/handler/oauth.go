@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/middleware"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/service"
+	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
+)
+
+// 認可フローのstateをブラウザに紐付けるCookie
+const (
+	oauthStateCookieName = "oauth_state"
+	oauthStateCookiePath = "/api/auth"
+	oauthStateCookieAge  = 600
+)
+
+// OAuthStartHandler godoc
+// @Summary 外部IDプロバイダーでのログインを開始する
+// @Description 指定したプロバイダーの認可エンドポイントへリダイレクトする(authorization code + PKCE)
+// @Description ログイン中(Bearer Token付き)で呼び出した場合は、コールバック時にそのユーザーへアカウントを紐付ける
+// @Description
+// @Description **エラー条件:**
+// @Description - 不正なトークン → 401 Unauthorized
+// @Description - プロバイダーが存在しない → 404 Not Found
+// @Description - state保存失敗、プロバイダーのメタデータ取得失敗 → 500 ServerError
+// @Tags auth
+// @Param provider path string true "プロバイダー名"
+// @Param Authorization header string false "Bearer Token(アカウント紐付け時)"
+// @Success 302 "認可エンドポイントへリダイレクト"
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/auth/{provider}/start [get]
+func OAuthStartHandler(oauthService *service.OAuthService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+		provider := mux.Vars(r)["provider"]
+
+		// ログイン中であればアカウント紐付けとして扱う
+		linkUserID, ok := ctx.Value(middleware.UserIDKey).(int)
+		if !ok {
+			linkUserID = 0
+		}
+
+		// 認可フローを開始する
+		authURL, state, err := oauthService.StartLogin(ctx, provider, linkUserID)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		// stateをCookieに保存して、コールバックが同じブラウザから来たことを確認できるようにする
+		http.SetCookie(w, &http.Cookie{
+			Name:     oauthStateCookieName,
+			Value:    state,
+			Path:     oauthStateCookiePath,
+			MaxAge:   oauthStateCookieAge,
+			HttpOnly: true,
+			Secure:   isSecureRequest(r),
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, authURL, http.StatusFound)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "oauth_login_started", UserID: linkUserID})
+	}
+}
+
+// OAuthCallbackHandler godoc
+// @Summary 外部IDプロバイダーからのコールバックを処理する
+// @Description 認可コードをトークンに交換してIDトークンを検証し、本サービスのJWTを発行する
+// @Description 未登録のアカウントの場合はユーザーを新規作成する
+// @Description
+// @Description **エラー条件:**
+// @Description - code/stateが無い、stateが不正・期限切れ、Cookieのstateと一致しない、認可が拒否された → 400 Bad Request
+// @Description - 認可コードの交換失敗、IDトークンの検証失敗 → 401 Unauthorized
+// @Description - プロバイダーが存在しない → 404 Not Found
+// @Description - 既に別のユーザーに紐付いているアカウント → 409 Conflict
+// @Description - データ更新/取得失敗、JWT生成失敗 → 500 ServerError
+// @Tags auth
+// @Produce json
+// @Param provider path string true "プロバイダー名"
+// @Param code query string true "認可コード"
+// @Param state query string true "state"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 409 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/auth/{provider}/callback [get]
+func OAuthCallbackHandler(oauthService *service.OAuthService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+		provider := mux.Vars(r)["provider"]
+		query := r.URL.Query()
+
+		// プロバイダー側でエラーになった場合(ユーザーが拒否した場合など)
+		if errCode := query.Get("error"); errCode != "" {
+			respondAppError(w, r, apperror.NewAppError(apperror.TypeBadRequest, "Authorization failed : Provider="+provider+" error="+errCode, nil).WithSubCode(apperror.CodeOAuthFailed))
+			return
+		}
+		code := query.Get("code")
+		state := query.Get("state")
+		if code == "" || state == "" {
+			respondAppError(w, r, apperror.NewAppError(apperror.TypeBadRequest, "code and state are required : Provider="+provider, nil).WithSubCode(apperror.CodeOAuthFailed))
+			return
+		}
+
+		// 開始時にCookieへ保存したstateと一致するか確認する(ログインCSRF対策)
+		cookie, err := r.Cookie(oauthStateCookieName)
+		if err != nil || cookie.Value != state {
+			respondAppError(w, r, apperror.NewAppError(apperror.TypeBadRequest, "State cookie mismatch : Provider="+provider, err).WithSubCode(apperror.CodeOAuthFailed))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     oauthStateCookieName,
+			Value:    "",
+			Path:     oauthStateCookiePath,
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   isSecureRequest(r),
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		// ユーザーを特定してJWTを発行する
+		result, err := oauthService.CompleteLogin(ctx, provider, state, code)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, models.TokenResponse{Token: result.Token})
+
+		// 監視ワーカープールにイベントを追加
+		action := "user_logged_in_oauth"
+		if result.Created {
+			action = "user_signed_up_oauth"
+		} else if result.Linked {
+			action = "oauth_identity_linked"
+		}
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: action, UserID: result.UserID})
+	}
+}
+
+// HTTPSでのリクエストか判定する(リバースプロキシ経由も考慮する)
+func isSecureRequest(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
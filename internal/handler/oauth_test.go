@@ -0,0 +1,153 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/app"
+	"github.com/yusuke-hoguro/BlogApi/internal/handler"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/oidc"
+	"github.com/yusuke-hoguro/BlogApi/internal/oidc/oidctest"
+	"github.com/yusuke-hoguro/BlogApi/testutils"
+)
+
+// 疑似プロバイダーを登録したテスト用サーバーを起動する
+func setupOAuthTestServer(t *testing.T) (*httptest.Server, *oidctest.Provider, func()) {
+	t.Helper()
+	db := testutils.SetupTestDB(t)
+	provider := oidctest.NewProvider("blogapi-test")
+
+	services := app.NewServices(db, testutils.TestConfig())
+	h, cleanup := testutils.SetupTestServerWithServices(services)
+	server := httptest.NewServer(h)
+
+	services.OAuth.RegisterProvider(oidc.NewClient(oidc.ProviderConfig{
+		Name:        "fake",
+		Issuer:      provider.Issuer(),
+		ClientID:    "blogapi-test",
+		RedirectURL: server.URL + "/api/auth/fake/callback",
+	}, nil))
+
+	return server, provider, func() {
+		server.Close()
+		cleanup()
+		provider.Close()
+		db.Close()
+	}
+}
+
+// Cookieを保持してリダイレクトを追うクライアントで認可フローを実行する
+func runOAuthFlow(t *testing.T, server *httptest.Server, token string) *http.Response {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal("cookiejarの生成失敗:", err)
+	}
+	client := &http.Client{Jar: jar}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/auth/fake/start", nil)
+	if err != nil {
+		t.Fatal("リクエスト生成失敗:", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal("HTTPリクエスト失敗:", err)
+	}
+	return resp
+}
+
+// 外部IDプロバイダーで初回ログインした場合にユーザーが作成されJWTが発行されることを確認する
+func TestOAuthLoginCreatesUser(t *testing.T) {
+	server, provider, cleanup := setupOAuthTestServer(t)
+	defer cleanup()
+	provider.SetUser(oidctest.User{Subject: "new-subject", Email: "new@example.com", PreferredUsername: "oauthuser"})
+
+	resp := runOAuthFlow(t, server, "")
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusOK, resp.StatusCode)
+	}
+	var result models.TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("JSONのデコード失敗: %v", err)
+	}
+	if result.Token == "" {
+		t.Error("トークンが発行されていない")
+	}
+
+	// 2回目のログインでは同じユーザーが使われることを確認する
+	resp2 := runOAuthFlow(t, server, "")
+	defer resp2.Body.Close()
+	if resp2.StatusCode != http.StatusOK {
+		t.Fatalf("[2回目] 期待するステータスコード %d, 実際は %d", http.StatusOK, resp2.StatusCode)
+	}
+}
+
+// ログイン中のユーザーから開始した場合に既存ユーザーへ紐付くことを確認する
+func TestOAuthLinkExistingUser(t *testing.T) {
+	server, provider, cleanup := setupOAuthTestServer(t)
+	defer cleanup()
+	provider.SetUser(oidctest.User{Subject: "link-subject", PreferredUsername: "linked"})
+
+	token, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	resp := runOAuthFlow(t, server, token)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusOK, resp.StatusCode)
+	}
+
+	// 別のユーザーから同じアカウントを紐付けようとした場合は409になる
+	otherToken, err := handler.GenerateJWT(2)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	resp2 := runOAuthFlow(t, server, otherToken)
+	defer resp2.Body.Close()
+	if resp2.StatusCode != http.StatusConflict {
+		t.Errorf("期待するステータスコード %d, 実際は %d", http.StatusConflict, resp2.StatusCode)
+	}
+}
+
+// 存在しないプロバイダーを指定した場合は404を返す
+func TestOAuthStartUnknownProvider(t *testing.T) {
+	server, _, cleanup := setupOAuthTestServer(t)
+	defer cleanup()
+
+	resp, err := http.Get(server.URL + "/api/auth/unknown/start")
+	if err != nil {
+		t.Fatal("HTTPリクエスト失敗:", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("期待するステータスコード %d, 実際は %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+// stateのCookieが無いコールバックは拒否する
+func TestOAuthCallbackWithoutStateCookie(t *testing.T) {
+	server, _, cleanup := setupOAuthTestServer(t)
+	defer cleanup()
+
+	resp, err := http.Get(server.URL + "/api/auth/fake/callback?code=dummy&state=dummy")
+	if err != nil {
+		t.Fatal("HTTPリクエスト失敗:", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("期待するステータスコード %d, 実際は %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
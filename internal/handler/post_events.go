@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
+	"github.com/yusuke-hoguro/BlogApi/internal/pubsub"
+	"github.com/yusuke-hoguro/BlogApi/internal/service"
+	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
+)
+
+// PostEventsHandler godoc
+// @Summary 投稿のライブイベントを購読する
+// @Description 指定した投稿へのコメントの作成・更新・削除といいね数の変化を Server-Sent Events で配信する
+// @Description 再接続時は Last-Event-ID ヘッダー(またはクエリの last_event_id)を指定すると取りこぼしたイベントを再送する
+// @Description 再送できない場合は resync イベントを送るので、コメント・いいねを取得し直す
+// @Description
+// @Description **エラー条件:**
+// @Description - 無効なID、無効な Last-Event-ID → 400 Bad Request
+// @Description - 投稿が存在しない → 404 Not Found
+// @Description - データ取得失敗 → 500 ServerError
+// @Tags posts
+// @Produce text/event-stream
+// @Param id path int true "投稿ID"
+// @Param Last-Event-ID header string false "最後に受信したイベントID"
+// @Param last_event_id query string false "最後に受信したイベントID(ヘッダーを指定できない場合)"
+// @Success 200 {string} string "text/event-stream"
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/posts/{id}/events [get]
+func PostEventsHandler(postEventService *service.PostEventService, cfg config.EventsConfig, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// URIからpostのIDを取得
+		vars := mux.Vars(r)
+		postID, appErr := parseID(vars["id"])
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// 再接続の場合は最後に受信したイベントIDを取得
+		lastEventID, appErr := lastEventIDFromRequest(r)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// 投稿のイベントを購読する
+		sub, err := postEventService.Subscribe(ctx, postID, lastEventID)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+		defer sub.Close()
+
+		// 長時間の接続になるため http.Server の ReadTimeout / WriteTimeout による切断を解除する
+		// 書き込みのタイムアウトはイベントごとに設定する
+		rc := http.NewResponseController(w)
+		if err := rc.SetReadDeadline(time.Time{}); err != nil {
+			log.Printf("Failed to clear read deadline : PostID=%d : %v", postID, err)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		// 監視ワーカープールにイベントを追加(接続が続くためストリーム開始時に記録する)
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "post_events_subscribed", PostID: postID})
+
+		// 再接続までの待ち時間と、取りこぼし・再送するイベントを送る
+		if err := writeSSE(rc, w, cfg.WriteTimeout, fmt.Sprintf("retry: %d\n\n", cfg.Retry.Milliseconds())); err != nil {
+			return
+		}
+		if sub.Gap {
+			if err := writeSSE(rc, w, cfg.WriteTimeout, fmt.Sprintf("event: resync\ndata: {\"post_id\":%d}\n\n", postID)); err != nil {
+				return
+			}
+		}
+		for _, event := range sub.Replay {
+			if err := writeSSEEvent(rc, w, cfg.WriteTimeout, event); err != nil {
+				return
+			}
+		}
+
+		heartbeat := time.NewTicker(cfg.HeartbeatInterval)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-sub.Events:
+				// 配信が追いつかない場合やシャットダウン時は切断してクライアントに再接続させる
+				if !ok {
+					return
+				}
+				if err := writeSSEEvent(rc, w, cfg.WriteTimeout, event); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				if err := writeSSE(rc, w, cfg.WriteTimeout, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Last-Event-ID ヘッダーまたはクエリからイベントIDを取得する(指定が無い場合は0)
+func lastEventIDFromRequest(r *http.Request) (uint64, *apperror.AppError) {
+	idStr := r.Header.Get("Last-Event-ID")
+	if idStr == "" {
+		idStr = r.URL.Query().Get("last_event_id")
+	}
+	if idStr == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		return 0, apperror.NewAppError(apperror.TypeBadRequest, "Invalid Last-Event-ID: "+idStr, err).WithSubCode(apperror.CodeInvalidQuery)
+	}
+	return id, nil
+}
+
+// イベントを SSE の形式で書き込む
+func writeSSEEvent(rc *http.ResponseController, w http.ResponseWriter, writeTimeout time.Duration, event pubsub.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		log.Printf("Failed to encode event : EventID=%d : %v", event.ID, err)
+		return nil
+	}
+	return writeSSE(rc, w, writeTimeout, fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data))
+}
+
+// タイムアウトを設定して書き込み、すぐにクライアントへ送る
+func writeSSE(rc *http.ResponseController, w http.ResponseWriter, writeTimeout time.Duration, message string) error {
+	if err := rc.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		log.Printf("Failed to set write deadline : %v", err)
+	}
+	if _, err := fmt.Fprint(w, message); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
@@ -0,0 +1,162 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/handler"
+)
+
+// 受信したSSEのイベント
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// SSEのストリームに接続してイベントを読み出すチャネルを返す
+func openEventStream(t *testing.T, ctx context.Context, server *httptest.Server, path string, lastEventID string) <-chan sseEvent {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+	if err != nil {
+		t.Fatal("リクエスト生成失敗:", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal("HTTPリクエスト失敗:", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		resp.Body.Close()
+		t.Fatalf("ストリームを開始できない: status=%d content-type=%s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	events := make(chan sseEvent, 10)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+		var current sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if current.event != "" {
+					events <- current
+				}
+				current = sseEvent{}
+			case strings.HasPrefix(line, "id: "):
+				current.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				current.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				current.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return events
+}
+
+// 指定した種類のイベントを受信するまで待つ
+func waitEvent(t *testing.T, events <-chan sseEvent, eventType string) sseEvent {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("イベント %s を受信する前にストリームが終了した", eventType)
+			}
+			if event.event == eventType {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("イベント %s を受信できない", eventType)
+		}
+	}
+}
+
+// コメントといいねの変化がSSEで配信され、Last-Event-IDで再開できることを確認する
+func TestPostEventsStream(t *testing.T) {
+	_, _, server, cleanup := setupAccountTestServer(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := openEventStream(t, ctx, server, "/api/posts/3/events", "")
+
+	token, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	bearer := "Bearer " + token
+
+	if status := doWithAuthorization(t, server, http.MethodPost, "/api/posts/3/comments", bearer, `{"content":"ライブコメント"}`); status != http.StatusCreated {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusCreated, status)
+	}
+	created := waitEvent(t, events, "comment_created")
+	if !strings.Contains(created.data, "ライブコメント") {
+		t.Errorf("コメントの内容が配信されていない: %s", created.data)
+	}
+
+	if status := doWithAuthorization(t, server, http.MethodPost, "/api/posts/3/like", bearer, ""); status != http.StatusCreated {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusCreated, status)
+	}
+	liked := waitEvent(t, events, "like_count")
+	if !strings.Contains(liked.data, `"like_count":1`) {
+		t.Errorf("いいね数が想定と異なる: %s", liked.data)
+	}
+
+	// 切断後にコメント作成のイベントIDから再開すると、いいねのイベントが再送される
+	cancel()
+	resumeCtx, resumeCancel := context.WithCancel(context.Background())
+	defer resumeCancel()
+	resumed := openEventStream(t, resumeCtx, server, "/api/posts/3/events", created.id)
+	if replayed := waitEvent(t, resumed, "like_count"); replayed.id != liked.id {
+		t.Errorf("再送されたイベントIDが一致しない: get %s, want %s", replayed.id, liked.id)
+	}
+	resumeCancel()
+}
+
+// 存在しない投稿や不正なLast-Event-IDではストリームを開始しないことを確認する
+func TestPostEventsInvalidRequest(t *testing.T) {
+	_, _, server, cleanup := setupAccountTestServer(t)
+	defer cleanup()
+
+	tests := []struct {
+		name        string
+		path        string
+		lastEventID string
+		want        int
+	}{
+		{name: "存在しない投稿", path: "/api/posts/9999/events", want: http.StatusNotFound},
+		{name: "無効なID", path: "/api/posts/abc/events", want: http.StatusBadRequest},
+		{name: "無効なLast-Event-ID", path: "/api/posts/1/events", lastEventID: "abc", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodGet, server.URL+tt.path, nil)
+		if err != nil {
+			t.Fatal("リクエスト生成失敗:", err)
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		if tt.lastEventID != "" {
+			req.Header.Set("Last-Event-ID", tt.lastEventID)
+		}
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatal("HTTPリクエスト失敗:", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("[%s] 期待するステータスコード %d, 実際は %d", tt.name, tt.want, resp.StatusCode)
+		}
+	}
+}
@@ -20,9 +20,9 @@ import (
 // @Produce json
 // @Param id path int true "PostID"
 // @Success 200 {object} models.Post
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 404 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
 // @Router /api/posts/{id} [get]
 func GetPostsByIDHandler(postService *service.PostService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -31,13 +31,13 @@ func GetPostsByIDHandler(postService *service.PostService, auditPool *workerpool
 		// URLから投稿IDを抽出する
 		id, appErr := postIDFromRequest(r)
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 		// DBから指定したIDの投稿を取得する
 		post, err := postService.GetPostByID(ctx, id)
 		if err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 		// 取得した投稿をJSONで返す
@@ -61,9 +61,9 @@ func GetPostsByIDHandler(postService *service.PostService, auditPool *workerpool
 // @Param Authorization header string true "Bearer Token"
 // @Param post body models.Post true "投稿内容"
 // @Success 201 {object} models.Post
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 401 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
 // @Router /api/posts [post]
 func CreatePostHandler(postService *service.PostService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -72,25 +72,25 @@ func CreatePostHandler(postService *service.PostService, auditPool *workerpool.A
 		// JWTからユーザーIDを取得する
 		userID, appErr := userIDFromContext(ctx)
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 		// Post型の構造体にデコードして格納
 		var post models.Post
 		if appErr := decodeJSON(r, &post); appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 		// 投稿のバリデーションを行う
 		if err := validatePostInput(post); err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 		// 記事にユーザーIDを設定する
 		post.UserID = userID
 		// 投稿を作成する
 		if err := postService.CreatePost(ctx, &post); err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 		// 作成した投稿をJSONで返す
@@ -117,11 +117,11 @@ func CreatePostHandler(postService *service.PostService, auditPool *workerpool.A
 // @Param id path int true "投稿ID"
 // @Param post body models.Post true "投稿内容"
 // @Success 200 {object} models.Post
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 401 {object} models.ErrorResponse
-// @Failure 403 {object} models.ErrorResponse
-// @Failure 404 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 403 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
 // @Router /api/posts/{id} [put]
 func UpdatePostHandler(postService *service.PostService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -130,34 +130,34 @@ func UpdatePostHandler(postService *service.PostService, auditPool *workerpool.A
 		// JWTからユーザーIDを取得する
 		userID, appErr := userIDFromContext(ctx)
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 		// URLから投稿IDを抽出する
 		id, appErr := postIDFromRequest(r)
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 		// DBから投稿者のユーザーIDを取得して、リクエストを投げたユーザーが記事の投稿者でない場合はエラーを返す
 		if err := postService.EnsurePostOwner(ctx, userID, id); err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 		// Post型の構造体にデコードして格納
 		var post models.Post
 		if appErr := decodeJSON(r, &post); appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 		// 投稿のバリデーションを行う
 		if err := validatePostInput(post); err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 		// 指定したIDの投稿を更新する
 		if err := postService.UpdatePost(ctx, id, &post); err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 		// 更新した投稿をJSONで返す
@@ -183,11 +183,11 @@ func UpdatePostHandler(postService *service.PostService, auditPool *workerpool.A
 // @Param Authorization header string true "Bearer Token"
 // @Param id path int true "投稿ID"
 // @Success 204 "No Content"
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 401 {object} models.ErrorResponse
-// @Failure 403 {object} models.ErrorResponse
-// @Failure 404 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 403 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
 // @Router /api/posts/{id} [put]
 func DeletePostHandler(postService *service.PostService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -196,23 +196,23 @@ func DeletePostHandler(postService *service.PostService, auditPool *workerpool.A
 		// JWTからリクエストをなげたユーザーIDを取得
 		userID, appErr := userIDFromContext(ctx)
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 		// URLからIDを取得する
 		id, appErr := postIDFromRequest(r)
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 		// 削除対象の投稿を作成したユーザーのIDを取得して、リクエストを投げたユーザーが記事の投稿者でない場合はエラーを返す
 		if err := postService.EnsurePostOwner(ctx, userID, id); err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 		// 指定したIDの投稿を削除する
 		if err := postService.DeletePost(ctx, id); err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 		// 削除成功のため204 No Contentを返す
@@ -234,9 +234,9 @@ func DeletePostHandler(postService *service.PostService, auditPool *workerpool.A
 // @Produce json
 // @Param Authorization header string true "Bearer Token"
 // @Success 200 {array} models.Post
-// @Failure 401 {object} models.ErrorResponse
-// @Failure 404 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
 // @Router /api/myposts [get]
 func GetMyPostsHandler(postService *service.PostService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -245,13 +245,13 @@ func GetMyPostsHandler(postService *service.PostService, auditPool *workerpool.A
 		// JWTからリクエストをなげたユーザーIDを取得
 		userID, appErr := userIDFromContext(ctx)
 		if appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 		// DBから指定したユーザーIDの投稿を取得する
 		posts, err := postService.GetPostsByUserID(ctx, userID)
 		if err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 		// 取得した投稿をJSONで返す
@@ -271,8 +271,8 @@ func GetMyPostsHandler(postService *service.PostService, auditPool *workerpool.A
 // @Tags posts
 // @Produce json
 // @Success 200 {array} models.Post
-// @Failure 404 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
 // @Router /api/posts [get]
 func GetAllPostsHandler(postService *service.PostService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -281,7 +281,7 @@ func GetAllPostsHandler(postService *service.PostService, auditPool *workerpool.
 		// 全投稿を取得する
 		posts, err := postService.GetAllPosts(ctx)
 		if err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 		// 取得した投稿をJSONで返す
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	_ "github.com/lib/pq"
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
 	"github.com/yusuke-hoguro/BlogApi/internal/handler"
 	"github.com/yusuke-hoguro/BlogApi/testutils"
 )
@@ -291,7 +292,7 @@ func TestCreatePostHandlerValidation(t *testing.T) {
 		// タイトルが文字数オーバーのテスト
 		{
 			name:       "title too long",
-			body:       fmt.Sprintf(`{"title": "%s", "content": "本文"}`, strings.Repeat("a", handler.MaxTitleLength+1)),
+			body:       fmt.Sprintf(`{"title": "%s", "content": "本文"}`, strings.Repeat("a", config.Default().Validation.TitleMaxLength+1)),
 			wantStatus: http.StatusBadRequest,
 		},
 		// 投稿内容が空の場合のテスト
@@ -303,7 +304,7 @@ func TestCreatePostHandlerValidation(t *testing.T) {
 		// 投稿内容が文字数オーバーのテスト
 		{
 			name:       "content too long",
-			body:       fmt.Sprintf(`{"title": "タイトル", "content": "%s"}`, strings.Repeat("a", handler.MaxContentLength+1)),
+			body:       fmt.Sprintf(`{"title": "タイトル", "content": "%s"}`, strings.Repeat("a", config.Default().Validation.ContentMaxLength+1)),
 			wantStatus: http.StatusBadRequest,
 		},
 	}
@@ -611,9 +612,9 @@ func TestUpdatePostHandlerValidation(t *testing.T) {
 		wantStatus int
 	}{
 		{"empty title", `{"title": "", "content": "本文"}`, http.StatusBadRequest},
-		{"title too long", fmt.Sprintf(`{"title": "%s", "content": "本文"}`, strings.Repeat("a", handler.MaxTitleLength+1)), http.StatusBadRequest},
+		{"title too long", fmt.Sprintf(`{"title": "%s", "content": "本文"}`, strings.Repeat("a", config.Default().Validation.TitleMaxLength+1)), http.StatusBadRequest},
 		{"empty content", `{"title": "タイトル", "content": ""}`, http.StatusBadRequest},
-		{"content too long", fmt.Sprintf(`{"title": "タイトル", "content": "%s"}`, strings.Repeat("a", handler.MaxContentLength+1)), http.StatusBadRequest},
+		{"content too long", fmt.Sprintf(`{"title": "タイトル", "content": "%s"}`, strings.Repeat("a", config.Default().Validation.ContentMaxLength+1)), http.StatusBadRequest},
 	}
 
 	// サブテストを実行する
@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -15,7 +16,7 @@ import (
 func parseID(idStr string) (int, *apperror.AppError) {
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		return 0, apperror.NewAppError(apperror.TypeBadRequest, "Invalid id: "+idStr, err)
+		return 0, apperror.NewAppError(apperror.TypeBadRequest, "Invalid id: "+idStr, err).WithSubCode(apperror.CodeInvalidID)
 	}
 	return id, nil
 }
@@ -26,19 +27,41 @@ func postIDFromRequest(r *http.Request) (int, *apperror.AppError) {
 	return parseID(idStr)
 }
 
+// クエリパラメーターからカーソルと取得件数を取得する関数
+func pageParamsFromRequest(r *http.Request) (string, int, *apperror.AppError) {
+	query := r.URL.Query()
+	limit := DefaultPageSize
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > MaxPageSize {
+			return "", 0, apperror.NewAppError(apperror.TypeBadRequest, fmt.Sprintf("Limit must be between 1 and %d : Limit=%s", MaxPageSize, limitStr), err).WithSubCode(apperror.CodeInvalidQuery)
+		}
+		limit = parsed
+	}
+	return query.Get("cursor"), limit, nil
+}
+
 // コンテキストからユーザーIDを取得する関数
 func userIDFromContext(ctx context.Context) (int, *apperror.AppError) {
 	userID, ok := ctx.Value(middleware.UserIDKey).(int)
 	if !ok {
-		return 0, apperror.NewAppError(apperror.TypeUnauthorized, "Unauthorized userID not found in context", nil)
+		return 0, apperror.NewAppError(apperror.TypeUnauthorized, "Unauthorized userID not found in context", nil).WithSubCode(apperror.CodeMissingToken)
 	}
 	return userID, nil
 }
 
+// JWTで認証されたリクエストか確認する関数(APIキーでの操作を禁止する場合に使う)
+func requireJWTAuth(ctx context.Context) *apperror.AppError {
+	if method, _ := ctx.Value(middleware.AuthMethodKey).(string); method == middleware.AuthMethodAPIKey {
+		return apperror.NewAppError(apperror.TypeForbidden, "This operation is not allowed with an api key", nil).WithSubCode(apperror.CodeAPIKeyNotAllowed)
+	}
+	return nil
+}
+
 // JSONのリクエストボディを構造体にデコードする関数
 func decodeJSON(r *http.Request, dst any) *apperror.AppError {
 	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
-		return apperror.NewAppError(apperror.TypeBadRequest, "Invalid request body", err)
+		return apperror.NewAppError(apperror.TypeBadRequest, "Invalid request body", err).WithSubCode(apperror.CodeInvalidBody)
 	}
 	return nil
 }
@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/middleware"
 )
 
 // JSONレスポンスを返す共通関数
@@ -20,24 +21,20 @@ func respondJSON(w http.ResponseWriter, status int, data any) {
 	}
 }
 
-// エラーレスポンスを返す関数
-func respondError(w http.ResponseWriter, message string, status int) {
-	respondJSON(w, status, map[string]string{"message": message})
-}
-
 // アプリケーションエラーを処理する関数
-func respondAppError(w http.ResponseWriter, err error) {
+// 内部向けのメッセージと原因はログにだけ出し、クライアントには application/problem+json で公開用のメッセージを返す
+func respondAppError(w http.ResponseWriter, r *http.Request, err error) {
+	requestID := middleware.RequestIDFromContext(r.Context())
 	var appErr *apperror.AppError
 	// エラーの中にAppError構造体が含まれているか確認
 	if errors.As(err, &appErr) {
 		if appErr.Err != nil {
-			log.Printf("app error: type=%s message=%s cause=%v", appErr.Type, appErr.Message, appErr.Err)
+			log.Printf("app error: request_id=%s type=%s message=%s cause=%v", requestID, appErr.Type, appErr.Message, appErr.Err)
 		} else {
-			log.Printf("app error: type=%s message=%s", appErr.Type, appErr.Message)
+			log.Printf("app error: request_id=%s type=%s message=%s", requestID, appErr.Type, appErr.Message)
 		}
-		respondError(w, appErr.Message, apperror.GetStatusCode(appErr.Type))
-		return
+	} else {
+		log.Printf("unexpected error: request_id=%s %v", requestID, err)
 	}
-	log.Printf("unexpected error: %v", err)
-	respondError(w, "Internal Server Error", http.StatusInternalServerError)
+	apperror.WriteProblem(w, r, err)
 }
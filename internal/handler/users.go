@@ -22,9 +22,9 @@ import (
 // @Produce json
 // @Param post body models.User true "ユーザー情報"
 // @Success 201 {object} models.User
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 405 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 405 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
 // @Router /api/signup [post]
 func SignupHandler(userService *service.UserService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -33,26 +33,26 @@ func SignupHandler(userService *service.UserService, auditPool *workerpool.Audit
 
 		// Postであるかをチェックする
 		if r.Method != http.MethodPost {
-			respondAppError(w, apperror.NewAppError(apperror.TypeMethodNotAllowed, "Method Not Allowed : Method="+r.Method, nil))
+			respondAppError(w, r, apperror.NewAppError(apperror.TypeMethodNotAllowed, "Method Not Allowed : Method="+r.Method, nil))
 			return
 		}
 
 		// GOの構造体にデコード
 		var userData models.User
 		if appErr := decodeJSON(r, &userData); appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
 		// ユーザー登録のバリデーションを行う
 		if err := validateSignupInput(userData); err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 
 		// ユーザー登録を実施する
 		if err := userService.Signup(ctx, &userData); err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 
@@ -77,10 +77,10 @@ func SignupHandler(userService *service.UserService, auditPool *workerpool.Audit
 // @Produce json
 // @Param post body models.User true "ユーザー情報"
 // @Success 200 {object} models.TokenResponse
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 401 {object} models.ErrorResponse
-// @Failure 405 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 405 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
 // @Router /api/login [post]
 func LoginHandler(userService *service.UserService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -89,27 +89,27 @@ func LoginHandler(userService *service.UserService, auditPool *workerpool.AuditW
 
 		// Postであるかをチェックする
 		if r.Method != http.MethodPost {
-			respondAppError(w, apperror.NewAppError(apperror.TypeMethodNotAllowed, "Method Not Allowed : Method="+r.Method, nil))
+			respondAppError(w, r, apperror.NewAppError(apperror.TypeMethodNotAllowed, "Method Not Allowed : Method="+r.Method, nil))
 			return
 		}
 
 		// GOの構造体にデコード
 		var userData models.User
 		if appErr := decodeJSON(r, &userData); appErr != nil {
-			respondAppError(w, appErr)
+			respondAppError(w, r, appErr)
 			return
 		}
 
 		// ログインのバリデーションを行う
 		if err := validateLoginInput(userData); err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 
 		// ログインを実施する
 		token, userID, err := userService.Login(ctx, userData)
 		if err != nil {
-			respondAppError(w, err)
+			respondAppError(w, r, err)
 			return
 		}
 
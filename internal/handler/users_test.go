@@ -21,7 +21,7 @@ func TestSignupHandlerDuplicateUsername(t *testing.T) {
 	defer server.Close()
 	defer cleanup()
 
-	// testdata/init_test.sql で作成済みのユーザー名を指定する
+	// testdata/seed_test.sql で作成済みのユーザー名を指定する
 	signupJSON := `{"username":"testuser","password":"password"}`
 	req, err := http.NewRequest(http.MethodPost, server.URL+"/api/signup", strings.NewReader(signupJSON))
 	if err != nil {
@@ -2,42 +2,45 @@ package handler
 
 import (
 	"fmt"
-	"strings"
-	"unicode/utf8"
+	"net/url"
+	"sync/atomic"
 
 	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
 	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/validation"
 )
 
 // 定数の定義
 const (
-	MaxTitleLength   = 100  // 投稿のタイトルの最大長
-	MaxContentLength = 1000 // 投稿の内容の最大長
-	MaxCommentLength = 500  // コメントの最大長
+	DefaultPageSize = 20  // 一覧取得の既定件数
+	MaxPageSize     = 100 // 一覧取得の最大件数
 )
 
-// 投稿の入力を検証する関数
-func validatePostInput(post models.Post) *apperror.AppError {
-	// タイトルが空の場合はエラーとする
-	if strings.TrimSpace(post.Title) == "" {
-		return apperror.NewAppError(apperror.TypeBadRequest, "Title must not be empty", nil)
-	}
+// 入力の検証に使う文字数の上限・下限(SIGHUPで再読み込みした設定を反映するため差し替えられるようにする)
+var validationLimits atomic.Pointer[config.ValidationConfig]
 
-	// タイトルが100文字より大きい場合はエラーとする
-	if utf8.RuneCountInString(post.Title) > MaxTitleLength {
-		return apperror.NewAppError(apperror.TypeBadRequest, "Title must be 100 characters or less", nil)
-	}
+func init() {
+	SetValidationLimits(config.Default().Validation)
+}
 
-	// 投稿の内容が空の場合はエラーとする
-	if strings.TrimSpace(post.Content) == "" {
-		return apperror.NewAppError(apperror.TypeBadRequest, "Content is required", nil)
-	}
+// 入力の検証に使う文字数の上限・下限を設定する
+func SetValidationLimits(limits config.ValidationConfig) {
+	validationLimits.Store(&limits)
+}
 
-	// 投稿内容が1000文字より大きい場合はエラーとする
-	if utf8.RuneCountInString(post.Content) > MaxContentLength {
-		return apperror.NewAppError(apperror.TypeBadRequest, "Content must be 1000 characters or less", nil)
-	}
-	return nil
+// 入力の検証に使う文字数の上限・下限を返す
+func currentLimits() config.ValidationConfig {
+	return *validationLimits.Load()
+}
+
+// 投稿の入力を検証する関数
+func validatePostInput(post models.Post) *apperror.AppError {
+	limits := currentLimits()
+	v := validation.New()
+	v.String("/title", "Title", post.Title).Required().MaxLength(limits.TitleMaxLength)
+	v.String("/content", "Content", post.Content).Required().MaxLength(limits.ContentMaxLength)
+	return v.Err()
 }
 
 // コメントの入力を検証する
@@ -50,47 +53,100 @@ func validateCommentUpdateInput(content string, commentID int) *apperror.AppErro
 	return validateCommentContent(content, fmt.Sprintf("CommentID=%d", commentID))
 }
 
-// コメント本文を検証する
+// コメント本文を検証する(投稿と同じく文字数で数え、ログで追えるように対象を内部向けのメッセージに付ける)
 func validateCommentContent(content string, target string) *apperror.AppError {
-	// コメントが空の場合はエラーとする
-	if strings.TrimSpace(content) == "" {
-		return apperror.NewAppError(apperror.TypeBadRequest, "Content is required : "+target, nil)
+	v := validation.New()
+	v.String("/content", "Content", content).Required().MaxLength(currentLimits().CommentMaxLength)
+	if err := v.Err(); err != nil {
+		err.Message += " : " + target
+		return err
 	}
-
-	// コメントが指定文字以上の場合はエラーとする
-	if len(content) > MaxCommentLength {
-		return apperror.NewAppError(apperror.TypeBadRequest, fmt.Sprintf("Content must be %d characters or less : %s", MaxCommentLength, target), nil)
-	}
-
 	return nil
 }
 
 // ユーザー登録の入力を検証する
 func validateSignupInput(user models.User) *apperror.AppError {
-	// ユーザー名が空の場合はエラーとする
-	if user.Username == "" {
-		return apperror.NewAppError(apperror.TypeBadRequest, "Username is required", nil)
-	}
-
-	// パスワードが8文字未満の場合はエラーとする
-	if len(user.Password) < 8 {
-		return apperror.NewAppError(apperror.TypeBadRequest, "Password must be at least 8 characters long", nil)
-	}
-
-	return nil
+	v := validation.New()
+	v.String("/username", "Username", user.Username).Required()
+	v.String("/password", "Password", user.Password).MinLength(currentLimits().PasswordMinLength)
+	return v.Err()
 }
 
 // ログインの入力を検証する
 func validateLoginInput(user models.User) *apperror.AppError {
-	// ユーザー名が空の場合はエラーとする
-	if user.Username == "" {
-		return apperror.NewAppError(apperror.TypeBadRequest, "Username is required", nil)
-	}
+	v := validation.New()
+	v.String("/username", "Username", user.Username).Required()
+	v.String("/password", "Password", user.Password).Required()
+	return v.Err()
+}
 
-	// パスワードが空の場合、エラーとする
-	if user.Password == "" {
-		return apperror.NewAppError(apperror.TypeBadRequest, "Password is required : Username="+user.Username, nil)
+// APIキー作成の入力を検証する(スコープ未指定の場合は読み取り専用にする)
+func validateAPIKeyInput(req *models.APIKeyRequest) *apperror.AppError {
+	v := validation.New()
+	v.String("/name", "Name", req.Name).Required().MaxLength(currentLimits().APIKeyNameMaxLength)
+
+	// 未知のスコープはエラーとし、重複は取り除く
+	scopes := []string{}
+	seen := map[string]bool{}
+	for i, scope := range req.Scopes {
+		v.String(validation.Pointer("scopes", i), "Scope", scope).OneOf(models.APIKeyScopeRead, models.APIKeyScopeWrite)
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
 	}
+	if err := v.Err(); err != nil {
+		return err
+	}
+	if len(scopes) == 0 {
+		scopes = []string{models.APIKeyScopeRead}
+	}
+	req.Scopes = scopes
+	return nil
+}
 
+// アカウント削除の入力を検証する
+func validateAccountDeletionInput(req models.AccountDeletionRequest) *apperror.AppError {
+	// 削除方式は明示的に指定させる
+	v := validation.New()
+	v.String("/mode", "Mode", req.Mode).OneOf(models.AccountDeletionModeAnonymize, models.AccountDeletionModePurge)
+	return v.Err()
+}
+
+// 通知設定の入力を検証する
+func validateNotificationPreferencesInput(req models.NotificationPreferencesRequest) *apperror.AppError {
+	// 変更する項目が1つも無い場合はリクエスト全体のエラーとする
+	v := validation.New()
+	v.Check("", req.Likes != nil || req.Comments != nil || req.Follows != nil, apperror.FieldRequired, "At least one of likes, comments or follows is required")
+	return v.Err()
+}
+
+// Webhookの入力を検証する
+func validateWebhookInput(req *models.WebhookRequest) *apperror.AppError {
+	v := validation.New()
+	// URLはhttp/httpsの絶対URLのみ受け付ける
+	v.String("/url", "URL", req.URL).Required().MaxLength(currentLimits().WebhookURLMaxLength).Must(isAbsoluteHTTPURL, "URL must be an absolute http or https URL")
+	v.Check("/events", len(req.Events) > 0, apperror.FieldRequired, "At least one event is required")
+
+	// 未知のイベントはエラーとし、重複は取り除く
+	events := []string{}
+	seen := map[string]bool{}
+	for i, event := range req.Events {
+		v.String(validation.Pointer("events", i), "Event", event).OneOf(models.WebhookEventPostCreated, models.WebhookEventPostUpdated, models.WebhookEventPostDeleted, models.WebhookEventCommentCreated)
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	if err := v.Err(); err != nil {
+		return err
+	}
+	req.Events = events
 	return nil
 }
+
+// http/httpsの絶対URLかどうか
+func isAbsoluteHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
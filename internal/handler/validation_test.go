@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// 投稿の入力で、すべての項目のエラーをJSON Pointerで返すことを確認する
+func TestValidatePostInput(t *testing.T) {
+	limits := config.Default().Validation
+	err := validatePostInput(models.Post{Title: "", Content: strings.Repeat("a", limits.ContentMaxLength+1)})
+	if err == nil || err.SubCode != apperror.CodeValidationFailed || len(err.Fields) != 2 {
+		t.Fatalf("err = %+v", err)
+	}
+	if err.Fields[0].Field != "/title" || err.Fields[0].Code != apperror.FieldRequired {
+		t.Errorf("fields[0] = %+v", err.Fields[0])
+	}
+	if err.Fields[1].Field != "/content" || err.Fields[1].Code != apperror.FieldTooLong {
+		t.Errorf("fields[1] = %+v", err.Fields[1])
+	}
+}
+
+// コメントの長さをバイト数ではなく文字数で数えることを確認する
+func TestValidateCommentContentLength(t *testing.T) {
+	limits := config.Default().Validation
+	if err := validateCommentInput(models.Comment{Content: strings.Repeat("あ", limits.CommentMaxLength)}, 1); err != nil {
+		t.Errorf("上限ちょうどの日本語のコメントがエラーになった: %v", err)
+	}
+	err := validateCommentUpdateInput(strings.Repeat("あ", limits.CommentMaxLength+1), 3)
+	if err == nil || err.Fields[0].Code != apperror.FieldTooLong {
+		t.Fatalf("err = %+v", err)
+	}
+	// 対象は内部向けのメッセージにだけ含める
+	if !strings.HasSuffix(err.Message, " : CommentID=3") || strings.Contains(err.PublicMessage(), "CommentID") {
+		t.Errorf("message = %q, public = %q", err.Message, err.PublicMessage())
+	}
+}
+
+// 設定した上限を検証に使うことを確認する
+func TestSetValidationLimits(t *testing.T) {
+	defer SetValidationLimits(config.Default().Validation)
+
+	limits := config.Default().Validation
+	limits.TitleMaxLength = 5
+	SetValidationLimits(limits)
+	if err := validatePostInput(models.Post{Title: "ブログのタイトル", Content: "本文"}); err == nil || err.Fields[0].Field != "/title" {
+		t.Errorf("err = %+v", err)
+	}
+}
+
+// Webhookの入力で、配列の要素のエラーを添字付きで返し、正常な場合は重複を取り除くことを確認する
+func TestValidateWebhookInput(t *testing.T) {
+	req := &models.WebhookRequest{URL: "ftp://example.com", Events: []string{models.WebhookEventPostCreated, "post.unknown"}}
+	err := validateWebhookInput(req)
+	if err == nil || len(err.Fields) != 2 {
+		t.Fatalf("err = %+v", err)
+	}
+	if err.Fields[0].Field != "/url" || err.Fields[0].Code != apperror.FieldInvalid {
+		t.Errorf("fields[0] = %+v", err.Fields[0])
+	}
+	if err.Fields[1].Field != "/events/1" || err.Fields[1].Code != apperror.FieldInvalid {
+		t.Errorf("fields[1] = %+v", err.Fields[1])
+	}
+
+	req = &models.WebhookRequest{URL: "https://example.com/hook", Events: []string{models.WebhookEventPostCreated, models.WebhookEventPostCreated}}
+	if err := validateWebhookInput(req); err != nil || len(req.Events) != 1 {
+		t.Errorf("err = %v, events = %v", err, req.Events)
+	}
+}
@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/service"
+	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
+)
+
+// CreateWebhookHandler godoc
+// @Summary Webhookを登録する
+// @Description 送信先のURLと受け取るイベント(post.created / post.updated / post.deleted / comment.created)を指定してWebhookを登録する。
+// @Description 配信には X-Webhook-Signature ヘッダーで HMAC-SHA256 の署名を付ける。署名用のシークレットはこのレスポンスでのみ返す
+// @Description
+// @Description **エラー条件:**
+// @Description - 無効なリクエスト、URLがhttp/httpsの絶対URLでない、URLが2048文字より大きい、イベントが空、未知のイベント → 400 Bad Request
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - APIキーでの操作 → 403 Forbidden
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param webhook body models.WebhookRequest true "送信先のURLとイベント"
+// @Success 201 {object} models.WebhookCreatedResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 403 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/webhooks [post]
+func CreateWebhookHandler(webhookService *service.WebhookService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// APIキーでWebhookを登録できないようにする
+		if appErr := requireJWTAuth(ctx); appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// リクエストボディを読み取る
+		var req models.WebhookRequest
+		if appErr := decodeJSON(r, &req); appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// Webhookのバリデーションを実施する
+		if err := validateWebhookInput(&req); err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		// Webhookを登録する
+		created, err := webhookService.CreateWebhook(ctx, userID, req)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusCreated, created)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "webhook_created", UserID: userID})
+	}
+}
+
+// ListWebhooksHandler godoc
+// @Summary Webhookの一覧を取得する
+// @Description ログインユーザーが登録したWebhookの一覧を取得する。シークレットは含まない
+// @Description
+// @Description **エラー条件:**
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags webhooks
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Success 200 {array} models.Webhook
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/webhooks [get]
+func ListWebhooksHandler(webhookService *service.WebhookService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// Webhookの一覧を取得する
+		webhooks, err := webhookService.ListWebhooks(ctx, userID)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, webhooks)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "webhooks_listed", UserID: userID})
+	}
+}
+
+// DeleteWebhookHandler godoc
+// @Summary Webhookを削除する
+// @Description 指定したIDのWebhookを削除する。配信待ちの配信も破棄される
+// @Description
+// @Description **エラー条件:**
+// @Description - 無効なID → 400 Bad Request
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - APIキーでの操作 → 403 Forbidden
+// @Description - Webhookが存在しない、他のユーザーのWebhook → 404 Not Found
+// @Description - データ更新/取得失敗 → 500 ServerError
+// @Tags webhooks
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "WebhookID"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 403 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/webhooks/{id} [delete]
+func DeleteWebhookHandler(webhookService *service.WebhookService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// APIキーでWebhookを削除できないようにする
+		if appErr := requireJWTAuth(ctx); appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// URIからWebhookのIDを取得
+		vars := mux.Vars(r)
+		id, appErr := parseID(vars["id"])
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// Webhookを削除する
+		if err := webhookService.DeleteWebhook(ctx, userID, id); err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusNoContent, nil)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "webhook_deleted", UserID: userID})
+	}
+}
+
+// GetWebhookDeliveriesHandler godoc
+// @Summary Webhookの配信履歴を取得する
+// @Description 指定したWebhookの配信履歴を作成日時の新しい順に取得する。next_cursor を cursor に指定すると続きを取得できる。
+// @Description status は pending(配信待ち・再送待ち) / succeeded(配信成功) / dead(再送の上限を超えた) のいずれか
+// @Description
+// @Description **エラー条件:**
+// @Description - 無効なID、不正なカーソル、limitが1～100の範囲外 → 400 Bad Request
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - Webhookが存在しない、他のユーザーのWebhook → 404 Not Found
+// @Description - データ更新/取得失敗 or レスポンス書き込み失敗 → 500 ServerError
+// @Tags webhooks
+// @Produce json
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "WebhookID"
+// @Param cursor query string false "前回のレスポンスの next_cursor"
+// @Param limit query int false "取得件数(既定20、最大100)"
+// @Success 200 {object} models.WebhookDeliveryListResponse
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/webhooks/{id}/deliveries [get]
+func GetWebhookDeliveriesHandler(webhookService *service.WebhookService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// URIからWebhookのIDを取得
+		vars := mux.Vars(r)
+		id, appErr := parseID(vars["id"])
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// カーソルと取得件数を取得
+		cursor, limit, appErr := pageParamsFromRequest(r)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// 配信履歴を取得する
+		deliveries, err := webhookService.ListDeliveries(ctx, userID, id, cursor, limit)
+		if err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, deliveries)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "webhook_deliveries_fetched", UserID: userID})
+	}
+}
+
+// RedeliverWebhookHandler godoc
+// @Summary Webhookを再配信する
+// @Description 指定した配信を試行回数を0に戻して配信待ちにし、すぐに再配信する。dead になった配信や成功済みの配信も再配信できる
+// @Description
+// @Description **エラー条件:**
+// @Description - 無効なID → 400 Bad Request
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Description - APIキーでの操作 → 403 Forbidden
+// @Description - Webhookまたは配信が存在しない、他のユーザーのWebhook → 404 Not Found
+// @Description - データ更新/取得失敗 → 500 ServerError
+// @Tags webhooks
+// @Param Authorization header string true "Bearer Token"
+// @Param id path int true "WebhookID"
+// @Param deliveryID path int true "配信ID"
+// @Success 202 "Accepted"
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Failure 403 {object} models.ProblemDetails
+// @Failure 404 {object} models.ProblemDetails
+// @Failure 500 {object} models.ProblemDetails
+// @Router /api/webhooks/{id}/deliveries/{deliveryID}/redeliver [post]
+func RedeliverWebhookHandler(webhookService *service.WebhookService, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// APIキーで再配信できないようにする
+		if appErr := requireJWTAuth(ctx); appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// URIからWebhookと配信のIDを取得
+		vars := mux.Vars(r)
+		id, appErr := parseID(vars["id"])
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+		deliveryID, appErr := parseID(vars["deliveryID"])
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// 配信をやり直す
+		if err := webhookService.Redeliver(ctx, userID, id, int64(deliveryID)); err != nil {
+			respondAppError(w, r, err)
+			return
+		}
+
+		respondJSON(w, http.StatusAccepted, nil)
+
+		// 監視ワーカープールにイベントを追加
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "webhook_redelivered", UserID: userID})
+	}
+}
@@ -0,0 +1,260 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
+	"github.com/yusuke-hoguro/BlogApi/internal/handler"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/service"
+)
+
+// 送信先が受け取ったWebhookのリクエスト
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+// テスト用の送信先はローカルアドレスのためプライベートアドレスへの送信を許可する
+func allowPrivateWebhooks(cfg *config.Config) {
+	cfg.Webhook.AllowPrivateNetworks = true
+}
+
+// Webhookの送信先を作成するヘルパー(statusCodes の順にステータスコードを返し、以降は最後のステータスコードを返す)
+func newWebhookReceiver(t *testing.T, statusCodes ...int) (*httptest.Server, <-chan receivedWebhook) {
+	t.Helper()
+	received := make(chan receivedWebhook, 10)
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedWebhook{header: r.Header.Clone(), body: body}
+		n := int(calls.Add(1)) - 1
+		w.WriteHeader(statusCodes[min(n, len(statusCodes)-1)])
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver, received
+}
+
+// Webhookを登録するヘルパー
+func createWebhook(t *testing.T, server *httptest.Server, token string, body string) models.WebhookCreatedResponse {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/api/webhooks", strings.NewReader(body))
+	if err != nil {
+		t.Fatal("リクエスト生成失敗:", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal("HTTPリクエスト失敗:", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusCreated, resp.StatusCode)
+	}
+	var created models.WebhookCreatedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("JSONのデコード失敗: %v", err)
+	}
+	return created
+}
+
+// 送信先がWebhookを受け取るまで待つヘルパー
+func waitWebhook(t *testing.T, received <-chan receivedWebhook) receivedWebhook {
+	t.Helper()
+	select {
+	case webhook := <-received:
+		return webhook
+	case <-time.After(5 * time.Second):
+		t.Fatal("Webhookが配信されない")
+		return receivedWebhook{}
+	}
+}
+
+// 配信履歴が条件を満たすまで待つヘルパー
+func waitDelivery(t *testing.T, server *httptest.Server, token string, webhookID int, match func(models.WebhookDelivery) bool) models.WebhookDelivery {
+	t.Helper()
+	path := fmt.Sprintf("/api/webhooks/%d/deliveries", webhookID)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var list models.WebhookDeliveryListResponse
+		if status := getJSONWithToken(t, server, path, token, &list); status != http.StatusOK {
+			t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusOK, status)
+		}
+		if len(list.Deliveries) == 1 && match(list.Deliveries[0]) {
+			return list.Deliveries[0]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("配信履歴が想定と異なる: %+v", list.Deliveries)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// 投稿の作成が署名付きで配信され、失敗した配信を再配信できることを確認する
+func TestWebhookDeliveryAndRedeliver(t *testing.T) {
+	// テスト用の送信先はローカルアドレスのため許可する
+	_, _, server, cleanup := setupAccountTestServer(t, allowPrivateWebhooks)
+	defer cleanup()
+
+	// 1回目は失敗し、2回目以降は成功する送信先
+	receiver, received := newWebhookReceiver(t, http.StatusInternalServerError, http.StatusOK)
+
+	token, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	webhook := createWebhook(t, server, token, `{"url": "`+receiver.URL+`", "events": ["post.created", "post.created"]}`)
+	if !strings.HasPrefix(webhook.Secret, "whsec_") || len(webhook.Events) != 1 {
+		t.Errorf("Webhookが想定と異なる: %+v", webhook)
+	}
+
+	// 投稿を作成するとWebhookが配信される
+	if status := doWithAuthorization(t, server, http.MethodPost, "/api/posts", "Bearer "+token, `{"title": "Webhook", "content": "配信テスト"}`); status != http.StatusCreated {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusCreated, status)
+	}
+	first := waitWebhook(t, received)
+	if first.header.Get("X-Webhook-Event") != models.WebhookEventPostCreated {
+		t.Errorf("イベントが想定と異なる: %s", first.header.Get("X-Webhook-Event"))
+	}
+	signature := service.SignWebhookPayload(webhook.Secret, first.header.Get("X-Webhook-Timestamp"), first.body)
+	if first.header.Get("X-Webhook-Signature") != signature {
+		t.Errorf("署名が一致しない: %s", first.header.Get("X-Webhook-Signature"))
+	}
+	var payload struct {
+		Event string      `json:"event"`
+		Data  models.Post `json:"data"`
+	}
+	if err := json.Unmarshal(first.body, &payload); err != nil || payload.Data.Title != "Webhook" {
+		t.Errorf("ペイロードが想定と異なる: %s", string(first.body))
+	}
+
+	// 失敗した配信は再送待ちになる
+	delivery := waitDelivery(t, server, token, webhook.ID, func(d models.WebhookDelivery) bool { return d.Attempts == 1 })
+	if delivery.Status != models.WebhookDeliveryStatusPending || delivery.LastStatusCode == nil || *delivery.LastStatusCode != http.StatusInternalServerError || delivery.NextAttemptAt == nil {
+		t.Errorf("配信履歴が想定と異なる: %+v", delivery)
+	}
+
+	// 他のユーザーは配信履歴を参照・再配信できない
+	otherToken, err := handler.GenerateJWT(2)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	redeliverPath := fmt.Sprintf("/api/webhooks/%d/deliveries/%d/redeliver", webhook.ID, delivery.ID)
+	if status := getJSONWithToken(t, server, fmt.Sprintf("/api/webhooks/%d/deliveries", webhook.ID), otherToken, &models.WebhookDeliveryListResponse{}); status != http.StatusNotFound {
+		t.Errorf("[他のユーザーの配信履歴] 期待するステータスコード %d, 実際は %d", http.StatusNotFound, status)
+	}
+	if status := doWithAuthorization(t, server, http.MethodPost, redeliverPath, "Bearer "+otherToken, ""); status != http.StatusNotFound {
+		t.Errorf("[他のユーザーの再配信] 期待するステータスコード %d, 実際は %d", http.StatusNotFound, status)
+	}
+
+	// 再配信すると同じ配信IDで送信され、成功が記録される
+	if status := doWithAuthorization(t, server, http.MethodPost, redeliverPath, "Bearer "+token, ""); status != http.StatusAccepted {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusAccepted, status)
+	}
+	second := waitWebhook(t, received)
+	if second.header.Get("X-Webhook-Delivery") != first.header.Get("X-Webhook-Delivery") {
+		t.Errorf("配信IDが一致しない: %s != %s", second.header.Get("X-Webhook-Delivery"), first.header.Get("X-Webhook-Delivery"))
+	}
+	delivery = waitDelivery(t, server, token, webhook.ID, func(d models.WebhookDelivery) bool {
+		return d.Status == models.WebhookDeliveryStatusSucceeded
+	})
+	if delivery.DeliveredAt == nil || delivery.Attempts != 1 {
+		t.Errorf("配信履歴が想定と異なる: %+v", delivery)
+	}
+}
+
+// 試行回数の上限を超えた配信が dead になることを確認する
+func TestWebhookDeadLetter(t *testing.T) {
+	// テスト用の送信先はローカルアドレスのため許可する
+	db, services, server, cleanup := setupAccountTestServer(t, allowPrivateWebhooks)
+	defer cleanup()
+
+	receiver, received := newWebhookReceiver(t, http.StatusServiceUnavailable)
+
+	token, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	webhook := createWebhook(t, server, token, `{"url": "`+receiver.URL+`", "events": ["post.deleted"]}`)
+
+	// 投稿(ID=1)を削除するとWebhookが配信される
+	if status := doWithAuthorization(t, server, http.MethodDelete, "/api/posts/1", "Bearer "+token, ""); status != http.StatusNoContent {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusNoContent, status)
+	}
+	waitWebhook(t, received)
+	delivery := waitDelivery(t, server, token, webhook.ID, func(d models.WebhookDelivery) bool { return d.Attempts == 1 })
+
+	// 試行回数を上限の直前にしてから配信すると dead になる
+	if _, err := db.Exec("UPDATE webhook_deliveries SET attempts = $1 WHERE id = $2", config.Default().Webhook.MaxAttempts-1, delivery.ID); err != nil {
+		t.Fatal("配信の更新に失敗:", err)
+	}
+	if err := services.Webhook.Deliver(context.Background(), delivery.ID); err != nil {
+		t.Fatal("配信に失敗:", err)
+	}
+	waitWebhook(t, received)
+	delivery = waitDelivery(t, server, token, webhook.ID, func(d models.WebhookDelivery) bool {
+		return d.Status == models.WebhookDeliveryStatusDead
+	})
+	if delivery.NextAttemptAt != nil || delivery.Attempts != config.Default().Webhook.MaxAttempts {
+		t.Errorf("配信履歴が想定と異なる: %+v", delivery)
+	}
+}
+
+// Webhookの登録・削除の入力チェックを確認する
+func TestWebhookInvalidInput(t *testing.T) {
+	_, _, server, cleanup := setupAccountTestServer(t)
+	defer cleanup()
+
+	token, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"URLが空", `{"url": "", "events": ["post.created"]}`},
+		{"相対URL", `{"url": "/hooks", "events": ["post.created"]}`},
+		{"http/https以外", `{"url": "ftp://example.com/hooks", "events": ["post.created"]}`},
+		{"イベントが空", `{"url": "https://example.com/hooks", "events": []}`},
+		{"未知のイベント", `{"url": "https://example.com/hooks", "events": ["post.liked"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := doWithAuthorization(t, server, http.MethodPost, "/api/webhooks", "Bearer "+token, tt.body); status != http.StatusBadRequest {
+				t.Errorf("期待するステータスコード %d, 実際は %d", http.StatusBadRequest, status)
+			}
+		})
+	}
+
+	// 他のユーザーのWebhookは削除できない
+	webhook := createWebhook(t, server, token, `{"url": "https://example.com/hooks", "events": ["comment.created"]}`)
+	otherToken, err := handler.GenerateJWT(2)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	path := fmt.Sprintf("/api/webhooks/%d", webhook.ID)
+	if status := doWithAuthorization(t, server, http.MethodDelete, path, "Bearer "+otherToken, ""); status != http.StatusNotFound {
+		t.Errorf("[他のユーザーのWebhook] 期待するステータスコード %d, 実際は %d", http.StatusNotFound, status)
+	}
+	if status := doWithAuthorization(t, server, http.MethodDelete, path, "Bearer "+token, ""); status != http.StatusNoContent {
+		t.Errorf("[削除] 期待するステータスコード %d, 実際は %d", http.StatusNoContent, status)
+	}
+	var webhooks []models.Webhook
+	if status := getJSONWithToken(t, server, "/api/webhooks", token, &webhooks); status != http.StatusOK || len(webhooks) != 0 {
+		t.Errorf("Webhookが削除されていない: status=%d %+v", status, webhooks)
+	}
+}
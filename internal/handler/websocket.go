@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
+	"github.com/yusuke-hoguro/BlogApi/internal/middleware"
+	"github.com/yusuke-hoguro/BlogApi/internal/service"
+	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
+)
+
+// WebSocketのアップグレード設定
+// サブプロトコルは JWT を含む "bearer.<JWT>" を返さないように WebSocketProtocol だけを選択する
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	Subprotocols:    []string{middleware.WebSocketProtocol},
+	CheckOrigin:     checkWebSocketOrigin,
+}
+
+// WebSocketの接続を許可するオリジン(CORSと同じ許可リストを使い、設定の再読み込みで差し替える)
+var wsAllowedOrigins atomic.Pointer[[]string]
+
+// WebSocketの接続を許可するオリジンを設定する
+func SetWebSocketAllowedOrigins(origins []string) {
+	wsAllowedOrigins.Store(&origins)
+}
+
+// WebSocketの接続元のオリジンを確認する
+// ブラウザ以外(Originなし)と同じホストからの接続は許可し、それ以外はCORSの許可リストに含まれるオリジンだけを許可する
+func checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	allowed := wsAllowedOrigins.Load()
+	return allowed != nil && middleware.OriginAllowed(*allowed, origin)
+}
+
+// WebSocketHandler godoc
+// @Summary ユーザーごとのWebSocketに接続する
+// @Description ログインユーザー宛ての通知と、フォロー中ユーザーの接続状態をWebSocketで配信する
+// @Description メッセージは {"type": "notification" | "presence" | "presence_snapshot", "data": {...}} の形式
+// @Description ブラウザからはサブプロトコルに "blogapi.v1" と "bearer.<JWT>" を指定して接続する(URLにJWTを含めない)
+// @Description CORSの許可リストに含まれないオリジンからの接続は 403 Forbidden とする
+// @Description 送信が追いつかない場合はクローズコード1013、サーバーのシャットダウン時は1001で切断する
+// @Description
+// @Description **エラー条件:**
+// @Description - WebSocketのアップグレードでないリクエスト → 400 Bad Request
+// @Description - リクエスト認証エラー → 401 Unauthorized
+// @Tags realtime
+// @Param Authorization header string false "Bearer Token"
+// @Param Sec-WebSocket-Protocol header string false "blogapi.v1, bearer.<JWT>(Authorizationヘッダーを指定できない場合)"
+// @Success 101 "Switching Protocols"
+// @Failure 400 {object} models.ProblemDetails
+// @Failure 401 {object} models.ProblemDetails
+// @Router /api/ws [get]
+func WebSocketHandler(realtimeService *service.RealtimeService, cfg config.WebSocketConfig, auditPool *workerpool.AuditWorkerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// リクエストのコンテキストを取得する
+		ctx := r.Context()
+
+		// 認証情報からユーザーIDを取得
+		userID, appErr := userIDFromContext(ctx)
+		if appErr != nil {
+			respondAppError(w, r, appErr)
+			return
+		}
+
+		// WebSocketにアップグレードする(失敗時はUpgraderがエラーレスポンスを返す)
+		conn, err := wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("Failed to upgrade websocket : UserID=%d : %v", userID, err)
+			return
+		}
+		defer conn.Close()
+
+		// 接続を登録し、切断時はフォロワーへのオフライン通知のため新しいコンテキストで解除する
+		client := realtimeService.Connect(ctx, userID)
+		defer func() {
+			disconnectCtx, cancel := context.WithTimeout(context.Background(), cfg.WriteTimeout)
+			defer cancel()
+			realtimeService.Disconnect(disconnectCtx, client)
+		}()
+
+		// 監視ワーカープールにイベントを追加(接続が続くため接続時に記録する)
+		enqueueAuditEvent(ctx, auditPool, workerpool.AuditEvent{Action: "websocket_connected", UserID: userID})
+
+		// クライアントからの受信はPongと切断の検知にだけ使う
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			conn.SetReadLimit(int64(cfg.MaxMessageSize))
+			_ = conn.SetReadDeadline(time.Now().Add(cfg.PongWait))
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(cfg.PongWait))
+			})
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) && !errors.Is(err, websocket.ErrCloseSent) {
+						log.Printf("Websocket read error : UserID=%d : %v", userID, err)
+					}
+					return
+				}
+			}
+		}()
+
+		ping := time.NewTicker(cfg.PingInterval)
+		defer ping.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case message, ok := <-client.Messages:
+				if !ok {
+					// 送信が追いつかない場合は再接続を促し、それ以外はシャットダウンとして切断する
+					code, reason := websocket.CloseGoingAway, "server shutting down"
+					if client.SlowConsumer {
+						code, reason = websocket.CloseTryAgainLater, "slow consumer"
+					}
+					deadline := time.Now().Add(cfg.WriteTimeout)
+					_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+					return
+				}
+				_ = conn.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+				if err := conn.WriteJSON(message); err != nil {
+					log.Printf("Failed to write websocket message : UserID=%d : %v", userID, err)
+					return
+				}
+			case <-ping.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(cfg.WriteTimeout)); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yusuke-hoguro/BlogApi/internal/handler"
+	"github.com/yusuke-hoguro/BlogApi/internal/middleware"
+	"github.com/yusuke-hoguro/BlogApi/internal/pubsub"
+	"github.com/yusuke-hoguro/BlogApi/internal/service"
+)
+
+// ブラウザと同じくサブプロトコルでJWTを渡すDialer
+func webSocketDialer(token string) *websocket.Dialer {
+	return &websocket.Dialer{Subprotocols: []string{middleware.WebSocketProtocol, middleware.WebSocketTokenProtocolPrefix + token}}
+}
+
+// 指定したユーザーでWebSocketに接続するヘルパー
+func dialWebSocket(t *testing.T, server *httptest.Server, userID int) *websocket.Conn {
+	t.Helper()
+	token, err := handler.GenerateJWT(userID)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/ws"
+	conn, resp, err := webSocketDialer(token).Dial(wsURL, nil)
+	if err != nil {
+		t.Fatal("WebSocketの接続失敗:", err)
+	}
+	resp.Body.Close()
+	// JWTを含むサブプロトコルは返さない
+	if conn.Subprotocol() != middleware.WebSocketProtocol {
+		t.Errorf("期待するサブプロトコル %s, 実際は %q", middleware.WebSocketProtocol, conn.Subprotocol())
+	}
+	return conn
+}
+
+// 指定した種類のメッセージを受信するまで待つ
+func readWebSocketMessage(t *testing.T, conn *websocket.Conn, messageType string) map[string]any {
+	t.Helper()
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal("読み込みのタイムアウト設定失敗:", err)
+	}
+	for {
+		var message pubsub.Message
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("メッセージ %s を受信できない: %v", messageType, err)
+		}
+		if message.Type == messageType {
+			data, _ := message.Data.(map[string]any)
+			return data
+		}
+	}
+}
+
+// 通知と接続状態がWebSocketで配信されることを確認する
+func TestWebSocketNotificationsAndPresence(t *testing.T) {
+	_, _, server, cleanup := setupAccountTestServer(t)
+	defer cleanup()
+
+	// ユーザー1がユーザー2をフォローする
+	token, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	if status := doWithAuthorization(t, server, http.MethodPost, "/api/users/2/follow", "Bearer "+token, ""); status != http.StatusCreated {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusCreated, status)
+	}
+
+	conn := dialWebSocket(t, server, 1)
+	defer conn.Close()
+	readWebSocketMessage(t, conn, service.RealtimeMessageSnapshot)
+
+	// フォロー中のユーザー2の接続・切断が通知される
+	other := dialWebSocket(t, server, 2)
+	if presence := readWebSocketMessage(t, conn, service.RealtimeMessagePresence); presence["user_id"] != float64(2) || presence["online"] != true {
+		t.Errorf("接続状態が想定と異なる: %v", presence)
+	}
+
+	// ユーザー2がユーザー1の投稿(ID=1)にコメントすると通知が届く
+	otherToken, err := handler.GenerateJWT(2)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	if status := doWithAuthorization(t, server, http.MethodPost, "/api/posts/1/comments", "Bearer "+otherToken, `{"content":"通知テスト"}`); status != http.StatusCreated {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusCreated, status)
+	}
+	if notification := readWebSocketMessage(t, conn, service.RealtimeMessageNotification); notification["type"] != "comment" || notification["post_id"] != float64(1) {
+		t.Errorf("通知が想定と異なる: %v", notification)
+	}
+
+	other.Close()
+	if presence := readWebSocketMessage(t, conn, service.RealtimeMessagePresence); presence["user_id"] != float64(2) || presence["online"] != false {
+		t.Errorf("接続状態が想定と異なる: %v", presence)
+	}
+}
+
+// 認証情報が無い・不正な場合は接続できないことを確認する
+func TestWebSocketUnauthorized(t *testing.T) {
+	_, _, server, cleanup := setupAccountTestServer(t)
+	defer cleanup()
+
+	token, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/ws"
+	tests := []struct {
+		name   string
+		url    string
+		dialer *websocket.Dialer
+	}{
+		{"認証情報なし", wsURL, websocket.DefaultDialer},
+		{"不正なJWT", wsURL, webSocketDialer("invalid")},
+		// URLのJWTは受け付けない
+		{"クエリのJWT", wsURL + "?access_token=" + token, websocket.DefaultDialer},
+	}
+	for _, tt := range tests {
+		conn, resp, err := tt.dialer.Dial(tt.url, nil)
+		if err == nil {
+			conn.Close()
+			t.Fatalf("[%s] 認証なしで接続できてしまった", tt.name)
+		}
+		if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("[%s] 期待するステータスコード %d, 実際は %v", tt.name, http.StatusUnauthorized, resp)
+		}
+	}
+}
+
+// CORSの許可リストに含まれないオリジンからは接続できないことを確認する
+func TestWebSocketOrigin(t *testing.T) {
+	_, _, server, cleanup := setupAccountTestServer(t)
+	defer cleanup()
+	handler.SetWebSocketAllowedOrigins([]string{"https://app.example.com"})
+	defer handler.SetWebSocketAllowedOrigins(nil)
+
+	token, err := handler.GenerateJWT(1)
+	if err != nil {
+		t.Fatal("JWTの生成に失敗:", err)
+	}
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/ws"
+	tests := []struct {
+		origin string
+		want   int
+	}{
+		{"https://app.example.com", http.StatusSwitchingProtocols},
+		{"https://evil.example.com", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		conn, resp, err := webSocketDialer(token).Dial(wsURL, http.Header{"Origin": []string{tt.origin}})
+		if err == nil {
+			conn.Close()
+		}
+		if resp == nil || resp.StatusCode != tt.want {
+			t.Errorf("[%s] 期待するステータスコード %d, 実際は %v", tt.origin, tt.want, resp)
+		}
+	}
+}
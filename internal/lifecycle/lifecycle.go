@@ -0,0 +1,121 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// 停止処理が期限を過ぎた後、結果を返すのを待つ時間
+// ctx に従って停止する処理は期限の直後に結果(破棄した件数など)を返すため、その結果を報告に含める
+const abandonGrace = 500 * time.Millisecond
+
+// コンポーネントの停止処理
+// Stop には Timeout を期限とする ctx を渡し、期限を過ぎても終わらない場合は終了を待たずに次のコンポーネントの停止に進む
+type Hook struct {
+	Name    string
+	Timeout time.Duration
+	Stop    func(ctx context.Context) error
+}
+
+// 期限を受け取らない停止処理を Hook の Stop にする(期限を過ぎた場合は終了を待たずに次のコンポーネントの停止に進む)
+func StopFunc(stop func()) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		stop()
+		return nil
+	}
+}
+
+// コンポーネントごとの停止の結果
+type Result struct {
+	Name     string
+	Elapsed  time.Duration
+	Err      error
+	TimedOut bool // 期限までに停止できなかった(処理中・キューに残った処理を破棄した)
+}
+
+// 停止の結果の一覧(停止した順)
+type Report []Result
+
+// 停止に失敗したコンポーネントのエラーをまとめて返す(すべて停止できた場合はnil)
+func (r Report) Err() error {
+	var errs []error
+	for _, result := range r {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Name, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// コンポーネントの停止の順番を管理する構造体
+// 起動した順に登録し、停止は登録と逆の順に1つずつ行う(依存されるコンポーネントほど後に停止する)
+type Manager struct {
+	mu     sync.Mutex
+	hooks  []Hook
+	once   sync.Once
+	report Report
+}
+
+// 新規マネージャーの作成
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// 停止処理を登録する(Shutdown の前に呼び出す)
+func (m *Manager) Register(hook Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook)
+}
+
+// 登録と逆の順にコンポーネントを停止して結果を返す
+// 2回目以降の呼び出しは停止せずに最初の結果を返す
+func (m *Manager) Shutdown(ctx context.Context) Report {
+	m.once.Do(func() {
+		m.mu.Lock()
+		hooks := m.hooks
+		m.mu.Unlock()
+
+		for i := len(hooks) - 1; i >= 0; i-- {
+			m.report = append(m.report, runHook(ctx, hooks[i]))
+		}
+	})
+	return m.report
+}
+
+// 停止処理を期限付きで実行する
+func runHook(parent context.Context, hook Hook) Result {
+	ctx, cancel := context.WithTimeout(parent, hook.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- hook.Stop(ctx)
+	}()
+
+	result := Result{Name: hook.Name}
+	select {
+	case result.Err = <-done:
+	case <-ctx.Done():
+		select {
+		case result.Err = <-done:
+		case <-time.After(abandonGrace):
+			result.Err = fmt.Errorf("did not stop within %s: %w", hook.Timeout, ctx.Err())
+		}
+	}
+	result.Elapsed = time.Since(start)
+	result.TimedOut = ctx.Err() != nil
+
+	switch {
+	case result.Err != nil:
+		log.Printf("shutdown: %s failed after %s: %v", hook.Name, result.Elapsed.Round(time.Millisecond), result.Err)
+	default:
+		log.Printf("shutdown: %s stopped in %s", hook.Name, result.Elapsed.Round(time.Millisecond))
+	}
+	return result
+}
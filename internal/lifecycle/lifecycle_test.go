@@ -0,0 +1,68 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/lifecycle"
+)
+
+// 登録と逆の順に停止し、期限を過ぎた停止処理を待たずに次に進むことを確認する
+func TestManagerShutdown(t *testing.T) {
+	var stopped []string
+	stop := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			stopped = append(stopped, name)
+			return nil
+		}
+	}
+	release := make(chan struct{})
+	defer close(release)
+
+	m := lifecycle.NewManager()
+	m.Register(lifecycle.Hook{Name: "database", Timeout: time.Second, Stop: stop("database")})
+	m.Register(lifecycle.Hook{Name: "hung", Timeout: 20 * time.Millisecond, Stop: lifecycle.StopFunc(func() { <-release })})
+	m.Register(lifecycle.Hook{Name: "queue", Timeout: 20 * time.Millisecond, Stop: func(ctx context.Context) error {
+		<-ctx.Done()
+		return errors.New("3 events dropped")
+	}})
+	m.Register(lifecycle.Hook{Name: "http_server", Timeout: time.Second, Stop: stop("http_server")})
+
+	start := time.Now()
+	report := m.Shutdown(context.Background())
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("期限を過ぎた停止処理を待っている: %s", elapsed)
+	}
+	if strings.Join(stopped, ",") != "http_server,database" {
+		t.Errorf("停止の順番が想定と異なる: %v", stopped)
+	}
+
+	var names []string
+	for _, result := range report {
+		names = append(names, result.Name)
+	}
+	if strings.Join(names, ",") != "http_server,queue,hung,database" {
+		t.Fatalf("結果の順番が想定と異なる: %v", names)
+	}
+	if report[0].Err != nil || report[0].TimedOut {
+		t.Errorf("正常に停止した結果が想定と異なる: %+v", report[0])
+	}
+	// 期限の直後に返した結果(破棄した件数など)は報告に含める
+	if !report[1].TimedOut || report[1].Err == nil || report[1].Err.Error() != "3 events dropped" {
+		t.Errorf("期限を過ぎた停止処理の結果が想定と異なる: %+v", report[1])
+	}
+	if !report[2].TimedOut || !errors.Is(report[2].Err, context.DeadlineExceeded) {
+		t.Errorf("終わらない停止処理の結果が想定と異なる: %+v", report[2])
+	}
+	if err := report.Err(); err == nil || !strings.Contains(err.Error(), "queue: 3 events dropped") || !strings.Contains(err.Error(), "hung:") {
+		t.Errorf("エラーが想定と異なる: %v", err)
+	}
+
+	// 2回目以降は停止せずに最初の結果を返す
+	if again := m.Shutdown(context.Background()); len(again) != len(report) || len(stopped) != 2 {
+		t.Errorf("2回目の呼び出しで停止処理が実行された: %v", stopped)
+	}
+}
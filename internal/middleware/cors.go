@@ -1,22 +1,131 @@
 package middleware
 
-import "net/http"
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// プリフライトで許可するリクエストヘッダー
+const corsAllowedHeaders = "Content-Type, Authorization, Last-Event-ID, If-None-Match, If-Match"
+
+// ルートごとに許可するメソッドを調べる候補(OPTIONSはこのミドルウェアで応答する)
+var corsMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// CORSの設定
+type CORSOptions struct {
+	AllowedOrigins []string      // 許可するオリジン(https://app.example.com または https://*.example.com)
+	ExposedHeaders []string      // ブラウザのスクリプトから読めるようにするレスポンスヘッダー
+	MaxAge         time.Duration // プリフライトの結果をブラウザがキャッシュする時間
+}
 
 // CORS設定
-func CorsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// CORSヘッダーを設定
-		w.Header().Set("Access-Control-Allow-Origin", "*") // 実環境では任意のドメインにする
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-
-		// プリフライトリクエストへの対応
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
+// 許可したオリジンからのリクエストにだけ、そのオリジンを Access-Control-Allow-Origin に返す
+// プリフライトで許可するメソッドは router に登録されたルートから求める
+// options はリクエストごとに呼び出す(設定の再読み込みで変更できるようにする)
+func CorsMiddleware(router *mux.Router, options func() CORSOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			opts := options()
+			origin := r.Header.Get("Origin")
+			// オリジンによって応答が変わるためキャッシュを分ける
+			w.Header().Add("Vary", "Origin")
+
+			// プリフライトリクエストへの対応
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+				methods := allowedMethods(router, r)
+				if origin == "" || !originAllowed(opts.AllowedOrigins, origin) || !containsMethod(methods, r.Header.Get("Access-Control-Request-Method")) {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+				w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+				if opts.MaxAge > 0 {
+					w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge.Seconds())))
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			// CORS以外のOPTIONSリクエストには許可するメソッドを返す
+			if r.Method == http.MethodOptions {
+				if methods := allowedMethods(router, r); len(methods) > 0 {
+					w.Header().Set("Allow", strings.Join(methods, ", "))
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+
+			if origin != "" && originAllowed(opts.AllowedOrigins, origin) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+				if len(opts.ExposedHeaders) > 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(opts.ExposedHeaders, ", "))
+				}
+			}
+			// 次のハンドラーへ処理を渡す
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// リクエストのパスに登録されたルートが受け付けるメソッドを返す(OPTIONSを含む)
+func allowedMethods(router *mux.Router, r *http.Request) []string {
+	var methods []string
+	for _, method := range corsMethods {
+		req := r.Clone(r.Context())
+		req.Method = method
+		var match mux.RouteMatch
+		if router.Match(req, &match) && match.MatchErr == nil {
+			methods = append(methods, method)
+		}
+	}
+	if len(methods) > 0 {
+		methods = append(methods, http.MethodOptions)
+	}
+	return methods
+}
+
+// メソッドが含まれるか判定する
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// オリジンが許可リストに含まれるか判定する(WebSocketの接続元の確認にも使う)
+func OriginAllowed(allowed []string, origin string) bool {
+	return originAllowed(allowed, origin)
+}
+
+// オリジンが許可リストに含まれるか判定する
+// https://*.example.com は example.com のサブドメイン(多段を含む)に一致し、example.com 自体には一致しない
+func originAllowed(allowed []string, origin string) bool {
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	for _, pattern := range allowed {
+		p, err := url.Parse(strings.ToLower(pattern))
+		if err != nil || p.Scheme != u.Scheme {
+			continue
+		}
+		if p.Host == u.Host {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(p.Host, "*"); ok && strings.HasSuffix(u.Host, suffix) && len(u.Host) > len(suffix) {
+			return true
 		}
-		// 次のハンドラーへ処理を渡す
-		next.ServeHTTP(w, r)
-	})
+	}
+	return false
 }
@@ -0,0 +1,102 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yusuke-hoguro/BlogApi/internal/middleware"
+)
+
+// CORSのテスト用のハンドラーを作成する
+func newCORSHandler() http.Handler {
+	r := mux.NewRouter()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r.HandleFunc("/api/posts", ok).Methods(http.MethodGet)
+	r.HandleFunc("/api/posts/{id}", ok).Methods(http.MethodGet)
+	r.HandleFunc("/api/posts/{id}", ok).Methods(http.MethodPut)
+	options := func() middleware.CORSOptions {
+		return middleware.CORSOptions{
+			AllowedOrigins: []string{"https://app.example.com", "https://*.preview.example.com"},
+			ExposedHeaders: []string{"ETag"},
+			MaxAge:         10 * time.Minute,
+		}
+	}
+	return middleware.CorsMiddleware(r, options)(r)
+}
+
+// 許可リストに一致したオリジンだけが返されることを確認する
+func TestCorsMiddlewareOrigin(t *testing.T) {
+	h := newCORSHandler()
+	tests := []struct {
+		origin string
+		want   string
+	}{
+		{"https://app.example.com", "https://app.example.com"},
+		{"https://pr-1.preview.example.com", "https://pr-1.preview.example.com"},
+		{"https://a.b.preview.example.com", "https://a.b.preview.example.com"},
+		{"https://preview.example.com", ""},
+		{"http://app.example.com", ""},
+		{"https://app.example.com:8443", ""},
+		{"https://evil.com", ""},
+		{"https://app.example.com.evil.com", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.want {
+			t.Errorf("origin %q: Allow-Origin get %q, want %q", tt.origin, got, tt.want)
+		}
+		if rec.Header().Get("Vary") != "Origin" {
+			t.Errorf("origin %q: Vary: Origin が設定されない: %v", tt.origin, rec.Header()["Vary"])
+		}
+		if tt.want != "" && (rec.Header().Get("Access-Control-Allow-Credentials") != "true" || rec.Header().Get("Access-Control-Expose-Headers") != "ETag") {
+			t.Errorf("origin %q: ヘッダーが想定と異なる: %v", tt.origin, rec.Header())
+		}
+	}
+}
+
+// プリフライトでルートに登録されたメソッドだけが許可されることを確認する
+func TestCorsMiddlewarePreflight(t *testing.T) {
+	h := newCORSHandler()
+	tests := []struct {
+		name        string
+		path        string
+		origin      string
+		method      string
+		wantStatus  int
+		wantMethods string
+	}{
+		{"許可", "/api/posts/1", "https://app.example.com", http.MethodPut, http.StatusNoContent, "GET, PUT, OPTIONS"},
+		{"ルートに無いメソッド", "/api/posts", "https://app.example.com", http.MethodDelete, http.StatusForbidden, ""},
+		{"許可されないオリジン", "/api/posts/1", "https://evil.com", http.MethodPut, http.StatusForbidden, ""},
+		{"存在しないパス", "/api/unknown", "https://app.example.com", http.MethodGet, http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ステータスコードが想定と異なる: get %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Errorf("Allow-Methods get %q, want %q", got, tt.wantMethods)
+			}
+			if tt.wantStatus == http.StatusNoContent && rec.Header().Get("Access-Control-Max-Age") != "600" {
+				t.Errorf("Max-Age が設定されない: %v", rec.Header())
+			}
+		})
+	}
+}
@@ -2,66 +2,326 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
 )
 
 type contextKey string
 
 // 衝突を防ぐために独自の型をキーに使用
-const UserIDKey contextKey = "userID"
+const (
+	UserIDKey              contextKey = "userID"
+	AuthMethodKey          contextKey = "authMethod"
+	APIKeyScopesKey        contextKey = "apiKeyScopes"
+	APIKeyIDKey            contextKey = "apiKeyID"
+	APIKeyPrefixKey        contextKey = "apiKeyPrefix"
+	apiKeyAuthenticatorKey contextKey = "apiKeyAuthenticator"
+	userVerifierKey        contextKey = "userVerifier"
+)
+
+// 認証方式(AuthMethodKeyに格納する値)
+const (
+	AuthMethodJWT    = "jwt"
+	AuthMethodAPIKey = "api_key"
+)
+
+// JWT認証用の秘密鍵(起動時に設定の auth.jwt_secret を SetJWTKey で設定する)
+var jwtKey []byte
+
+// JWT認証用の秘密鍵を設定する(リクエストを受け付ける前に呼び出す)
+func SetJWTKey(key []byte) {
+	jwtKey = key
+}
+
+// JWT認証用の秘密鍵を返す(トークンの発行に使う)
+func JWTKey() []byte {
+	return jwtKey
+}
+
+// APIキーを検証するインターフェース(service.APIKeyService が実装する)
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, rawKey string) (*models.APIKey, error)
+}
+
+// AuthMiddlewareでAPIキーを検証できるように、リクエストのContextに検証処理を設定するミドルウェア
+func WithAPIKeyAuthenticator(authenticator APIKeyAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), apiKeyAuthenticatorKey, authenticator)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-// JWT認証用の秘密鍵（最終的には環境変数から）
-var JwtKey = []byte("your_secret_key")
+// JWTのユーザーが有効か確認するインターフェース(service.UserService が実装する)
+type UserVerifier interface {
+	VerifyActiveUser(ctx context.Context, userID int) error
+}
 
-// JWTの検証を実施するミドルウェア
+// 削除済みのユーザーのJWTを拒否できるように、リクエストのContextに確認処理を設定するミドルウェア
+func WithUserVerifier(verifier UserVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), userVerifierKey, verifier)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// JWTまたはAPIキーの検証を実施するミドルウェア
 func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		// リクエストヘッダーの確認
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Missing token", http.StatusUnauthorized)
+			apperror.WriteProblem(w, r, errMissingToken())
 			return
 		}
 
-		// Bearer形式のtokenを分解する
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
+		ctx, err := authenticate(r, authHeader)
+		if err != nil {
+			apperror.WriteProblem(w, r, err)
 			return
 		}
-		tokenStr := parts[1]
 
-		// JWTの解析
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (any, error) {
-			return JwtKey, nil
-		})
-		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		// 引数で指定されたハンドラー関数を実行
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// 認証情報があれば検証してユーザーIDを埋め込むミドルウェア(未ログインでも次のハンドラーを実行する)
+func OptionalAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			next.ServeHTTP(w, r)
 			return
 		}
 
-		// JWTの中身（Claims）を取り出してmap形式に変換
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+		// ヘッダーが付いている場合は不正な認証情報を黙って無視しない
+		ctx, err := authenticate(r, authHeader)
+		if err != nil {
+			apperror.WriteProblem(w, r, err)
 			return
 		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// WebSocketのサブプロトコル
+// ブラウザのWebSocketはヘッダーを付けられないため、new WebSocket(url, ["blogapi.v1", "bearer." + jwt]) のようにJWTをサブプロトコルで渡す
+// (URLに含めるとプロキシやアクセスログに残るため、クエリでは受け付けない)。サーバーは WebSocketProtocol だけを選択して返す
+const (
+	WebSocketProtocol            = "blogapi.v1"
+	WebSocketTokenProtocolPrefix = "bearer."
+)
 
-		// user id を保管する
-		userIDFloat, ok := claims["user_id"].(float64)
-		if !ok {
-			http.Error(w, "Invalid user ID in token", http.StatusUnauthorized)
+// WebSocket接続用のJWT認証ミドルウェア
+// Authorizationヘッダーが無い場合は Sec-WebSocket-Protocol の "bearer.<JWT>" をJWTとして検証する
+// APIキーは受け付けない
+func WebSocketAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var tokenStr string
+		if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+			// AuthMiddleware と同じく認証方式を確認し、Bearer 以外は受け付けない
+			scheme, credentials, err := splitAuthorization(authHeader)
+			if err == nil && scheme != "Bearer" {
+				err = errInvalidAuthorization()
+			}
+			if err != nil {
+				apperror.WriteProblem(w, r, err)
+				return
+			}
+			tokenStr = credentials
+		} else {
+			tokenStr = tokenFromWebSocketProtocol(r)
+		}
+		if tokenStr == "" {
+			apperror.WriteProblem(w, r, errMissingToken())
 			return
 		}
-		userID := int(userIDFloat)
 
-		// ユーザーIDをリクエストのContextに埋め込んで次のハンドラー関数に渡す
+		userID, err := userIDFromJWT(tokenStr)
+		if err == nil {
+			err = verifyUser(r.Context(), userID)
+		}
+		if err != nil {
+			apperror.WriteProblem(w, r, err)
+			return
+		}
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
-		// 引数で指定されたハンドラー関数を実行
+		ctx = context.WithValue(ctx, AuthMethodKey, AuthMethodJWT)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
+
+// Sec-WebSocket-Protocol に含まれるJWTを取り出す
+func tokenFromWebSocketProtocol(r *http.Request) string {
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			if token, ok := strings.CutPrefix(strings.TrimSpace(protocol), WebSocketTokenProtocolPrefix); ok {
+				return token
+			}
+		}
+	}
+	return ""
+}
+
+// 認証情報が無い場合のエラー
+func errMissingToken() *apperror.AppError {
+	return apperror.NewAppError(apperror.TypeUnauthorized, "Missing token", nil).WithSubCode(apperror.CodeMissingToken)
+}
+
+// Authorizationヘッダーの形式が不正な場合のエラー
+func errInvalidAuthorization() *apperror.AppError {
+	return apperror.NewAppError(apperror.TypeUnauthorized, "Invalid Authorization header format", nil).WithSubCode(apperror.CodeInvalidToken)
+}
+
+// Authorizationヘッダーを認証方式と資格情報に分解する
+func splitAuthorization(authHeader string) (string, string, *apperror.AppError) {
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 {
+		return "", "", errInvalidAuthorization()
+	}
+	return parts[0], parts[1], nil
+}
+
+// Authorizationヘッダーを検証して、ユーザーIDと認証方式を埋め込んだContextを返す
+func authenticate(r *http.Request, authHeader string) (context.Context, *apperror.AppError) {
+	// 認証方式と資格情報に分解する
+	scheme, credentials, appErr := splitAuthorization(authHeader)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	ctx := r.Context()
+	switch scheme {
+	case "Bearer":
+		userID, err := userIDFromJWT(credentials)
+		if err != nil {
+			return nil, err
+		}
+		// 削除済みのユーザーのJWTは有効期限内でも受け付けない
+		if err := verifyUser(ctx, userID); err != nil {
+			return nil, err
+		}
+		// ユーザーIDをリクエストのContextに埋め込んで次のハンドラー関数に渡す
+		ctx = context.WithValue(ctx, UserIDKey, userID)
+		return context.WithValue(ctx, AuthMethodKey, AuthMethodJWT), nil
+	case "ApiKey":
+		key, err := authenticateAPIKey(ctx, credentials)
+		if err != nil {
+			return nil, err
+		}
+		// APIキーのスコープでメソッドを制限する
+		if !scopeAllowsMethod(key.Scopes, r.Method) {
+			return nil, apperror.NewAppError(apperror.TypeForbidden, fmt.Sprintf("Insufficient api key scope : APIKeyID=%d", key.ID), nil).WithSubCode(apperror.CodeInsufficientScope)
+		}
+		ctx = context.WithValue(ctx, UserIDKey, key.UserID)
+		ctx = context.WithValue(ctx, APIKeyScopesKey, key.Scopes)
+		// 監視イベントにどのキーで操作したかを記録するため、キーのIDとプレフィックスを埋め込む
+		ctx = context.WithValue(ctx, APIKeyIDKey, key.ID)
+		ctx = context.WithValue(ctx, APIKeyPrefixKey, key.Prefix)
+		return context.WithValue(ctx, AuthMethodKey, AuthMethodAPIKey), nil
+	default:
+		return nil, errInvalidAuthorization()
+	}
+}
+
+// JWTを検証してユーザーIDを取り出す
+func userIDFromJWT(tokenStr string) (int, *apperror.AppError) {
+	// JWTの解析
+	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (any, error) {
+		// 秘密鍵が設定されていない場合はどのトークンも受け付けない
+		if len(jwtKey) == 0 {
+			return nil, errors.New("jwt key is not configured")
+		}
+		return jwtKey, nil
+	})
+	if err != nil || !token.Valid {
+		return 0, apperror.NewAppError(apperror.TypeUnauthorized, "Invalid token", err).WithSubCode(apperror.CodeInvalidToken)
+	}
+
+	// JWTの中身（Claims）を取り出してmap形式に変換
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, apperror.NewAppError(apperror.TypeUnauthorized, "Invalid token claims", nil).WithSubCode(apperror.CodeInvalidToken)
+	}
+
+	// user id を保管する
+	userIDFloat, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, apperror.NewAppError(apperror.TypeUnauthorized, "Invalid user ID in token", nil).WithSubCode(apperror.CodeInvalidToken)
+	}
+	return int(userIDFloat), nil
+}
+
+// JWTのユーザーが存在し、削除されていないことを確認する(確認できない場合は受け付けない)
+func verifyUser(ctx context.Context, userID int) *apperror.AppError {
+	verifier, ok := ctx.Value(userVerifierKey).(UserVerifier)
+	if !ok || verifier == nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, "User verification is not available", nil)
+	}
+	if err := verifier.VerifyActiveUser(ctx, userID); err != nil {
+		var appErr *apperror.AppError
+		if errors.As(err, &appErr) && appErr.Type == apperror.TypeUnauthorized {
+			return appErr
+		}
+		log.Printf("failed to verify user: %v", err)
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to verify user : UserID=%d", userID), err)
+	}
+	return nil
+}
+
+// APIキーを検証して利用されたキーを取り出す
+func authenticateAPIKey(ctx context.Context, rawKey string) (*models.APIKey, *apperror.AppError) {
+	authenticator, ok := ctx.Value(apiKeyAuthenticatorKey).(APIKeyAuthenticator)
+	if !ok || authenticator == nil {
+		return nil, apperror.NewAppError(apperror.TypeUnauthorized, "API key authentication is not available", nil).WithSubCode(apperror.CodeInvalidAPIKey)
+	}
+
+	key, err := authenticator.AuthenticateAPIKey(ctx, rawKey)
+	if err != nil {
+		// 認証失敗とDB障害などを区別する
+		var appErr *apperror.AppError
+		if errors.As(err, &appErr) && appErr.Type == apperror.TypeUnauthorized {
+			return nil, apperror.NewAppError(apperror.TypeUnauthorized, "Invalid api key", err).WithSubCode(apperror.CodeInvalidAPIKey)
+		}
+		log.Printf("failed to authenticate api key: %v", err)
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to authenticate api key", err)
+	}
+	return key, nil
+}
+
+// APIキーで認証された場合にキーのIDとプレフィックスを返す
+func APIKeyFromContext(ctx context.Context) (int, string, bool) {
+	id, ok := ctx.Value(APIKeyIDKey).(int)
+	if !ok {
+		return 0, "", false
+	}
+	prefix, _ := ctx.Value(APIKeyPrefixKey).(string)
+	return id, prefix, true
+}
+
+// APIキーのスコープでリクエストメソッドが許可されているか判定する
+func scopeAllowsMethod(scopes []string, method string) bool {
+	required := models.APIKeyScopeWrite
+	if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+		required = models.APIKeyScopeRead
+	}
+	for _, scope := range scopes {
+		// writeスコープは参照も許可する
+		if scope == required || scope == models.APIKeyScopeWrite {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/middleware"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// WebSocket接続で Bearer 以外の認証方式を「形式が不正」として拒否することを確認する
+func TestWebSocketAuthMiddlewareRejectsOtherSchemes(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("認証に失敗したのに次のハンドラーが呼ばれた")
+	})
+	h := middleware.WebSocketAuthMiddleware(next)
+
+	for _, header := range []string{"ApiKey blog_abc", "Basic dXNlcjpwYXNz", "bearer token", "Bearer"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/ws", nil)
+		req.Header.Set("Authorization", header)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		var problem models.ProblemDetails
+		if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("[%s] レスポンスの解析失敗: %v", header, err)
+		}
+		if rec.Code != http.StatusUnauthorized || problem.SubCode != apperror.CodeInvalidToken || problem.Detail != "Invalid Authorization header format" {
+			t.Errorf("[%s] status=%d problem=%+v", header, rec.Code, problem)
+		}
+	}
+}
+
+// URLのクエリに含めたJWTは受け付けないことを確認する
+func TestWebSocketAuthMiddlewareIgnoresQueryToken(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("認証に失敗したのに次のハンドラーが呼ばれた")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/api/ws?access_token=token", nil)
+	req.Header.Set("Sec-WebSocket-Protocol", middleware.WebSocketProtocol)
+	rec := httptest.NewRecorder()
+	middleware.WebSocketAuthMiddleware(next).ServeHTTP(rec, req)
+
+	var problem models.ProblemDetails
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatal("レスポンスの解析失敗:", err)
+	}
+	if rec.Code != http.StatusUnauthorized || problem.SubCode != apperror.CodeMissingToken {
+		t.Errorf("status=%d problem=%+v", rec.Code, problem)
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/errreport"
+	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
+)
+
+// panic から復帰するミドルウェア
+// 500の problem+json を返し、スタックトレースをリクエストIDとともにログに出して、エラー監視サービスへの報告と監視イベントの追加を行う
+// (処理を中断するための http.ErrAbortHandler はそのまま伝える)
+func RecoverMiddleware(reporter errreport.Reporter, auditPool *workerpool.AuditWorkerPool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &recoverWriter{ResponseWriter: w}
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(recovered)
+				}
+
+				event := errreport.NewEvent("panic", fmt.Sprint(recovered))
+				event.Frames = errreport.CaptureFrames(0)
+				event.RequestID = RequestIDFromContext(r.Context())
+				event.Method = r.Method
+				event.Path = r.URL.Path
+				log.Printf("panic recovered: request_id=%s event_id=%s %s %s: %v\n%s", event.RequestID, event.ID, r.Method, r.URL.Path, recovered, debug.Stack())
+
+				reporter.Report(event)
+				if auditPool != nil {
+					if err := auditPool.Enqueue(context.Background(), workerpool.AuditEvent{Action: "panic_recovered"}); err != nil {
+						log.Printf("Failed to enqueue audit event: %v", err)
+					}
+				}
+
+				// レスポンスを書き始めていた場合はステータスを変更できないため、接続を切断して途中までのレスポンスを完了させない
+				if rw.wroteHeader {
+					panic(http.ErrAbortHandler)
+				}
+				apperror.WriteProblem(rw, r, apperror.NewAppError(apperror.TypeInternalServer, "Panic recovered : EventID="+event.ID, nil))
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// レスポンスを書き始めたかを記録する ResponseWriter
+// SSEの Flush などは http.ResponseController が Unwrap で元の ResponseWriter を使い、WebSocketのために Hijack を実装する
+type recoverWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *recoverWriter) WriteHeader(status int) {
+	if status >= http.StatusOK {
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recoverWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recoverWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *recoverWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.wroteHeader = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
@@ -0,0 +1,111 @@
+package middleware_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/errreport"
+	"github.com/yusuke-hoguro/BlogApi/internal/middleware"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// 報告されたイベントを記録する Reporter
+type recordingReporter struct {
+	events []errreport.Event
+}
+
+func (r *recordingReporter) Report(event errreport.Event) {
+	r.events = append(r.events, event)
+}
+
+func (r *recordingReporter) Flush(context.Context) error {
+	return nil
+}
+
+// panic した場合に500の problem+json を返し、リクエストIDを付けて報告することを確認する
+func TestRecoverMiddleware(t *testing.T) {
+	reporter := &recordingReporter{}
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	h := middleware.RequestIDMiddleware(middleware.RecoverMiddleware(reporter, nil)(panicking))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/posts?token=secret", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-123")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("ステータスが想定と異なる: get %d, want 500", rec.Code)
+	}
+	var body models.ProblemDetails
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Code != "internal_server_error" || body.Detail != "" || body.Instance != "/api/posts" {
+		t.Errorf("レスポンスが想定と異なる: %s (%v)", rec.Body.String(), err)
+	}
+	if got := rec.Header().Get(middleware.RequestIDHeader); got != "req-123" {
+		t.Errorf("リクエストIDが返されない: %q", got)
+	}
+
+	if len(reporter.events) != 1 {
+		t.Fatalf("報告の件数が想定と異なる: %d", len(reporter.events))
+	}
+	event := reporter.events[0]
+	if event.Type != "panic" || event.Message != "boom" || event.RequestID != "req-123" || event.Path != "/api/posts" || event.ID == "" {
+		t.Errorf("報告の内容が想定と異なる: %+v", event)
+	}
+	// スタックトレースは panic を起こした関数から始まる
+	if len(event.Frames) == 0 || !strings.Contains(event.Frames[0].Function, "TestRecoverMiddleware") {
+		t.Errorf("スタックトレースが想定と異なる: %+v", event.Frames)
+	}
+}
+
+// レスポンスを書き始めた後の panic と http.ErrAbortHandler は接続の切断として伝えることを確認する
+func TestRecoverMiddlewareAbort(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		report  int
+	}{
+		{"ErrAbortHandler", func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) }, 0},
+		{"書き込み後", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			panic("boom")
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter := &recordingReporter{}
+			h := middleware.RecoverMiddleware(reporter, nil)(tt.handler)
+			defer func() {
+				if recovered := recover(); recovered != http.ErrAbortHandler {
+					t.Errorf("ErrAbortHandler で中断されない: %v", recovered)
+				}
+				if len(reporter.events) != tt.report {
+					t.Errorf("報告の件数が想定と異なる: get %d, want %d", len(reporter.events), tt.report)
+				}
+			}()
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	}
+}
+
+// 不正なリクエストIDは受け取らずに新しく発行することを確認する
+func TestRequestIDMiddleware(t *testing.T) {
+	var got string
+	h := middleware.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = middleware.RequestIDFromContext(r.Context())
+	}))
+	for _, header := range []string{"", "bad id\n", strings.Repeat("a", 129)} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(middleware.RequestIDHeader, header)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if got == header || len(got) != 32 || rec.Header().Get(middleware.RequestIDHeader) != got {
+			t.Errorf("%q: リクエストIDが想定と異なる: %q", header, got)
+		}
+	}
+}
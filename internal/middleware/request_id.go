@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// リクエストIDを受け取り・返すヘッダー
+const RequestIDHeader = "X-Request-ID"
+
+// リクエストIDをContextに格納するキー
+const RequestIDKey contextKey = "requestID"
+
+// クライアント・ロードバランサーから受け取るリクエストIDの最大の長さ
+const maxRequestIDLength = 128
+
+// リクエストIDを設定するミドルウェア
+// X-Request-ID が正しい形式の場合はその値を使い、無い場合は新しく発行してレスポンスのヘッダーとContextに設定する
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), RequestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ContextのリクエストIDを返す(設定されていない場合は空文字)
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey).(string)
+	return id
+}
+
+// 新しいリクエストIDを発行する(32文字の16進数)
+func newRequestID() string {
+	b := make([]byte, 16)
+	// crypto/rand.Read はエラーを返さない
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ログに出しても安全な文字だけで構成されたリクエストIDか判定する
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -4,14 +4,37 @@ import (
 	"context"
 	"net/http"
 	"time"
+
+	"github.com/gorilla/mux"
 )
 
+// 長時間接続のハンドラー(Streaming でルートに登録したハンドラーをタイムアウトの対象外にする)
+type streamingHandler struct {
+	http.Handler
+}
+
+// Server-Sent Events・WebSocket などの長時間接続のハンドラーであることをルートに記録する
+// ヘッダーはクライアントが自由に付けられるため、対象外にするかはルートで判定する
+func Streaming(h http.Handler) http.Handler {
+	return streamingHandler{Handler: h}
+}
+
 // タイムアウトミドルウェア
-func TimeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+// Streaming で登録したルートはタイムアウトの対象外とする
+func TimeoutMiddleware(router *mux.Router, timeout time.Duration) func(http.Handler) http.Handler {
+	return DynamicTimeoutMiddleware(router, func() time.Duration { return timeout })
+}
+
+// リクエストごとにタイムアウト時間を取得するタイムアウトミドルウェア(設定の再読み込みで変更する場合に使う)
+func DynamicTimeoutMiddleware(router *mux.Router, timeout func() time.Duration) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isStreamingRoute(router, r) {
+				next.ServeHTTP(w, r)
+				return
+			}
 			// タイムアウト付きのコンテキストを作成
-			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			ctx, cancel := context.WithTimeout(r.Context(), timeout())
 			defer cancel()
 			// リクエストに新しいコンテキストを設定
 			r = r.WithContext(ctx)
@@ -20,3 +43,13 @@ func TimeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// リクエストが Streaming で登録したルートに一致するか判定する
+func isStreamingRoute(router *mux.Router, r *http.Request) bool {
+	var match mux.RouteMatch
+	if !router.Match(r, &match) || match.Route == nil {
+		return false
+	}
+	_, ok := match.Route.GetHandler().(streamingHandler)
+	return ok
+}
@@ -0,0 +1,49 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yusuke-hoguro/BlogApi/internal/middleware"
+)
+
+// Streaming で登録したルートだけがタイムアウトの対象外になり、ヘッダーでは対象外にできないことを確認する
+func TestTimeoutMiddlewareStreamingRoutes(t *testing.T) {
+	var hasDeadline bool
+	record := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline = r.Context().Deadline()
+	})
+	r := mux.NewRouter()
+	r.Handle("/api/posts", record).Methods(http.MethodGet)
+	r.Handle("/api/posts/{id}/events", middleware.Streaming(record)).Methods(http.MethodGet)
+	r.Handle("/api/ws", middleware.Streaming(record)).Methods(http.MethodGet)
+	h := middleware.TimeoutMiddleware(r, time.Second)(r)
+
+	tests := []struct {
+		name         string
+		path         string
+		header       string
+		value        string
+		wantDeadline bool
+	}{
+		{"通常のGET", "/api/posts", "", "", true},
+		{"通常のGETにSSEのAcceptを付ける", "/api/posts", "Accept", "text/event-stream", true},
+		{"通常のGETにWebSocketのUpgradeを付ける", "/api/posts", "Upgrade", "websocket", true},
+		{"SSEのルート", "/api/posts/1/events", "Accept", "text/event-stream", false},
+		{"WebSocketのルート", "/api/ws", "Upgrade", "websocket", false},
+	}
+	for _, tt := range tests {
+		hasDeadline = false
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if hasDeadline != tt.wantDeadline {
+			t.Errorf("[%s] 期限の有無 %v, 期待値 %v", tt.name, hasDeadline, tt.wantDeadline)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// アカウント削除の方式
+const (
+	AccountDeletionModeAnonymize = "anonymize" // 投稿・コメント・いいねを残してユーザー情報を匿名化する
+	AccountDeletionModePurge     = "purge"     // 投稿・コメント・いいねを含めてすべて削除する
+)
+
+// データエクスポートの状態
+const (
+	DataExportStatusPending   = "pending"
+	DataExportStatusRunning   = "running"
+	DataExportStatusCompleted = "completed"
+	DataExportStatusFailed    = "failed"
+)
+
+// AccountDeletionRequest はアカウント削除リクエストを表します。
+// @Description アカウント削除リクエスト用の構造体
+type AccountDeletionRequest struct {
+	Mode string `json:"mode" example:"anonymize"`
+}
+
+// AccountDeletion は猶予期間付きのアカウント削除予約を表します。
+// @Description アカウント削除予約の構造体
+type AccountDeletion struct {
+	UserID      int       `json:"user_id"`
+	Mode        string    `json:"mode"`
+	RequestedAt time.Time `json:"requested_at"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+}
+
+// DataExport は非同期で生成するデータエクスポートを表します。
+// @Description データエクスポートの状態を表す構造体
+type DataExport struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// AuditLog は保存された監視イベントを表します。
+// @Description 監視イベントの構造体
+type AuditLog struct {
+	ID           int64     `json:"id"`
+	Action       string    `json:"action"`
+	UserID       int       `json:"user_id"`
+	PostID       int       `json:"post_id,omitempty"`
+	APIKeyID     int       `json:"api_key_id,omitempty"`
+	APIKeyPrefix string    `json:"api_key_prefix,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// APIキーに付与できるスコープ
+const (
+	APIKeyScopeRead  = "read"  // GET/HEADのみ許可
+	APIKeyScopeWrite = "write" // 作成・更新・削除を許可
+)
+
+// APIKey は個人用APIキーを表します(キー本体は含みません)。
+// @Description 個人用APIキーの構造体
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// APIKeyRequest はAPIキー作成時のリクエストを表します。
+// @Description APIキー作成リクエスト構造体
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// APIKeyCreatedResponse はAPIキー作成時のレスポンスを表します。キー本体はこのレスポンスでのみ返します。
+// @Description APIキー作成レスポンス構造体
+type APIKeyCreatedResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -11,3 +11,10 @@ type Comment struct {
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// CommentDeletedEvent は削除されたコメントのライブイベントを表します。
+// @Description コメント削除イベントの構造体
+type CommentDeletedEvent struct {
+	ID     int `json:"id"`
+	PostID int `json:"post_id"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// DBStatsResponse はDBのコネクションプールの状態を表します。
+// @Description コネクションプールの状態の構造体(database/sql の DBStats)
+type DBStatsResponse struct {
+	MaxOpenConnections int   `json:"max_open_connections"` // 設定された最大接続数(0は無制限)
+	OpenConnections    int   `json:"open_connections"`     // 使用中とアイドルの接続数
+	InUse              int   `json:"in_use"`               // 使用中の接続数
+	Idle               int   `json:"idle"`                 // アイドルの接続数
+	WaitCount          int64 `json:"wait_count"`           // 接続が空くのを待った回数
+	WaitDurationMS     int64 `json:"wait_duration_ms"`     // 接続が空くのを待った合計時間(ミリ秒)
+	MaxIdleClosed      int64 `json:"max_idle_closed"`      // max_idle_conns を超えたため閉じた接続数
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"` // conn_max_idle_time を超えたため閉じた接続数
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`  // conn_max_lifetime を超えたため閉じた接続数
+}
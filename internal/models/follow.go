@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// PageCursor は作成日時とIDによるカーソルページネーションの位置を表します。
+type PageCursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// FollowUser はフォロー一覧に表示するユーザーを表します。
+// @Description フォロー一覧のユーザー構造体
+type FollowUser struct {
+	ID         int       `json:"id"`
+	Username   string    `json:"username"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// FollowListResponse はフォロワー・フォロー中一覧のレスポンスを表します。
+// @Description フォロー一覧のレスポンス構造体
+type FollowListResponse struct {
+	Users      []FollowUser `json:"users"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// FeedResponse はホームフィードのレスポンスを表します。
+// @Description ホームフィードのレスポンス構造体
+type FeedResponse struct {
+	Posts      []Post `json:"posts"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
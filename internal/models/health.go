@@ -0,0 +1,17 @@
+package models
+
+// ReadinessResponse はリクエストを受け付けられる状態かの確認結果を表します。
+// @Description readinessの確認結果の構造体(verbose を指定した場合に返す)
+type ReadinessResponse struct {
+	Status string              `json:"status"`           // ok / unavailable / draining
+	Checks []HealthCheckResult `json:"checks,omitempty"` // 依存先ごとの確認結果(シャットダウン中は確認しない)
+}
+
+// HealthCheckResult は依存先1つの確認結果を表します。
+// @Description 依存先の確認結果の構造体
+type HealthCheckResult struct {
+	Name       string `json:"name"`            // 依存先の名前(database / migrations / audit_pool)
+	Status     string `json:"status"`          // ok / fail
+	Error      string `json:"error,omitempty"` // 失敗した理由
+	DurationMS int64  `json:"duration_ms"`     // 確認にかかった時間(ミリ秒)
+}
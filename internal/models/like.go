@@ -15,3 +15,10 @@ type LikesResponse struct {
 	LikeCount int   `json:"like_count"`
 	UserIDs   []int `json:"user_ids"`
 }
+
+// LikeCountEvent はいいね数が変わったときのライブイベントを表します。
+// @Description いいね数イベントの構造体
+type LikeCountEvent struct {
+	PostID    int `json:"post_id"`
+	LikeCount int `json:"like_count"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// 通知の種類
+const (
+	NotificationTypeLike    = "like"
+	NotificationTypeComment = "comment"
+	NotificationTypeFollow  = "follow"
+)
+
+// Notification はユーザーへの通知を表します。
+// 同じ投稿へのいいね・コメントなどは未読の間1件にまとめられ、ActorCount に操作したユーザー数が入ります。
+// @Description 通知の構造体
+type Notification struct {
+	ID              int        `json:"id"`
+	Type            string     `json:"type"`
+	PostID          int        `json:"post_id,omitempty"`
+	ActorCount      int        `json:"actor_count"`
+	LatestActorID   int        `json:"latest_actor_id"`
+	LatestActorName string     `json:"latest_actor_name"`
+	Message         string     `json:"message"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	ReadAt          *time.Time `json:"read_at,omitempty"`
+}
+
+// NotificationListResponse は通知一覧のレスポンスを表します。
+// @Description 通知一覧のレスポンス構造体
+type NotificationListResponse struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int            `json:"unread_count"`
+	NextCursor    string         `json:"next_cursor,omitempty"`
+}
+
+// UnreadCountResponse は未読の通知件数のレスポンスを表します。
+// @Description 未読件数のレスポンス構造体
+type UnreadCountResponse struct {
+	UnreadCount int `json:"unread_count"`
+}
+
+// NotificationPreferences は通知の種類ごとの受け取り設定を表します。
+// @Description 通知設定の構造体
+type NotificationPreferences struct {
+	Likes    bool `json:"likes"`
+	Comments bool `json:"comments"`
+	Follows  bool `json:"follows"`
+}
+
+// NotificationPreferencesRequest は通知設定の更新リクエストを表します。
+// 指定しなかった項目は現在の設定のまま変更しません。
+// @Description 通知設定の更新リクエスト構造体
+type NotificationPreferencesRequest struct {
+	Likes    *bool `json:"likes"`
+	Comments *bool `json:"comments"`
+	Follows  *bool `json:"follows"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// OAuthState は外部IDプロバイダーの認可フロー中に保持する情報を表します。
+type OAuthState struct {
+	State        string
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	LinkUserID   int
+	ExpiresAt    time.Time
+}
+
+// UserIdentity は外部IDプロバイダーのアカウントとユーザーの紐付けを表します。
+type UserIdentity struct {
+	ID       int    `json:"id"`
+	UserID   int    `json:"user_id"`
+	Provider string `json:"provider"`
+	Subject  string `json:"subject"`
+	Email    string `json:"email"`
+}
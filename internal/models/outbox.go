@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// アウトボックスに書き込むドメインイベントの種類
+const (
+	DomainEventPostCreated    = "post.created"
+	DomainEventPostUpdated    = "post.updated"
+	DomainEventPostDeleted    = "post.deleted"
+	DomainEventCommentCreated = "comment.created"
+	DomainEventPostLiked      = "post.liked"
+	DomainEventUserFollowed   = "user.followed"
+)
+
+// OutboxEvent はアウトボックスから取り出したドメインイベントを表します。
+// Payload は投稿・コメントの作成・更新時はその時点の内容、それ以外は下記のイベントごとの構造体です。
+type OutboxEvent struct {
+	ID          int64
+	Type        string
+	AggregateID int
+	Payload     json.RawMessage
+	Attempts    int
+	CreatedAt   time.Time
+}
+
+// PostDeletedEvent は投稿の削除イベントの内容を表します。
+type PostDeletedEvent struct {
+	ID     int `json:"id"`
+	UserID int `json:"user_id"`
+}
+
+// PostLikedEvent はいいねの追加イベントの内容を表します。
+type PostLikedEvent struct {
+	PostID int `json:"post_id"`
+	UserID int `json:"user_id"`
+}
+
+// UserFollowedEvent はフォローイベントの内容を表します。
+type UserFollowedEvent struct {
+	FollowerID int `json:"follower_id"`
+	FolloweeID int `json:"followee_id"`
+}
@@ -0,0 +1,23 @@
+package models
+
+// ProblemDetails はRFC 7807(application/problem+json)形式のエラーレスポンスを表します。
+// @Description エラーレスポンスの構造体(RFC 7807)
+type ProblemDetails struct {
+	Type     string       `json:"type"`               // エラーの種類を表すURI(urn:blogapi:problem:<code>)
+	Title    string       `json:"title"`              // ステータスコードの説明
+	Status   int          `json:"status"`             // HTTPステータスコード
+	Detail   string       `json:"detail,omitempty"`   // クライアントに表示できるエラーの説明(内部エラーの場合は返さない)
+	Instance string       `json:"instance,omitempty"` // エラーが発生したリクエストのパス
+	Code     string       `json:"code"`               // エラーの種類ごとのコード(not_found など)
+	SubCode  string       `json:"sub_code,omitempty"` // エラーを細かく分けたコード(post_not_found など)
+	Errors   []FieldError `json:"errors,omitempty"`   // 入力項目ごとの検証エラー
+	Message  string       `json:"message"`            // 以前の形式との互換のためのメッセージ(detail が無い場合は title)
+}
+
+// FieldError は入力項目ごとの検証エラーを表します。
+// @Description 入力項目の検証エラーの構造体
+type FieldError struct {
+	Field   string `json:"field"`   // 入力項目の名前
+	Code    string `json:"code"`    // 検証エラーの種類(required / too_long / invalid など)
+	Message string `json:"message"` // エラーの説明
+}
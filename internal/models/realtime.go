@@ -0,0 +1,14 @@
+package models
+
+// PresenceEvent はフォロー中のユーザーの接続状態の変化を表します。
+// @Description 接続状態の変化イベントの構造体
+type PresenceEvent struct {
+	UserID int  `json:"user_id"`
+	Online bool `json:"online"`
+}
+
+// PresenceSnapshot は接続時点でオンラインのフォロー中ユーザーを表します。
+// @Description 接続時点の接続状態の構造体
+type PresenceSnapshot struct {
+	OnlineUserIDs []int `json:"online_user_ids"`
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhookで送るイベントの種類(アウトボックスのドメインイベントと同じ名前にする)
+const (
+	WebhookEventPostCreated    = DomainEventPostCreated
+	WebhookEventPostUpdated    = DomainEventPostUpdated
+	WebhookEventPostDeleted    = DomainEventPostDeleted
+	WebhookEventCommentCreated = DomainEventCommentCreated
+)
+
+// Webhookの配信状態
+const (
+	WebhookDeliveryStatusPending   = "pending"   // 配信待ち・再送待ち
+	WebhookDeliveryStatusSucceeded = "succeeded" // 配信成功
+	WebhookDeliveryStatusDead      = "dead"      // 再送の上限を超えた
+)
+
+// Webhook はイベントの送信先を表します(署名用のシークレットは含みません)。
+// @Description Webhookの構造体
+type Webhook struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookRequest はWebhook作成時のリクエストを表します。
+// @Description Webhook作成リクエスト構造体
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// WebhookCreatedResponse はWebhook作成時のレスポンスを表します。シークレットはこのレスポンスでのみ返します。
+// @Description Webhook作成レスポンス構造体
+type WebhookCreatedResponse struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookTarget は配信に必要なWebhookの情報を表します。
+type WebhookTarget struct {
+	ID     int
+	URL    string
+	Secret string
+}
+
+// WebhookDelivery はWebhookの配信履歴を表します。
+// @Description Webhookの配信履歴の構造体
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastStatusCode *int            `json:"last_status_code"`
+	LastError      *string         `json:"last_error"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+// WebhookDeliveryListResponse は配信履歴一覧のレスポンスを表します。
+// @Description 配信履歴一覧のレスポンス構造体
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// WebhookPayload は送信先へPOSTするリクエストボディを表します。
+// @Description Webhookで送信するリクエストボディの構造体
+type WebhookPayload struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
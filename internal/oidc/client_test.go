@@ -0,0 +1,214 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/yusuke-hoguro/BlogApi/internal/oidc"
+	"github.com/yusuke-hoguro/BlogApi/internal/oidc/oidctest"
+)
+
+const (
+	testClientID    = "blogapi-test"
+	testRedirectURL = "http://localhost/callback"
+)
+
+// 疑似プロバイダーの認可エンドポイントを呼び出して認可コードを取得する
+func authorize(t *testing.T, client *oidc.Client, state string, nonce string, verifier string) string {
+	t.Helper()
+	authURL, err := client.AuthCodeURL(context.Background(), state, nonce, oidc.CodeChallengeS256(verifier))
+	if err != nil {
+		t.Fatalf("認可URLの生成失敗: %v", err)
+	}
+
+	// リダイレクトを追わずにLocationヘッダーを確認する
+	httpClient := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := httpClient.Get(authURL)
+	if err != nil {
+		t.Fatalf("認可リクエスト失敗: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("期待するステータスコード %d, 実際は %d", http.StatusFound, resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Locationヘッダーの解析失敗: %v", err)
+	}
+	if got := location.Query().Get("state"); got != state {
+		t.Fatalf("stateが一致しない: get %s, want %s", got, state)
+	}
+	return location.Query().Get("code")
+}
+
+// 認可コードの交換からIDトークン検証までの正常系テスト
+func TestClientAuthorizationCodeFlow(t *testing.T) {
+	provider := oidctest.NewProvider(testClientID)
+	defer provider.Close()
+	provider.SetUser(oidctest.User{Subject: "user-123", Email: "alice@example.com", PreferredUsername: "alice"})
+
+	client := oidc.NewClient(oidc.ProviderConfig{
+		Name:        "fake",
+		Issuer:      provider.Issuer(),
+		ClientID:    testClientID,
+		RedirectURL: testRedirectURL,
+	}, nil)
+
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		t.Fatal("code_verifierの生成失敗:", err)
+	}
+	code := authorize(t, client, "state-1", "nonce-1", verifier)
+
+	token, err := client.Exchange(context.Background(), code, verifier)
+	if err != nil {
+		t.Fatalf("認可コードの交換失敗: %v", err)
+	}
+	claims, err := client.VerifyIDToken(context.Background(), token.IDToken, "nonce-1")
+	if err != nil {
+		t.Fatalf("IDトークンの検証失敗: %v", err)
+	}
+	if claims.Subject != "user-123" || claims.Email != "alice@example.com" || claims.PreferredUsername != "alice" {
+		t.Errorf("クレームが一致しない: %+v", claims)
+	}
+}
+
+// code_verifierが一致しない場合は認可コードを交換できないことを確認する
+func TestClientExchangeWrongVerifier(t *testing.T) {
+	provider := oidctest.NewProvider(testClientID)
+	defer provider.Close()
+
+	client := oidc.NewClient(oidc.ProviderConfig{
+		Name:        "fake",
+		Issuer:      provider.Issuer(),
+		ClientID:    testClientID,
+		RedirectURL: testRedirectURL,
+	}, nil)
+
+	code := authorize(t, client, "state-1", "nonce-1", "correct-verifier-correct-verifier-correct")
+	if _, err := client.Exchange(context.Background(), code, "wrong-verifier-wrong-verifier-wrong-verifier"); err == nil {
+		t.Error("code_verifierが不正なのに交換に成功した")
+	}
+}
+
+// IDトークンの不正なクレームを検出できることを確認する
+func TestClientVerifyIDTokenInvalidClaims(t *testing.T) {
+	provider := oidctest.NewProvider(testClientID)
+	defer provider.Close()
+
+	client := oidc.NewClient(oidc.ProviderConfig{
+		Name:        "fake",
+		Issuer:      provider.Issuer(),
+		ClientID:    testClientID,
+		RedirectURL: testRedirectURL,
+	}, nil)
+
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   provider.Issuer(),
+			"sub":   "user-123",
+			"aud":   testClientID,
+			"exp":   now.Add(time.Minute).Unix(),
+			"iat":   now.Unix(),
+			"nonce": "nonce-1",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+	}{
+		{name: "audience mismatch", modify: func(c jwt.MapClaims) { c["aud"] = "other-client" }},
+		{name: "issuer mismatch", modify: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{name: "expired", modify: func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() }},
+		{name: "nonce mismatch", modify: func(c jwt.MapClaims) { c["nonce"] = "other-nonce" }},
+		{name: "missing subject", modify: func(c jwt.MapClaims) { delete(c, "sub") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(claims)
+			idToken, err := provider.SignIDToken(claims)
+			if err != nil {
+				t.Fatal("IDトークンの署名失敗:", err)
+			}
+			_, err = client.VerifyIDToken(context.Background(), idToken, "nonce-1")
+			if !errors.Is(err, oidc.ErrInvalidIDToken) {
+				t.Errorf("ErrInvalidIDTokenを期待したが %v", err)
+			}
+		})
+	}
+}
+
+// Discoveryの取得に失敗した結果はキャッシュせず、取得中の呼び出しは呼び出し元のキャンセルで戻ることを確認する
+func TestClientDiscoverDoesNotBlockOrCacheFailure(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	release := make(chan struct{})
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		call := calls
+		mu.Unlock()
+		switch call {
+		case 1:
+			// 1回目は失敗する
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		case 2:
+			// 2回目は応答を止める
+			<-release
+			fallthrough
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"issuer": %q, "authorization_endpoint": %q, "token_endpoint": %q, "jwks_uri": %q}`,
+				server.URL, server.URL+"/authorize", server.URL+"/token", server.URL+"/jwks")
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := oidc.NewClient(oidc.ProviderConfig{Name: "slow", Issuer: server.URL, ClientID: testClientID, RedirectURL: testRedirectURL}, nil)
+	if _, err := client.Discover(context.Background()); err == nil {
+		t.Fatal("失敗した取得がエラーにならない")
+	}
+
+	// 応答しない間も呼び出し元の期限で戻る
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.Discover(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("期待するエラー %v, 実際は %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("呼び出し元の期限を過ぎても戻らない: %v", elapsed)
+	}
+
+	// 応答が返れば取得中の結果を使ってキャッシュする
+	release <- struct{}{}
+	metadata, err := client.Discover(context.Background())
+	if err != nil || metadata.Issuer != server.URL {
+		t.Fatalf("Discoveryの取得失敗: metadata=%+v, err=%v", metadata, err)
+	}
+	if _, err := client.Discover(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 {
+		t.Errorf("期待する取得回数 2, 実際は %d", calls)
+	}
+}
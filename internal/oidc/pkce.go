@@ -0,0 +1,27 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// 推測不可能なランダム文字列を生成する(state, nonce, code_verifier用)
+func RandomString(byteLength int) (string, error) {
+	b := make([]byte, byteLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// PKCEのcode_verifierを生成する(RFC 7636 の43〜128文字に収まる長さ)
+func NewCodeVerifier() (string, error) {
+	return RandomString(32)
+}
+
+// code_verifierからS256方式のcode_challengeを算出する
+func CodeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
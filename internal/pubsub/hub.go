@@ -0,0 +1,194 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Event はトピックに配信するイベントを表す
+type Event struct {
+	ID          uint64 // Hub全体で単調増加するイベントID(Last-Event-IDでの再開に使う)
+	Topic       string
+	Type        string
+	Data        any
+	PublishedAt time.Time
+}
+
+// Subscription はトピックの購読を表す
+type Subscription struct {
+	// Replay は Last-Event-ID より後に配信済みのイベント(購読開始時に送る)
+	Replay []Event
+	// Gap は Last-Event-ID より後のイベントが履歴から消えていて取りこぼしがあることを表す
+	Gap bool
+	// Events は購読開始後に配信されるイベント(配信が追いつかない場合はクローズされる)
+	Events <-chan Event
+
+	hub *Hub
+	sub *subscriber
+}
+
+type subscriber struct {
+	topic string
+	ch    chan Event
+}
+
+// トピックごとの履歴
+type topicHistory struct {
+	events    []Event
+	droppedID uint64 // 履歴から捨てた最新のイベントID
+}
+
+// プロセス内でイベントを配信するHub
+// トピックごとに直近のイベントを履歴として保持し、再接続時に取りこぼしたイベントを返す
+type Hub struct {
+	mu          sync.Mutex
+	nextID      uint64
+	evictedID   uint64 // Pruneでトピックごと捨てた最新のイベントID
+	historySize int
+	historyTTL  time.Duration
+	bufferSize  int
+	history     map[string]*topicHistory
+	subscribers map[string]map[*subscriber]struct{}
+	closed      bool
+}
+
+// Hubのインスタンスを生成する
+// historySize と historyTTL はトピックごとに保持する履歴の件数と期間、bufferSize は購読者ごとの送信バッファの件数
+func NewHub(historySize int, historyTTL time.Duration, bufferSize int) *Hub {
+	return &Hub{
+		historySize: historySize,
+		historyTTL:  historyTTL,
+		bufferSize:  bufferSize,
+		history:     make(map[string]*topicHistory),
+		subscribers: make(map[string]map[*subscriber]struct{}),
+	}
+}
+
+// トピックにイベントを配信する
+// 送信バッファがいっぱいの購読者は配信を止めてチャネルをクローズする(クライアントは再接続して履歴から再開する)
+func (h *Hub) Publish(topic string, eventType string, data any) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	event := Event{ID: h.nextID, Topic: topic, Type: eventType, Data: data, PublishedAt: time.Now()}
+
+	// 履歴は古いものから捨てて historySize 件までにする
+	th := h.history[topic]
+	if th == nil {
+		th = &topicHistory{}
+		h.history[topic] = th
+	}
+	th.events = append(th.events, event)
+	if over := len(th.events) - h.historySize; over > 0 {
+		th.droppedID = th.events[over-1].ID
+		th.events = append([]Event(nil), th.events[over:]...)
+	}
+
+	for sub := range h.subscribers[topic] {
+		select {
+		case sub.ch <- event:
+		default:
+			h.removeLocked(sub)
+		}
+	}
+	return event
+}
+
+// トピックを購読する
+// lastEventID が0より大きい場合は、それより後の履歴を Replay に入れて返す
+func (h *Hub) Subscribe(topic string, lastEventID uint64) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &subscriber{topic: topic, ch: make(chan Event, h.bufferSize)}
+	if h.subscribers[topic] == nil {
+		h.subscribers[topic] = make(map[*subscriber]struct{})
+	}
+	h.subscribers[topic][sub] = struct{}{}
+
+	subscription := &Subscription{Events: sub.ch, hub: h, sub: sub}
+	// 停止済みの場合はすぐに終了する購読を返す
+	if h.closed {
+		h.removeLocked(sub)
+		return subscription
+	}
+	if lastEventID == 0 {
+		return subscription
+	}
+
+	var droppedID uint64
+	if th := h.history[topic]; th != nil {
+		droppedID = th.droppedID
+		for _, event := range th.events {
+			if event.ID > lastEventID {
+				subscription.Replay = append(subscription.Replay, event)
+			}
+		}
+	}
+	// 再起動などでIDが巻き戻った場合や、続きのイベントが履歴から消えている場合は取りこぼしがある
+	subscription.Gap = lastEventID > h.nextID || lastEventID < max(droppedID, h.evictedID)
+	return subscription
+}
+
+// 保持期間を過ぎた履歴を削除する(workerpool.PeriodicJob として定期的に実行する)
+func (h *Hub) Prune(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	threshold := time.Now().Add(-h.historyTTL)
+	for topic, th := range h.history {
+		expired := 0
+		for expired < len(th.events) && th.events[expired].PublishedAt.Before(threshold) {
+			expired++
+		}
+		if expired == 0 {
+			continue
+		}
+		th.droppedID = th.events[expired-1].ID
+		th.events = append([]Event(nil), th.events[expired:]...)
+		if len(th.events) == 0 {
+			// 履歴が無くなったトピックは削除し、取りこぼしの判定用に捨てたIDだけ残す
+			h.evictedID = max(h.evictedID, th.droppedID)
+			delete(h.history, topic)
+		}
+	}
+	return nil
+}
+
+// すべての購読を終了する(サーバーのシャットダウン時に接続中のストリームを終わらせるために使う)
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, subs := range h.subscribers {
+		for sub := range subs {
+			h.removeLocked(sub)
+		}
+	}
+}
+
+// 購読を終了する(複数回呼んでも問題ない)
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.removeLocked(s.sub)
+}
+
+// 購読者を削除してチャネルをクローズする(ロックを取得した状態で呼ぶ)
+func (h *Hub) removeLocked(sub *subscriber) {
+	subs, ok := h.subscribers[sub.topic]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	close(sub.ch)
+	if len(subs) == 0 {
+		delete(h.subscribers, sub.topic)
+	}
+}
@@ -0,0 +1,104 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/pubsub"
+)
+
+// 購読中のイベント配信と、Last-Event-IDからの再送をテストする
+func TestHubPublishAndReplay(t *testing.T) {
+	hub := pubsub.NewHub(10, time.Minute, 10)
+
+	sub := hub.Subscribe("post:1", 0)
+	defer sub.Close()
+
+	first := hub.Publish("post:1", "comment_created", 1)
+	hub.Publish("post:2", "comment_created", 2)
+	second := hub.Publish("post:1", "like_count", 3)
+
+	for _, want := range []pubsub.Event{first, second} {
+		select {
+		case got := <-sub.Events:
+			if got.ID != want.ID || got.Type != want.Type {
+				t.Errorf("配信されたイベントが一致しない: get %+v, want %+v", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("イベントが配信されない")
+		}
+	}
+
+	// 最初のイベントまで受信済みの場合は2件目だけ再送する(他のトピックのイベントは含まない)
+	resumed := hub.Subscribe("post:1", first.ID)
+	defer resumed.Close()
+	if resumed.Gap || len(resumed.Replay) != 1 || resumed.Replay[0].ID != second.ID {
+		t.Errorf("再送するイベントが想定と異なる: gap=%v replay=%+v", resumed.Gap, resumed.Replay)
+	}
+}
+
+// 履歴から消えたイベントや未来のイベントIDを指定した場合に取りこぼしを検知することをテストする
+func TestHubGap(t *testing.T) {
+	hub := pubsub.NewHub(2, time.Minute, 10)
+
+	first := hub.Publish("post:1", "comment_created", 1)
+	hub.Publish("post:1", "comment_created", 2)
+	hub.Publish("post:1", "comment_created", 3)
+	last := hub.Publish("post:1", "comment_created", 4)
+
+	tests := []struct {
+		name        string
+		lastEventID uint64
+		wantGap     bool
+	}{
+		{name: "履歴から消えたイベント", lastEventID: first.ID, wantGap: true},
+		{name: "最新のイベント", lastEventID: last.ID, wantGap: false},
+		{name: "再起動前のイベントID", lastEventID: last.ID + 100, wantGap: true},
+	}
+	for _, tt := range tests {
+		sub := hub.Subscribe("post:1", tt.lastEventID)
+		if sub.Gap != tt.wantGap {
+			t.Errorf("[%s] 取りこぼしの判定が一致しない: get %v, want %v", tt.name, sub.Gap, tt.wantGap)
+		}
+		sub.Close()
+	}
+}
+
+// 送信バッファがいっぱいの購読者は切断され、Closeで全購読が終了することをテストする
+func TestHubSlowSubscriberAndClose(t *testing.T) {
+	hub := pubsub.NewHub(10, time.Minute, 1)
+
+	slow := hub.Subscribe("post:1", 0)
+	hub.Publish("post:1", "like_count", 1)
+	hub.Publish("post:1", "like_count", 2)
+	<-slow.Events
+	if _, ok := <-slow.Events; ok {
+		t.Error("送信バッファがいっぱいの購読者が切断されていない")
+	}
+	slow.Close()
+
+	sub := hub.Subscribe("post:1", 0)
+	hub.Close()
+	if _, ok := <-sub.Events; ok {
+		t.Error("Close後も購読が終了していない")
+	}
+	sub.Close()
+}
+
+// 保持期間を過ぎた履歴が削除され、再接続時に取りこぼしとして扱われることをテストする
+func TestHubPrune(t *testing.T) {
+	hub := pubsub.NewHub(10, 0, 10)
+
+	event := hub.Publish("post:1", "comment_created", 1)
+	hub.Publish("post:1", "comment_created", 2)
+	if err := hub.Prune(context.Background()); err != nil {
+		t.Fatal("履歴の削除失敗:", err)
+	}
+
+	sub := hub.Subscribe("post:1", event.ID)
+	defer sub.Close()
+	if !sub.Gap || len(sub.Replay) != 0 {
+		t.Errorf("削除した履歴が残っている: gap=%v replay=%+v", sub.Gap, sub.Replay)
+	}
+}
@@ -0,0 +1,143 @@
+package pubsub
+
+import (
+	"sync"
+)
+
+// Message はユーザーの接続へ送るメッセージを表す
+type Message struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// Client はユーザーの接続1つ分を表す
+type Client struct {
+	UserID int
+	// Messages は接続へ送るメッセージ(送信が追いつかない場合やHubの停止時はクローズされる)
+	Messages <-chan Message
+	// SlowConsumer は送信が追いつかずに切断されたことを表す(Messagesがクローズされた後に参照する)
+	SlowConsumer bool
+
+	ch chan Message
+}
+
+// ユーザーごとの接続へメッセージを配信するHub
+// 1人のユーザーが複数の接続(タブ・端末)を持つ場合はすべての接続へ送る
+type UserHub struct {
+	mu         sync.Mutex
+	bufferSize int
+	clients    map[int]map[*Client]struct{}
+	closed     bool
+}
+
+// UserHubのインスタンスを生成する(bufferSize は接続ごとの送信バッファの件数)
+func NewUserHub(bufferSize int) *UserHub {
+	return &UserHub{
+		bufferSize: bufferSize,
+		clients:    make(map[int]map[*Client]struct{}),
+	}
+}
+
+// 接続を登録する(ユーザーの最初の接続の場合は first にtrueを返す)
+func (h *UserHub) Register(userID int) (client *Client, first bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan Message, h.bufferSize)
+	client = &Client{UserID: userID, Messages: ch, ch: ch}
+	// 停止済みの場合はすぐに終了する接続を返す
+	if h.closed {
+		close(ch)
+		return client, false
+	}
+	if h.clients[userID] == nil {
+		h.clients[userID] = make(map[*Client]struct{})
+		first = true
+	}
+	h.clients[userID][client] = struct{}{}
+	return client, first
+}
+
+// 接続の登録を解除する(ユーザーの接続が他に残っている場合は online にtrueを返す)
+// 送信が追いつかずに切断済みの接続を渡した場合も、残りの接続の有無を返す
+func (h *UserHub) Unregister(client *Client) (online bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(client)
+	return len(h.clients[client.UserID]) > 0
+}
+
+// 指定したユーザーのすべての接続へメッセージを送る
+// 送信バッファがいっぱいの接続は切断する(他の接続やユーザーへの配信を待たせない)
+func (h *UserHub) SendToUser(userID int, message Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for client := range h.clients[userID] {
+		select {
+		case client.ch <- message:
+		default:
+			client.SlowConsumer = true
+			h.removeLocked(client)
+		}
+	}
+}
+
+// 指定した接続だけにメッセージを送る(送信バッファがいっぱいの場合は切断する)
+func (h *UserHub) SendToClient(client *Client, message Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[client.UserID][client]; !ok {
+		return
+	}
+	select {
+	case client.ch <- message:
+	default:
+		client.SlowConsumer = true
+		h.removeLocked(client)
+	}
+}
+
+// 指定したユーザーのうち接続中のユーザーIDを返す
+func (h *UserHub) Online(userIDs []int) []int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	online := []int{}
+	for _, userID := range userIDs {
+		if len(h.clients[userID]) > 0 {
+			online = append(online, userID)
+		}
+	}
+	return online
+}
+
+// すべての接続を終了する(サーバーのシャットダウン時に使う)
+func (h *UserHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, clients := range h.clients {
+		for client := range clients {
+			h.removeLocked(client)
+		}
+	}
+}
+
+// 接続を削除してチャネルをクローズする(ロックを取得した状態で呼ぶ)
+func (h *UserHub) removeLocked(client *Client) {
+	clients, ok := h.clients[client.UserID]
+	if !ok {
+		return
+	}
+	if _, ok := clients[client]; !ok {
+		return
+	}
+	delete(clients, client)
+	close(client.ch)
+	if len(clients) == 0 {
+		delete(h.clients, client.UserID)
+	}
+}
@@ -0,0 +1,67 @@
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/pubsub"
+)
+
+// ユーザーのすべての接続へ配信され、最後の接続の解除でオフラインになることをテストする
+func TestUserHubFanOut(t *testing.T) {
+	hub := pubsub.NewUserHub(10)
+
+	first, isFirst := hub.Register(1)
+	second, isSecondFirst := hub.Register(1)
+	if !isFirst || isSecondFirst {
+		t.Errorf("最初の接続の判定が一致しない: first=%v second=%v", isFirst, isSecondFirst)
+	}
+
+	hub.SendToUser(1, pubsub.Message{Type: "notification"})
+	for _, client := range []*pubsub.Client{first, second} {
+		if message := <-client.Messages; message.Type != "notification" {
+			t.Errorf("配信されたメッセージが一致しない: %+v", message)
+		}
+	}
+
+	if online := hub.Unregister(first); !online {
+		t.Error("接続が残っているのにオフラインになった")
+	}
+	if online := hub.Unregister(second); online {
+		t.Error("最後の接続を解除してもオンラインのまま")
+	}
+	if got := hub.Online([]int{1, 2}); len(got) != 0 {
+		t.Errorf("オンラインのユーザーが残っている: %v", got)
+	}
+}
+
+// 送信バッファがいっぱいの接続だけが切断されることをテストする
+func TestUserHubSlowConsumer(t *testing.T) {
+	hub := pubsub.NewUserHub(1)
+
+	slow, _ := hub.Register(1)
+	fast, _ := hub.Register(1)
+	hub.SendToUser(1, pubsub.Message{Type: "notification"})
+	<-fast.Messages
+	hub.SendToUser(1, pubsub.Message{Type: "notification"})
+
+	<-slow.Messages
+	if _, ok := <-slow.Messages; ok || !slow.SlowConsumer {
+		t.Error("送信バッファがいっぱいの接続が切断されていない")
+	}
+	if message := <-fast.Messages; message.Type != "notification" || fast.SlowConsumer {
+		t.Error("追いついている接続に配信されていない")
+	}
+	if online := hub.Unregister(slow); !online {
+		t.Error("切断されていない接続が残っているのにオフラインになった")
+	}
+
+	// 停止後は新しい接続もすぐに終了する
+	hub.Close()
+	if _, ok := <-fast.Messages; ok {
+		t.Error("Close後も接続が終了していない")
+	}
+	closed, _ := hub.Register(2)
+	if _, ok := <-closed.Messages; ok {
+		t.Error("停止後の接続が終了していない")
+	}
+}
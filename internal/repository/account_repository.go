@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// アカウント削除用のリポジトリ
+type AccountRepository struct {
+	db DBExecutor
+}
+
+// アカウント削除用リポジトリのインスタンスを生成
+func NewAccountRepository(db DBExecutor) *AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+// アカウント削除を予約する(予約済みの場合は方式と実行日時を更新する)
+func (r *AccountRepository) ScheduleDeletion(ctx context.Context, userID int, mode string, scheduledAt time.Time) (*models.AccountDeletion, error) {
+	var deletion models.AccountDeletion
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO account_deletions (user_id, mode, scheduled_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET mode = EXCLUDED.mode, requested_at = CURRENT_TIMESTAMP, scheduled_at = EXCLUDED.scheduled_at
+		RETURNING user_id, mode, requested_at, scheduled_at
+	`, userID, mode, scheduledAt).Scan(&deletion.UserID, &deletion.Mode, &deletion.RequestedAt, &deletion.ScheduledAt)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to schedule account deletion : UserID=%d", userID), err)
+	}
+	return &deletion, nil
+}
+
+// 指定したユーザーのアカウント削除予約を取得する
+func (r *AccountRepository) FindDeletion(ctx context.Context, userID int) (*models.AccountDeletion, error) {
+	var deletion models.AccountDeletion
+	err := executor(ctx, r.db).QueryRowContext(ctx,
+		"SELECT user_id, mode, requested_at, scheduled_at FROM account_deletions WHERE user_id = $1", userID,
+	).Scan(&deletion.UserID, &deletion.Mode, &deletion.RequestedAt, &deletion.ScheduledAt)
+	if err == sql.ErrNoRows {
+		return nil, apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Account deletion not scheduled : UserID=%d", userID), err).WithSubCode(apperror.CodeDeletionNotScheduled)
+	} else if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Database error : UserID=%d", userID), err)
+	}
+	return &deletion, nil
+}
+
+// 指定したユーザーのアカウント削除予約を取り消す
+func (r *AccountRepository) CancelDeletion(ctx context.Context, userID int) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, "DELETE FROM account_deletions WHERE user_id = $1", userID)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to cancel account deletion : UserID=%d", userID), err)
+	}
+	return checkRowAffected(result, fmt.Sprintf("Account deletion not scheduled : UserID=%d", userID), apperror.CodeDeletionNotScheduled)
+}
+
+// 猶予期間が過ぎたアカウント削除予約のユーザーID一覧を取得する
+func (r *AccountRepository) ListDueDeletionUserIDs(ctx context.Context, limit int) ([]int, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, `
+		SELECT user_id FROM account_deletions
+		WHERE scheduled_at <= NOW()
+		ORDER BY scheduled_at ASC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to fetch due account deletions", err)
+	}
+	defer rows.Close()
+
+	userIDs := []int{}
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to parse account deletion", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to fetch due account deletions", err)
+	}
+	return userIDs, nil
+}
+
+// 猶予期間が過ぎたアカウント削除予約をロックして削除方式を返す(トランザクション内で呼ぶ)
+// 取り消し済み・他のインスタンスで処理中の場合はfalseを返す
+func (r *AccountRepository) LockDueDeletion(ctx context.Context, userID int) (string, bool, error) {
+	// 削除予約をロックして取り消しと同時に実行されないようにする
+	var mode string
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT mode FROM account_deletions
+		WHERE user_id = $1 AND scheduled_at <= NOW()
+		FOR UPDATE SKIP LOCKED
+	`, userID).Scan(&mode)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Database error : UserID=%d", userID), err)
+	}
+	return mode, true, nil
+}
+
+// ユーザー情報を匿名化する(投稿・コメント・いいねは匿名ユーザーのものとして残す)
+// 複数のテーブルを更新するため TxManager.WithinTx の中で呼ぶ
+func (r *AccountRepository) AnonymizeUser(ctx context.Context, userID int) error {
+	// ユーザー名を推測できない値に置き換え、空のパスワードでログインできないようにする
+	_, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE users
+		SET username = 'deleted-user-' || id || '-' || substr(md5(random()::text), 1, 8), password = '', deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, userID)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to anonymize user : UserID=%d", userID), err)
+	}
+
+	// ログイン手段と個人に紐づくデータを削除する
+	queries := []string{
+		"DELETE FROM user_identities WHERE user_id = $1",
+		"DELETE FROM api_keys WHERE user_id = $1",
+		"DELETE FROM oauth_states WHERE link_user_id = $1",
+		"DELETE FROM data_exports WHERE user_id = $1",
+		"DELETE FROM follows WHERE follower_id = $1 OR followee_id = $1",
+		"DELETE FROM notifications WHERE user_id = $1",
+		"DELETE FROM notification_preferences WHERE user_id = $1",
+		"DELETE FROM webhooks WHERE user_id = $1",
+		"DELETE FROM account_deletions WHERE user_id = $1",
+	}
+	for _, query := range queries {
+		if _, err := executor(ctx, r.db).ExecContext(ctx, query, userID); err != nil {
+			return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to anonymize user : UserID=%d", userID), err)
+		}
+	}
+	return nil
+}
+
+// ユーザーと投稿・コメント・いいね・監視イベントをすべて削除する
+// 複数のテーブルを更新するため TxManager.WithinTx の中で呼ぶ
+func (r *AccountRepository) PurgeUser(ctx context.Context, userID int) error {
+	// postsには外部キーが無いため明示的に削除する(投稿へのコメント・いいね・統計はCASCADEで削除される)
+	// PostRepository.Delete と同じく、削除した投稿ごとに削除イベントをアウトボックスに書き込む
+	if err := r.purgePosts(ctx, userID); err != nil {
+		return err
+	}
+
+	// usersの削除でコメント・いいね・APIキーなどはCASCADEで削除される
+	queries := []string{
+		"DELETE FROM audit_logs WHERE user_id = $1",
+		"DELETE FROM users WHERE id = $1",
+	}
+	for _, query := range queries {
+		if _, err := executor(ctx, r.db).ExecContext(ctx, query, userID); err != nil {
+			return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to purge user : UserID=%d", userID), err)
+		}
+	}
+	return nil
+}
+
+// ユーザーの投稿を削除し、投稿ごとの削除イベントをアウトボックスに書き込む
+func (r *AccountRepository) purgePosts(ctx context.Context, userID int) error {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, "DELETE FROM posts WHERE user_id = $1 RETURNING id", userID)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to purge user : UserID=%d", userID), err)
+	}
+	// 同じトランザクションで次のSQLを実行するため、先にすべて読み取って閉じる
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to purge user : UserID=%d", userID), err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to purge user : UserID=%d", userID), err)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if err := insertOutboxEvent(ctx, r.db, models.DomainEventPostDeleted, id, models.PostDeletedEvent{ID: id, UserID: userID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
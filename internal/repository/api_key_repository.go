@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// APIキー用のリポジトリ
+type APIKeyRepository struct {
+	db DBExecutor
+}
+
+// APIキー用リポジトリのインスタンスを生成
+func NewAPIKeyRepository(db DBExecutor) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// APIキーを作成する(キー本体ではなくハッシュを保存する)
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey, keyHash string) error {
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, key.UserID, key.Name, key.Prefix, keyHash, pq.Array(key.Scopes)).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to insert api key : UserID=%d", key.UserID), err)
+	}
+	return nil
+}
+
+// 指定したユーザーのAPIキー一覧を取得する(失効済みも含む)
+func (r *APIKeyRepository) ListByUserID(ctx context.Context, userID int) ([]models.APIKey, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, `
+		SELECT id, user_id, name, prefix, scopes, created_at, last_used_at, revoked_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch api keys : UserID=%d", userID), err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		if err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, pq.Array(&key.Scopes), &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt); err != nil {
+			return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to parse api key : UserID=%d", userID), err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch api keys : UserID=%d", userID), err)
+	}
+	return keys, nil
+}
+
+// プレフィックスから失効していないAPIキーとハッシュを取得する
+func (r *APIKeyRepository) FindActiveByPrefix(ctx context.Context, prefix string) (*models.APIKey, string, error) {
+	var key models.APIKey
+	var keyHash string
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT id, user_id, name, prefix, key_hash, scopes, created_at, last_used_at
+		FROM api_keys
+		WHERE prefix = $1 AND revoked_at IS NULL
+	`, prefix).Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &keyHash, pq.Array(&key.Scopes), &key.CreatedAt, &key.LastUsedAt)
+	if err == sql.ErrNoRows {
+		return nil, "", apperror.NewAppError(apperror.TypeUnauthorized, "Invalid api key : Prefix="+prefix, err).WithSubCode(apperror.CodeInvalidAPIKey)
+	} else if err != nil {
+		return nil, "", apperror.NewAppError(apperror.TypeInternalServer, "Database error : Prefix="+prefix, err)
+	}
+	return &key, keyHash, nil
+}
+
+// 最終利用日時を更新する(1分以内に更新済みの場合は書き込みを省略する)
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id int) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')
+	`, id)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to update api key last used : APIKeyID=%d", id), err)
+	}
+	return nil
+}
+
+// 指定したユーザーのAPIキーを失効させる
+func (r *APIKeyRepository) Revoke(ctx context.Context, userID int, id int) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, "UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL", id, userID)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to revoke api key : APIKeyID=%d", id), err)
+	}
+	return checkRowAffected(result, fmt.Sprintf("API key not found : APIKeyID=%d", id), apperror.CodeAPIKeyNotFound)
+}
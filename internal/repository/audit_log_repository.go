@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// 監視イベント用のリポジトリ
+type AuditLogRepository struct {
+	db DBExecutor
+}
+
+// 監視イベント用リポジトリのインスタンスを生成
+func NewAuditLogRepository(db DBExecutor) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+// 監視イベントを保存する(IDが0・プレフィックスが空の場合はNULLで保存する)
+func (r *AuditLogRepository) Create(ctx context.Context, entry models.AuditLog) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx,
+		"INSERT INTO audit_logs (action, user_id, post_id, api_key_id, api_key_prefix) VALUES ($1, NULLIF($2, 0), NULLIF($3, 0), NULLIF($4, 0), NULLIF($5, ''))",
+		entry.Action, entry.UserID, entry.PostID, entry.APIKeyID, entry.APIKeyPrefix,
+	)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to insert audit log : Action="+entry.Action, err)
+	}
+	return nil
+}
+
+// 指定したユーザーの監視イベント一覧を取得する
+func (r *AuditLogRepository) ListByUserID(ctx context.Context, userID int) ([]models.AuditLog, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, `
+		SELECT id, action, user_id, post_id, api_key_id, api_key_prefix, created_at
+		FROM audit_logs
+		WHERE user_id = $1
+		ORDER BY created_at ASC, id ASC
+	`, userID)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch audit logs : UserID=%d", userID), err)
+	}
+	defer rows.Close()
+
+	logs := []models.AuditLog{}
+	for rows.Next() {
+		var entry models.AuditLog
+		var postID, apiKeyID sql.NullInt64
+		var apiKeyPrefix sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.Action, &entry.UserID, &postID, &apiKeyID, &apiKeyPrefix, &entry.CreatedAt); err != nil {
+			return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to parse audit log : UserID=%d", userID), err)
+		}
+		entry.PostID = int(postID.Int64)
+		entry.APIKeyID = int(apiKeyID.Int64)
+		entry.APIKeyPrefix = apiKeyPrefix.String
+		logs = append(logs, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch audit logs : UserID=%d", userID), err)
+	}
+	return logs, nil
+}
@@ -0,0 +1,24 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/repository"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository/repotest"
+	"github.com/yusuke-hoguro/BlogApi/testutils"
+)
+
+// PostgreSQLの実装に契約テストを実行する
+func TestPostgresRepositoryContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Backend {
+		db := testutils.SetupTestDB(t)
+		t.Cleanup(func() { db.Close() })
+		return repotest.Backend{
+			Users:    repository.NewUserRepository(db),
+			Posts:    repository.NewPostRepository(db),
+			Comments: repository.NewCommentRepository(db),
+			Likes:    repository.NewLikeRepository(db),
+			Follows:  repository.NewFollowRepository(db),
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// データエクスポートの取得に使うカラム
+const dataExportColumns = "id, user_id, status, COALESCE(error, ''), created_at, completed_at, expires_at"
+
+// データエクスポート用のリポジトリ
+type DataExportRepository struct {
+	db DBExecutor
+}
+
+// データエクスポート用リポジトリのインスタンスを生成
+func NewDataExportRepository(db DBExecutor) *DataExportRepository {
+	return &DataExportRepository{db: db}
+}
+
+// データエクスポートを受け付ける
+func (r *DataExportRepository) Create(ctx context.Context, userID int) (*models.DataExport, error) {
+	export, err := scanDataExport(executor(ctx, r.db).QueryRowContext(ctx,
+		"INSERT INTO data_exports (user_id, status) VALUES ($1, $2) RETURNING "+dataExportColumns,
+		userID, models.DataExportStatusPending,
+	))
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to insert data export : UserID=%d", userID), err)
+	}
+	return export, nil
+}
+
+// 指定したユーザーの最新のデータエクスポートを取得する
+func (r *DataExportRepository) FindLatestByUserID(ctx context.Context, userID int) (*models.DataExport, error) {
+	export, err := scanDataExport(executor(ctx, r.db).QueryRowContext(ctx,
+		"SELECT "+dataExportColumns+" FROM data_exports WHERE user_id = $1 ORDER BY id DESC LIMIT 1", userID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Data export not found : UserID=%d", userID), err).WithSubCode(apperror.CodeExportNotFound)
+	} else if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Database error : UserID=%d", userID), err)
+	}
+	return export, nil
+}
+
+// 指定したデータエクスポートのアーカイブを取得する(期限切れの場合は見つからない扱いにする)
+func (r *DataExportRepository) FindArchive(ctx context.Context, id int) ([]byte, error) {
+	var archive []byte
+	err := executor(ctx, r.db).QueryRowContext(ctx,
+		"SELECT archive FROM data_exports WHERE id = $1 AND status = $2 AND expires_at > NOW()",
+		id, models.DataExportStatusCompleted,
+	).Scan(&archive)
+	if err == sql.ErrNoRows {
+		return nil, apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Data export not found or expired : ExportID=%d", id), err).WithSubCode(apperror.CodeExportNotFound)
+	} else if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Database error : ExportID=%d", id), err)
+	}
+	return archive, nil
+}
+
+// 未処理のデータエクスポートを1件取り出して処理中にする(対象が無い場合はnilを返す)
+// 処理中のまま staleAfter を過ぎたもの(処理中にプロセスが停止したもの)も再処理の対象にする
+func (r *DataExportRepository) ClaimPending(ctx context.Context, staleAfter time.Duration) (*models.DataExport, error) {
+	export, err := scanDataExport(executor(ctx, r.db).QueryRowContext(ctx, `
+		UPDATE data_exports SET status = $1, started_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = $2 OR (status = $1 AND started_at < $3)
+			ORDER BY id ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+dataExportColumns,
+		models.DataExportStatusRunning, models.DataExportStatusPending, time.Now().Add(-staleAfter),
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to claim data export", err)
+	}
+	return export, nil
+}
+
+// データエクスポートを完了にしてアーカイブを保存する
+func (r *DataExportRepository) Complete(ctx context.Context, id int, archive []byte, expiresAt time.Time) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx,
+		"UPDATE data_exports SET status = $1, archive = $2, completed_at = CURRENT_TIMESTAMP, expires_at = $3 WHERE id = $4",
+		models.DataExportStatusCompleted, archive, expiresAt, id,
+	)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to complete data export : ExportID=%d", id), err)
+	}
+	return checkRowAffected(result, fmt.Sprintf("Data export not found : ExportID=%d", id), apperror.CodeExportNotFound)
+}
+
+// データエクスポートを失敗にする
+func (r *DataExportRepository) Fail(ctx context.Context, id int, message string) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx,
+		"UPDATE data_exports SET status = $1, error = $2, completed_at = CURRENT_TIMESTAMP WHERE id = $3",
+		models.DataExportStatusFailed, message, id,
+	)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to update data export : ExportID=%d", id), err)
+	}
+	return checkRowAffected(result, fmt.Sprintf("Data export not found : ExportID=%d", id), apperror.CodeExportNotFound)
+}
+
+// 期限切れのデータエクスポートを削除する
+func (r *DataExportRepository) DeleteExpired(ctx context.Context) error {
+	if _, err := executor(ctx, r.db).ExecContext(ctx, "DELETE FROM data_exports WHERE expires_at < NOW()"); err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to delete expired data exports", err)
+	}
+	return nil
+}
+
+// データエクスポートの行を構造体に変換する
+func scanDataExport(row *sql.Row) (*models.DataExport, error) {
+	var export models.DataExport
+	if err := row.Scan(&export.ID, &export.UserID, &export.Status, &export.Error, &export.CreatedAt, &export.CompletedAt, &export.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
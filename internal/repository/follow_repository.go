@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// フォロー用のリポジトリ
+type FollowRepository struct {
+	db DBExecutor
+}
+
+// フォロー用リポジトリのインスタンスを生成
+func NewFollowRepository(db DBExecutor) *FollowRepository {
+	return &FollowRepository{db: db}
+}
+
+// ユーザーをフォローする(フォロー済みの場合は何もしない)
+func (r *FollowRepository) Create(ctx context.Context, followerID int, followeeID int) error {
+	return withTx(ctx, r.db, func(ctx context.Context) error {
+		result, err := executor(ctx, r.db).ExecContext(ctx, "INSERT INTO follows (follower_id, followee_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", followerID, followeeID)
+		if err != nil {
+			if isForeignKeyViolation(err, "follows_followee_id_fkey") {
+				return apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("User not found : UserID=%d", followeeID), err).WithSubCode(apperror.CodeUserNotFound)
+			}
+			return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to follow user : UserID=%d", followeeID), err)
+		}
+		// 新しくフォローした場合のみフォローのイベントをアウトボックスに書き込む
+		added, err := result.RowsAffected()
+		if err != nil {
+			return apperror.NewAppError(apperror.TypeInternalServer, "Failed to confirm operation", err)
+		} else if added == 0 {
+			return nil
+		}
+		return insertOutboxEvent(ctx, r.db, models.DomainEventUserFollowed, followeeID, models.UserFollowedEvent{FollowerID: followerID, FolloweeID: followeeID})
+	})
+}
+
+// ユーザーのフォローを解除する
+func (r *FollowRepository) Delete(ctx context.Context, followerID int, followeeID int) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx, "DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2", followerID, followeeID)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to unfollow user : UserID=%d", followeeID), err)
+	}
+	return nil
+}
+
+// 指定したユーザーのフォロワー一覧を新しい順に取得する
+func (r *FollowRepository) ListFollowers(ctx context.Context, userID int, cursor *models.PageCursor, limit int) ([]models.FollowUser, error) {
+	query := `
+		SELECT u.id, u.username, f.created_at
+		FROM follows f
+		JOIN users u ON u.id = f.follower_id
+		WHERE f.followee_id = $1`
+	return r.listFollowUsers(ctx, query, "f.follower_id", userID, cursor, limit)
+}
+
+// 指定したユーザーのフォロー中一覧を新しい順に取得する
+func (r *FollowRepository) ListFollowing(ctx context.Context, userID int, cursor *models.PageCursor, limit int) ([]models.FollowUser, error) {
+	query := `
+		SELECT u.id, u.username, f.created_at
+		FROM follows f
+		JOIN users u ON u.id = f.followee_id
+		WHERE f.follower_id = $1`
+	return r.listFollowUsers(ctx, query, "f.followee_id", userID, cursor, limit)
+}
+
+// 指定したユーザーのフォロワーのID一覧を取得する
+func (r *FollowRepository) ListFollowerIDs(ctx context.Context, userID int) ([]int, error) {
+	return r.listIDs(ctx, "SELECT follower_id FROM follows WHERE followee_id = $1", userID)
+}
+
+// 指定したユーザーがフォローしているユーザーのID一覧を取得する
+func (r *FollowRepository) ListFolloweeIDs(ctx context.Context, userID int) ([]int, error) {
+	return r.listIDs(ctx, "SELECT followee_id FROM follows WHERE follower_id = $1", userID)
+}
+
+// ユーザーID一覧を取得する共通処理
+func (r *FollowRepository) listIDs(ctx context.Context, query string, userID int) ([]int, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch follows : UserID=%d", userID), err)
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to parse follow : UserID=%d", userID), err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch follows : UserID=%d", userID), err)
+	}
+	return ids, nil
+}
+
+// フォロー一覧をカーソル位置から取得する共通処理
+func (r *FollowRepository) listFollowUsers(ctx context.Context, query string, idColumn string, userID int, cursor *models.PageCursor, limit int) ([]models.FollowUser, error) {
+	args := []any{userID}
+	if cursor != nil {
+		query += fmt.Sprintf(" AND (f.created_at, %s) < ($2, $3)", idColumn)
+		args = append(args, cursor.CreatedAt, cursor.ID)
+	}
+	query += fmt.Sprintf(" ORDER BY f.created_at DESC, %s DESC LIMIT $%d", idColumn, len(args)+1)
+	args = append(args, limit)
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch follows : UserID=%d", userID), err)
+	}
+	defer rows.Close()
+
+	users := []models.FollowUser{}
+	for rows.Next() {
+		var user models.FollowUser
+		if err := rows.Scan(&user.ID, &user.Username, &user.FollowedAt); err != nil {
+			return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to parse follow : UserID=%d", userID), err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch follows : UserID=%d", userID), err)
+	}
+	return users, nil
+}
+
+func isForeignKeyViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && string(pqErr.Code) == "23503" && pqErr.Constraint == constraint
+}
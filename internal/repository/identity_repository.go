@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// 外部IDプロバイダー連携用のリポジトリ
+type IdentityRepository struct {
+	db DBExecutor
+}
+
+// 外部IDプロバイダー連携用リポジトリのインスタンスを生成
+func NewIdentityRepository(db DBExecutor) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// プロバイダーとsubjectから紐付いているユーザーIDを取得する
+func (r *IdentityRepository) FindUserID(ctx context.Context, provider string, subject string) (int, error) {
+	var userID int
+	err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2", provider, subject).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Identity not found : Provider=%s", provider), err).WithSubCode(apperror.CodeProviderNotFound)
+	} else if err != nil {
+		return 0, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Database error : Provider=%s", provider), err)
+	}
+	return userID, nil
+}
+
+// 外部IDプロバイダーのアカウントをユーザーに紐付ける
+func (r *IdentityRepository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	err := executor(ctx, r.db).QueryRowContext(ctx,
+		"INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, $4) RETURNING id",
+		identity.UserID, identity.Provider, identity.Subject, identity.Email,
+	).Scan(&identity.ID)
+	if err != nil {
+		if isUniqueViolation(err, "user_identities_provider_subject_key") {
+			return apperror.NewAppError(apperror.TypeConflict, fmt.Sprintf("Identity already linked : Provider=%s", identity.Provider), err).WithSubCode(apperror.CodeIdentityLinked)
+		}
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to insert identity : Provider=%s", identity.Provider), err)
+	}
+	return nil
+}
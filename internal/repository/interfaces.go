@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// サービスが利用するリポジトリのインターフェース
+// PostgreSQLの実装(このパッケージ)とインメモリの実装(memoryパッケージ)で同じAppErrorの種別を返す
+// 振る舞いの取り決めは repotest パッケージの契約テストで確認する
+
+// 投稿用のリポジトリのインターフェース
+type PostStore interface {
+	FindByID(ctx context.Context, id int) (*models.Post, error)
+	ListByUserID(ctx context.Context, userID int) ([]models.Post, error)
+	ListAll(ctx context.Context) ([]models.Post, error)
+	ListFeed(ctx context.Context, userID int, cursor *models.PageCursor, limit int) ([]models.Post, error)
+	Create(ctx context.Context, post *models.Post) error
+	FindUserIDByPostID(ctx context.Context, postID int) (int, error)
+	Update(ctx context.Context, id int, post *models.Post) error
+	Delete(ctx context.Context, id int) error
+}
+
+// コメント用のリポジトリのインターフェース
+type CommentStore interface {
+	ListByPostID(ctx context.Context, postID int) ([]models.Comment, error)
+	FindByID(ctx context.Context, id int) (*models.Comment, error)
+	Create(ctx context.Context, comment *models.Comment) error
+	FindOwnerByID(ctx context.Context, commentID int) (int, int, error)
+	Delete(ctx context.Context, commentID int) error
+	Update(ctx context.Context, commentID int, content string) error
+	ListByUserID(ctx context.Context, userID int) ([]models.Comment, error)
+}
+
+// いいね用のリポジトリのインターフェース
+type LikeStore interface {
+	Create(ctx context.Context, userID int, postID int) error
+	Delete(ctx context.Context, userID int, postID int) error
+	CountByPostID(ctx context.Context, postID int) (int, error)
+	ListUserIDsByPostID(ctx context.Context, postID int) ([]int, error)
+	ListByUserID(ctx context.Context, userID int) ([]models.Like, error)
+}
+
+// ユーザー用のリポジトリのインターフェース
+type UserStore interface {
+	Create(ctx context.Context, username string, hashedPassword string) (int, error)
+	CreateIfAvailable(ctx context.Context, username string, hashedPassword string) (int, bool, error)
+	FindAuthByUsername(ctx context.Context, username string) (int, string, error)
+	FindUsernameByID(ctx context.Context, id int) (string, error)
+	IsActive(ctx context.Context, id int) (bool, error)
+}
+
+// フォロー用のリポジトリのインターフェース
+type FollowStore interface {
+	Create(ctx context.Context, followerID int, followeeID int) error
+	Delete(ctx context.Context, followerID int, followeeID int) error
+	ListFollowers(ctx context.Context, userID int, cursor *models.PageCursor, limit int) ([]models.FollowUser, error)
+	ListFollowing(ctx context.Context, userID int, cursor *models.PageCursor, limit int) ([]models.FollowUser, error)
+	ListFollowerIDs(ctx context.Context, userID int) ([]int, error)
+	ListFolloweeIDs(ctx context.Context, userID int) ([]int, error)
+}
+
+// PostgreSQLの実装がインターフェースを満たしていることをコンパイル時に確認する
+var (
+	_ PostStore    = (*PostRepository)(nil)
+	_ CommentStore = (*CommentRepository)(nil)
+	_ LikeStore    = (*LikeRepository)(nil)
+	_ UserStore    = (*UserRepository)(nil)
+	_ FollowStore  = (*FollowRepository)(nil)
+)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
 )
 
 // いいね用のリポジトリ
@@ -17,27 +18,46 @@ func NewLikeRepository(db DBExecutor) *LikeRepository {
 	return &LikeRepository{db: db}
 }
 
-// 投稿にいいねを追加する
+// 投稿にいいねを追加する(いいね済みの場合は何もしない)
 func (r *LikeRepository) Create(ctx context.Context, userID int, postID int) error {
-	_, err := r.db.ExecContext(ctx, "INSERT INTO likes (user_id, post_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", userID, postID)
-	if err != nil {
-		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to like post : PostID=%d", postID), err)
-	}
-	return nil
+	return withTx(ctx, r.db, func(ctx context.Context) error {
+		result, err := executor(ctx, r.db).ExecContext(ctx, "INSERT INTO likes (user_id, post_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", userID, postID)
+		if err != nil {
+			return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to like post : PostID=%d", postID), err)
+		}
+		// 新しく追加した場合のみいいねのイベントをアウトボックスに書き込む
+		added, err := result.RowsAffected()
+		if err != nil {
+			return apperror.NewAppError(apperror.TypeInternalServer, "Failed to confirm operation", err)
+		} else if added == 0 {
+			return nil
+		}
+		return insertOutboxEvent(ctx, r.db, models.DomainEventPostLiked, postID, models.PostLikedEvent{PostID: postID, UserID: userID})
+	})
 }
 
 // 投稿のいいねを削除する
 func (r *LikeRepository) Delete(ctx context.Context, userID int, postID int) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM likes WHERE user_id = $1 AND post_id = $2", userID, postID)
+	_, err := executor(ctx, r.db).ExecContext(ctx, "DELETE FROM likes WHERE user_id = $1 AND post_id = $2", userID, postID)
 	if err != nil {
 		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to remove like : PostID=%d", postID), err)
 	}
 	return nil
 }
 
-// 指定した投稿のいいねユーザーID一覧を取得する
+// 指定した投稿のいいね数を取得する
+func (r *LikeRepository) CountByPostID(ctx context.Context, postID int) (int, error) {
+	var count int
+	err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM likes WHERE post_id = $1", postID).Scan(&count)
+	if err != nil {
+		return 0, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to count likes : PostID=%d", postID), err)
+	}
+	return count, nil
+}
+
+// 指定した投稿のいいねユーザーID一覧を取得する(リードレプリカが設定されている場合はレプリカから読み取る)
 func (r *LikeRepository) ListUserIDsByPostID(ctx context.Context, postID int) ([]int, error) {
-	rows, err := r.db.QueryContext(ctx, "SELECT user_id FROM likes WHERE post_id = $1", postID)
+	rows, err := readExecutor(ctx, r.db).QueryContext(ctx, "SELECT user_id FROM likes WHERE post_id = $1", postID)
 	if err != nil {
 		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch likes : PostID=%d", postID), err)
 	}
@@ -58,3 +78,27 @@ func (r *LikeRepository) ListUserIDsByPostID(ctx context.Context, postID int) ([
 
 	return userIDs, nil
 }
+
+// 指定したユーザーのいいね一覧を取得する
+func (r *LikeRepository) ListByUserID(ctx context.Context, userID int) ([]models.Like, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, "SELECT id, user_id, post_id FROM likes WHERE user_id = $1 ORDER BY id ASC", userID)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch likes : UserID=%d", userID), err)
+	}
+	defer rows.Close()
+
+	likes := []models.Like{}
+	for rows.Next() {
+		var like models.Like
+		if err := rows.Scan(&like.ID, &like.UserID, &like.PostID); err != nil {
+			return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to scan row : UserID=%d", userID), err)
+		}
+		likes = append(likes, like)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch likes : UserID=%d", userID), err)
+	}
+
+	return likes, nil
+}
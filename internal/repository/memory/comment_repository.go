@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// コメント用のインメモリのリポジトリ
+type CommentRepository struct {
+	store *Store
+}
+
+// 指定した投稿IDのコメントを古い順に見つける
+func (r *CommentRepository) ListByPostID(ctx context.Context, postID int) ([]models.Comment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.sortedComments(func(comment models.Comment) bool { return comment.PostID == postID }), nil
+}
+
+// 指定したIDのコメントを見つける
+func (r *CommentRepository) FindByID(ctx context.Context, id int) (*models.Comment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	comment, ok := r.store.comments[id]
+	if !ok {
+		return nil, apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Comment Not Found : CommentID=%d", id), nil).WithSubCode(apperror.CodeCommentNotFound)
+	}
+	return &comment, nil
+}
+
+// コメントを作成する(投稿とユーザーが存在しない場合は外部キー違反として扱う)
+func (r *CommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	_, postExists := r.store.posts[comment.PostID]
+	_, userExists := r.store.users[comment.UserID]
+	if !postExists || !userExists {
+		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to insert comment", errForeignKeyViolation)
+	}
+
+	r.store.lastCommentID++
+	comment.ID = r.store.lastCommentID
+	comment.CreatedAt = r.store.now()
+	r.store.comments[comment.ID] = *comment
+	return nil
+}
+
+// 指定したコメントIDから所有者のユーザーIDと投稿IDを取得する
+func (r *CommentRepository) FindOwnerByID(ctx context.Context, commentID int) (int, int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	comment, ok := r.store.comments[commentID]
+	if !ok {
+		return 0, 0, apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Comment not found : CommentID=%d", commentID), nil).WithSubCode(apperror.CodeCommentNotFound)
+	}
+	return comment.UserID, comment.PostID, nil
+}
+
+// 指定したIDのコメントを削除する
+func (r *CommentRepository) Delete(ctx context.Context, commentID int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.comments[commentID]; !ok {
+		return apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Comment not found : CommentID=%d", commentID), nil).WithSubCode(apperror.CodeCommentNotFound)
+	}
+	delete(r.store.comments, commentID)
+	return nil
+}
+
+// 指定したIDのコメントを更新する
+func (r *CommentRepository) Update(ctx context.Context, commentID int, content string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	comment, ok := r.store.comments[commentID]
+	if !ok {
+		return apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Comment not found : CommentID=%d", commentID), nil).WithSubCode(apperror.CodeCommentNotFound)
+	}
+	comment.Content = content
+	r.store.comments[commentID] = comment
+	return nil
+}
+
+// 指定したユーザーのコメント一覧を古い順に取得する
+func (r *CommentRepository) ListByUserID(ctx context.Context, userID int) ([]models.Comment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.sortedComments(func(comment models.Comment) bool { return comment.UserID == userID }), nil
+}
@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// フォロー用のインメモリのリポジトリ
+type FollowRepository struct {
+	store *Store
+}
+
+// ユーザーをフォローする(フォロー済みの場合は何もしない)
+// 制約の確認順はPostgreSQLに合わせる(自分自身のフォロー・フォローされる側・フォローする側の順)
+func (r *FollowRepository) Create(ctx context.Context, followerID int, followeeID int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if followerID == followeeID {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to follow user : UserID=%d", followeeID), errCheckViolation)
+	}
+	if _, ok := r.store.users[followeeID]; !ok {
+		return apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("User not found : UserID=%d", followeeID), errForeignKeyViolation).WithSubCode(apperror.CodeUserNotFound)
+	}
+	if _, ok := r.store.users[followerID]; !ok {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to follow user : UserID=%d", followeeID), errForeignKeyViolation)
+	}
+
+	key := followKey{followerID: followerID, followeeID: followeeID}
+	if _, ok := r.store.follows[key]; ok {
+		return nil
+	}
+	r.store.follows[key] = r.store.now()
+	return nil
+}
+
+// ユーザーのフォローを解除する
+func (r *FollowRepository) Delete(ctx context.Context, followerID int, followeeID int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.follows, followKey{followerID: followerID, followeeID: followeeID})
+	return nil
+}
+
+// 指定したユーザーのフォロワー一覧を新しい順に取得する
+func (r *FollowRepository) ListFollowers(ctx context.Context, userID int, cursor *models.PageCursor, limit int) ([]models.FollowUser, error) {
+	return r.listFollowUsers(cursor, limit, func(key followKey) (int, bool) {
+		return key.followerID, key.followeeID == userID
+	}), nil
+}
+
+// 指定したユーザーのフォロー中一覧を新しい順に取得する
+func (r *FollowRepository) ListFollowing(ctx context.Context, userID int, cursor *models.PageCursor, limit int) ([]models.FollowUser, error) {
+	return r.listFollowUsers(cursor, limit, func(key followKey) (int, bool) {
+		return key.followeeID, key.followerID == userID
+	}), nil
+}
+
+// 指定したユーザーのフォロワーのID一覧を取得する
+func (r *FollowRepository) ListFollowerIDs(ctx context.Context, userID int) ([]int, error) {
+	return r.listIDs(func(key followKey) (int, bool) {
+		return key.followerID, key.followeeID == userID
+	}), nil
+}
+
+// 指定したユーザーがフォローしているユーザーのID一覧を取得する
+func (r *FollowRepository) ListFolloweeIDs(ctx context.Context, userID int) ([]int, error) {
+	return r.listIDs(func(key followKey) (int, bool) {
+		return key.followeeID, key.followerID == userID
+	}), nil
+}
+
+// 条件に一致するフォローの相手のID一覧をID順に取得する共通処理
+func (r *FollowRepository) listIDs(match func(followKey) (int, bool)) []int {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	ids := []int{}
+	for key := range r.store.follows {
+		if id, ok := match(key); ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// フォロー一覧をカーソル位置から取得する共通処理
+func (r *FollowRepository) listFollowUsers(cursor *models.PageCursor, limit int, match func(followKey) (int, bool)) []models.FollowUser {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	users := []models.FollowUser{}
+	for key, followedAt := range r.store.follows {
+		id, ok := match(key)
+		if !ok || !olderThan(cursor, followedAt, id) {
+			continue
+		}
+		users = append(users, models.FollowUser{ID: id, Username: r.store.users[id].username, FollowedAt: followedAt})
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return newerFirst(users[i].FollowedAt, users[i].ID, users[j].FollowedAt, users[j].ID)
+	})
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// いいね用のインメモリのリポジトリ
+type LikeRepository struct {
+	store *Store
+}
+
+// 投稿にいいねを追加する(いいね済みの場合は何もしない)
+func (r *LikeRepository) Create(ctx context.Context, userID int, postID int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	_, postExists := r.store.posts[postID]
+	_, userExists := r.store.users[userID]
+	if !postExists || !userExists {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to like post : PostID=%d", postID), errForeignKeyViolation)
+	}
+
+	key := likeKey{userID: userID, postID: postID}
+	if _, ok := r.store.likes[key]; ok {
+		return nil
+	}
+	r.store.lastLikeID++
+	r.store.likes[key] = models.Like{ID: r.store.lastLikeID, UserID: userID, PostID: postID}
+	return nil
+}
+
+// 投稿のいいねを削除する
+func (r *LikeRepository) Delete(ctx context.Context, userID int, postID int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.likes, likeKey{userID: userID, postID: postID})
+	return nil
+}
+
+// 指定した投稿のいいね数を取得する
+func (r *LikeRepository) CountByPostID(ctx context.Context, postID int) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return len(r.store.sortedLikes(func(like models.Like) bool { return like.PostID == postID })), nil
+}
+
+// 指定した投稿のいいねユーザーID一覧を取得する
+func (r *LikeRepository) ListUserIDsByPostID(ctx context.Context, postID int) ([]int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	userIDs := []int{}
+	for _, like := range r.store.sortedLikes(func(like models.Like) bool { return like.PostID == postID }) {
+		userIDs = append(userIDs, like.UserID)
+	}
+	return userIDs, nil
+}
+
+// 指定したユーザーのいいね一覧を取得する
+func (r *LikeRepository) ListByUserID(ctx context.Context, userID int) ([]models.Like, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.sortedLikes(func(like models.Like) bool { return like.UserID == userID }), nil
+}
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// 投稿用のインメモリのリポジトリ
+type PostRepository struct {
+	store *Store
+}
+
+// 指定したIDから投稿を見つける
+func (r *PostRepository) FindByID(ctx context.Context, id int) (*models.Post, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	post, ok := r.store.posts[id]
+	if !ok {
+		return nil, apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Post not found : PostID=%d", id), nil).WithSubCode(apperror.CodePostNotFound)
+	}
+	return &post, nil
+}
+
+// 指定したUserIDから投稿を見つける
+func (r *PostRepository) ListByUserID(ctx context.Context, userID int) ([]models.Post, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.sortedPosts(func(post models.Post) bool { return post.UserID == userID }), nil
+}
+
+// 全ての投稿を新しい順に見つける
+func (r *PostRepository) ListAll(ctx context.Context) ([]models.Post, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.sortedPosts(func(models.Post) bool { return true }), nil
+}
+
+// フォロー中のユーザーの投稿を新しい順に取得する(カーソルより古いものを取得する)
+func (r *PostRepository) ListFeed(ctx context.Context, userID int, cursor *models.PageCursor, limit int) ([]models.Post, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	posts := r.store.sortedPosts(func(post models.Post) bool {
+		_, following := r.store.follows[followKey{followerID: userID, followeeID: post.UserID}]
+		return following && olderThan(cursor, post.CreatedAt, post.ID)
+	})
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts, nil
+}
+
+// 新しい投稿を作成する
+func (r *PostRepository) Create(ctx context.Context, post *models.Post) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.lastPostID++
+	post.ID = r.store.lastPostID
+	post.CreatedAt = r.store.now()
+	r.store.posts[post.ID] = *post
+	return nil
+}
+
+// 指定した投稿のIDからユーザーIDを見つける
+func (r *PostRepository) FindUserIDByPostID(ctx context.Context, postID int) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	post, ok := r.store.posts[postID]
+	if !ok {
+		return 0, apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Post not found : PostID=%d", postID), nil).WithSubCode(apperror.CodePostNotFound)
+	}
+	return post.UserID, nil
+}
+
+// 指定したIDの投稿を更新する
+func (r *PostRepository) Update(ctx context.Context, id int, post *models.Post) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	current, ok := r.store.posts[id]
+	if !ok {
+		return apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Post not found : PostID=%d", id), nil).WithSubCode(apperror.CodePostNotFound)
+	}
+	current.Title = post.Title
+	current.Content = post.Content
+	r.store.posts[id] = current
+	return nil
+}
+
+// 指定したIDの投稿を削除する(コメントといいねもカスケード削除する)
+func (r *PostRepository) Delete(ctx context.Context, id int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.posts[id]; !ok {
+		return apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Post not found : PostID=%d", id), nil).WithSubCode(apperror.CodePostNotFound)
+	}
+	delete(r.store.posts, id)
+	for commentID, comment := range r.store.comments {
+		if comment.PostID == id {
+			delete(r.store.comments, commentID)
+		}
+	}
+	for key := range r.store.likes {
+		if key.postID == id {
+			delete(r.store.likes, key)
+		}
+	}
+	return nil
+}
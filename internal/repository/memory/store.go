@@ -0,0 +1,163 @@
+// Package memory はサービスのテストで使うインメモリのリポジトリ実装を提供する。
+// PostgreSQLの実装と同じAppErrorの種別(not found・conflictなど)を返し、外部キーのカスケード削除も再現する。
+// ドメインイベントのアウトボックスへの書き込みとトランザクションはPostgreSQLの実装のみが扱う。
+package memory
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository"
+)
+
+// 外部キー・チェック制約の違反を表すエラー(PostgreSQLの実装と同じくInternalServerErrorの原因として返す)
+var (
+	errForeignKeyViolation = errors.New("foreign key violation")
+	errCheckViolation      = errors.New("check constraint violation")
+)
+
+// ユーザーのレコード
+type userRecord struct {
+	id       int
+	username string
+	password string
+}
+
+// いいねのキー(ユーザーと投稿の組み合わせで一意)
+type likeKey struct {
+	userID int
+	postID int
+}
+
+// フォローのキー(フォローする側とされる側の組み合わせで一意)
+type followKey struct {
+	followerID int
+	followeeID int
+}
+
+// 全てのリポジトリで共有するインメモリのデータ
+type Store struct {
+	mu sync.Mutex
+
+	users     map[int]userRecord
+	usernames map[string]int
+	posts     map[int]models.Post
+	comments  map[int]models.Comment
+	likes     map[likeKey]models.Like
+	follows   map[followKey]time.Time
+
+	// SERIALの採番を再現する
+	lastUserID    int
+	lastPostID    int
+	lastCommentID int
+	lastLikeID    int
+
+	now func() time.Time
+}
+
+// インメモリのデータのインスタンスを生成
+func NewStore() *Store {
+	return &Store{
+		users:     map[int]userRecord{},
+		usernames: map[string]int{},
+		posts:     map[int]models.Post{},
+		comments:  map[int]models.Comment{},
+		likes:     map[likeKey]models.Like{},
+		follows:   map[followKey]time.Time{},
+		// PostgreSQLのTIMESTAMPに合わせてマイクロ秒に丸める
+		now: func() time.Time { return time.Now().UTC().Truncate(time.Microsecond) },
+	}
+}
+
+// 投稿用のリポジトリを取得する
+func (s *Store) Posts() *PostRepository {
+	return &PostRepository{store: s}
+}
+
+// コメント用のリポジトリを取得する
+func (s *Store) Comments() *CommentRepository {
+	return &CommentRepository{store: s}
+}
+
+// いいね用のリポジトリを取得する
+func (s *Store) Likes() *LikeRepository {
+	return &LikeRepository{store: s}
+}
+
+// ユーザー用のリポジトリを取得する
+func (s *Store) Users() *UserRepository {
+	return &UserRepository{store: s}
+}
+
+// フォロー用のリポジトリを取得する
+func (s *Store) Follows() *FollowRepository {
+	return &FollowRepository{store: s}
+}
+
+// 作成日時とIDの降順(新しい順)に並べ替えるための比較関数
+func newerFirst(aCreatedAt time.Time, aID int, bCreatedAt time.Time, bID int) bool {
+	if !aCreatedAt.Equal(bCreatedAt) {
+		return aCreatedAt.After(bCreatedAt)
+	}
+	return aID > bID
+}
+
+// カーソルより古い位置にあるかを判定する((created_at, id) < (cursor.CreatedAt, cursor.ID))
+func olderThan(cursor *models.PageCursor, createdAt time.Time, id int) bool {
+	if cursor == nil {
+		return true
+	}
+	return newerFirst(cursor.CreatedAt, cursor.ID, createdAt, id)
+}
+
+// 条件に一致する投稿を新しい順に取得する(ロック取得済みで呼び出す)
+func (s *Store) sortedPosts(match func(models.Post) bool) []models.Post {
+	posts := []models.Post{}
+	for _, post := range s.posts {
+		if match(post) {
+			posts = append(posts, post)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return newerFirst(posts[i].CreatedAt, posts[i].ID, posts[j].CreatedAt, posts[j].ID)
+	})
+	return posts
+}
+
+// 条件に一致するコメントを古い順に取得する(ロック取得済みで呼び出す)
+func (s *Store) sortedComments(match func(models.Comment) bool) []models.Comment {
+	comments := []models.Comment{}
+	for _, comment := range s.comments {
+		if match(comment) {
+			comments = append(comments, comment)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		return newerFirst(comments[j].CreatedAt, comments[j].ID, comments[i].CreatedAt, comments[i].ID)
+	})
+	return comments
+}
+
+// 条件に一致するいいねをID順に取得する(ロック取得済みで呼び出す)
+func (s *Store) sortedLikes(match func(models.Like) bool) []models.Like {
+	likes := []models.Like{}
+	for _, like := range s.likes {
+		if match(like) {
+			likes = append(likes, like)
+		}
+	}
+	sort.Slice(likes, func(i, j int) bool { return likes[i].ID < likes[j].ID })
+	return likes
+}
+
+// インメモリの実装がインターフェースを満たしていることをコンパイル時に確認する
+var (
+	_ repository.PostStore    = (*PostRepository)(nil)
+	_ repository.CommentStore = (*CommentRepository)(nil)
+	_ repository.LikeStore    = (*LikeRepository)(nil)
+	_ repository.UserStore    = (*UserRepository)(nil)
+	_ repository.FollowStore  = (*FollowRepository)(nil)
+)
@@ -0,0 +1,22 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/repository/memory"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository/repotest"
+)
+
+// インメモリの実装に契約テストを実行する
+func TestMemoryRepositoryContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Backend {
+		store := memory.NewStore()
+		return repotest.Backend{
+			Users:    store.Users(),
+			Posts:    store.Posts(),
+			Comments: store.Comments(),
+			Likes:    store.Likes(),
+			Follows:  store.Follows(),
+		}
+	})
+}
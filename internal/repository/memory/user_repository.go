@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+)
+
+// ユーザー用のインメモリのリポジトリ
+type UserRepository struct {
+	store *Store
+}
+
+// ユーザーを作成する
+func (r *UserRepository) Create(ctx context.Context, username string, hashedPassword string) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.usernames[username]; ok {
+		return 0, apperror.NewAppError(apperror.TypeConflict, "User already exists : Username="+username, nil).WithSubCode(apperror.CodeUsernameTaken)
+	}
+	return r.insert(username, hashedPassword), nil
+}
+
+// ユーザー名が未使用の場合のみユーザーを作成する(使用済みの場合はfalseを返す)
+func (r *UserRepository) CreateIfAvailable(ctx context.Context, username string, hashedPassword string) (int, bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.usernames[username]; ok {
+		return 0, false, nil
+	}
+	return r.insert(username, hashedPassword), true, nil
+}
+
+// ユーザー名から認証情報を取得する
+func (r *UserRepository) FindAuthByUsername(ctx context.Context, username string) (int, string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	id, ok := r.store.usernames[username]
+	if !ok {
+		return 0, "", apperror.NewAppError(apperror.TypeUnauthorized, "Invalid username or password : Username="+username, nil).WithSubCode(apperror.CodeInvalidCredentials)
+	}
+	return id, r.store.users[id].password, nil
+}
+
+// 指定したIDのユーザー名を取得する
+func (r *UserRepository) FindUsernameByID(ctx context.Context, id int) (string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return "", apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("User not found : UserID=%d", id), nil).WithSubCode(apperror.CodeUserNotFound)
+	}
+	return user.username, nil
+}
+
+// ユーザーが存在するか確認する(インメモリのストアではアカウント削除を扱わない)
+func (r *UserRepository) IsActive(ctx context.Context, id int) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	_, ok := r.store.users[id]
+	return ok, nil
+}
+
+// ユーザーを登録して採番したIDを返す(ロック取得済みで呼び出す)
+func (r *UserRepository) insert(username string, hashedPassword string) int {
+	r.store.lastUserID++
+	id := r.store.lastUserID
+	r.store.users[id] = userRecord{id: id, username: username, password: hashedPassword}
+	r.store.usernames[username] = id
+	return id
+}
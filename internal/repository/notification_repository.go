@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// 通知を取得するSELECT句(最後に操作したユーザーは actor_ids の末尾に入っている)
+const notificationSelect = `
+	SELECT n.id, n.type, COALESCE(n.post_id, 0), cardinality(n.actor_ids), n.actor_ids[cardinality(n.actor_ids)],
+		COALESCE(u.username, ''), n.created_at, n.updated_at, n.read_at
+	FROM notifications n
+	LEFT JOIN users u ON u.id = n.actor_ids[cardinality(n.actor_ids)]`
+
+// 通知用のリポジトリ
+type NotificationRepository struct {
+	db DBExecutor
+}
+
+// 通知用リポジトリのインスタンスを生成
+func NewNotificationRepository(db DBExecutor) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// 通知を追加して通知IDを返す(同じまとめ単位の未読の通知がある場合は操作したユーザーを追加して更新日時を進める)
+func (r *NotificationRepository) Upsert(ctx context.Context, userID int, notificationType string, postID int, groupKey string, actorID int) (int, error) {
+	var id int
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO notifications (user_id, type, post_id, group_key, actor_ids)
+		VALUES ($1, $2, NULLIF($3, 0), $4, ARRAY[$5::INTEGER])
+		ON CONFLICT (user_id, group_key) WHERE read_at IS NULL DO UPDATE SET
+			actor_ids = array_remove(notifications.actor_ids, $5::INTEGER) || $5::INTEGER,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING id
+	`, userID, notificationType, postID, groupKey, actorID).Scan(&id)
+	if err != nil {
+		return 0, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to create notification : UserID=%d", userID), err)
+	}
+	return id, nil
+}
+
+// 指定したユーザーの通知をIDで取得する
+func (r *NotificationRepository) FindByID(ctx context.Context, userID int, id int) (*models.Notification, error) {
+	n, err := scanNotification(executor(ctx, r.db).QueryRowContext(ctx, notificationSelect+" WHERE n.id = $1 AND n.user_id = $2", id, userID))
+	if err == sql.ErrNoRows {
+		return nil, apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Notification not found : NotificationID=%d", id), err).WithSubCode(apperror.CodeNotificationNotFound)
+	} else if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Database error : NotificationID=%d", id), err)
+	}
+	return n, nil
+}
+
+// 指定したユーザーの通知を作成日時の新しい順に取得する
+// まとめた操作で更新日時が進んでもページの境界をまたがないように、変わらない (created_at, id) をカーソルにする
+func (r *NotificationRepository) List(ctx context.Context, userID int, unreadOnly bool, cursor *models.PageCursor, limit int) ([]models.Notification, error) {
+	query := notificationSelect + " WHERE n.user_id = $1"
+	args := []any{userID}
+	if unreadOnly {
+		query += " AND n.read_at IS NULL"
+	}
+	if cursor != nil {
+		query += " AND (n.created_at, n.id) < ($2, $3)"
+		args = append(args, cursor.CreatedAt, cursor.ID)
+	}
+	query += fmt.Sprintf(" ORDER BY n.created_at DESC, n.id DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit)
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch notifications : UserID=%d", userID), err)
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to parse notification : UserID=%d", userID), err)
+		}
+		notifications = append(notifications, *n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch notifications : UserID=%d", userID), err)
+	}
+	return notifications, nil
+}
+
+// 指定したユーザーの未読の通知件数を取得する
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL", userID).Scan(&count)
+	if err != nil {
+		return 0, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to count unread notifications : UserID=%d", userID), err)
+	}
+	return count, nil
+}
+
+// 指定した通知を既読にする(既読済みの場合は既読日時を変更しない)
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID int, id int) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx,
+		"UPDATE notifications SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP) WHERE id = $1 AND user_id = $2", id, userID,
+	)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to mark notification as read : NotificationID=%d", id), err)
+	}
+	return checkRowAffected(result, fmt.Sprintf("Notification not found : NotificationID=%d", id), apperror.CodeNotificationNotFound)
+}
+
+// 指定したユーザーの未読の通知をすべて既読にする
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID int) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx, "UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND read_at IS NULL", userID)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to mark notifications as read : UserID=%d", userID), err)
+	}
+	return nil
+}
+
+// 指定したユーザーの通知設定を取得する(未設定の場合はすべて通知する)
+func (r *NotificationRepository) FindPreferences(ctx context.Context, userID int) (*models.NotificationPreferences, error) {
+	prefs := models.NotificationPreferences{Likes: true, Comments: true, Follows: true}
+	err := executor(ctx, r.db).QueryRowContext(ctx,
+		"SELECT likes, comments, follows FROM notification_preferences WHERE user_id = $1", userID,
+	).Scan(&prefs.Likes, &prefs.Comments, &prefs.Follows)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Database error : UserID=%d", userID), err)
+	}
+	return &prefs, nil
+}
+
+// 指定したユーザーの通知設定を保存する
+func (r *NotificationRepository) UpsertPreferences(ctx context.Context, userID int, prefs *models.NotificationPreferences) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO notification_preferences (user_id, likes, comments, follows)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			likes = EXCLUDED.likes, comments = EXCLUDED.comments, follows = EXCLUDED.follows, updated_at = CURRENT_TIMESTAMP
+	`, userID, prefs.Likes, prefs.Comments, prefs.Follows)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to update notification preferences : UserID=%d", userID), err)
+	}
+	return nil
+}
+
+// 通知の1行を読み取る
+func scanNotification(row interface{ Scan(...any) error }) (*models.Notification, error) {
+	var n models.Notification
+	var readAt sql.NullTime
+	if err := row.Scan(&n.ID, &n.Type, &n.PostID, &n.ActorCount, &n.LatestActorID, &n.LatestActorName, &n.CreatedAt, &n.UpdatedAt, &readAt); err != nil {
+		return nil, err
+	}
+	if readAt.Valid {
+		n.ReadAt = &readAt.Time
+	}
+	return &n, nil
+}
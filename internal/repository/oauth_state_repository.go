@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// 認可フローのstate用のリポジトリ
+type OAuthStateRepository struct {
+	db DBExecutor
+}
+
+// 認可フローのstate用リポジトリのインスタンスを生成
+func NewOAuthStateRepository(db DBExecutor) *OAuthStateRepository {
+	return &OAuthStateRepository{db: db}
+}
+
+// stateを保存する(期限切れのstateもあわせて削除する)
+func (r *OAuthStateRepository) Create(ctx context.Context, state *models.OAuthState) error {
+	if _, err := executor(ctx, r.db).ExecContext(ctx, "DELETE FROM oauth_states WHERE expires_at < NOW()"); err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to purge expired oauth states", err)
+	}
+
+	var linkUserID sql.NullInt64
+	if state.LinkUserID != 0 {
+		linkUserID = sql.NullInt64{Int64: int64(state.LinkUserID), Valid: true}
+	}
+	_, err := executor(ctx, r.db).ExecContext(ctx,
+		"INSERT INTO oauth_states (state, provider, code_verifier, nonce, link_user_id, expires_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		state.State, state.Provider, state.CodeVerifier, state.Nonce, linkUserID, state.ExpiresAt,
+	)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to insert oauth state : Provider="+state.Provider, err)
+	}
+	return nil
+}
+
+// 有効期限内のstateを取得して削除する(同じstateは1回しか使えない)
+func (r *OAuthStateRepository) Consume(ctx context.Context, stateValue string) (*models.OAuthState, error) {
+	var state models.OAuthState
+	var linkUserID sql.NullInt64
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		DELETE FROM oauth_states
+		WHERE state = $1 AND expires_at >= NOW()
+		RETURNING state, provider, code_verifier, nonce, link_user_id, expires_at
+	`, stateValue).Scan(&state.State, &state.Provider, &state.CodeVerifier, &state.Nonce, &linkUserID, &state.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, apperror.NewAppError(apperror.TypeNotFound, "OAuth state not found or expired", err)
+	} else if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, "Database error : OAuth state", err)
+	}
+	state.LinkUserID = int(linkUserID.Int64)
+	return &state, nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// アウトボックス用のリポジトリ
+type OutboxRepository struct {
+	db DBExecutor
+}
+
+// アウトボックス用リポジトリのインスタンスを生成
+func NewOutboxRepository(db DBExecutor) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// ドメインイベントをアウトボックスに書き込む(変更と同じトランザクションの中で呼ぶ)
+func insertOutboxEvent(ctx context.Context, db DBExecutor, eventType string, aggregateID int, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to encode outbox event : Event="+eventType, err)
+	}
+	_, err = executor(ctx, db).ExecContext(ctx, "INSERT INTO outbox_events (event_type, aggregate_id, payload) VALUES ($1, $2, $3)", eventType, aggregateID, string(body))
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to insert outbox event : Event="+eventType, err)
+	}
+	return nil
+}
+
+// 配信待ちのイベントを古い順に取得し、他のインスタンスで重複して処理しないように lease の間は対象外にする
+func (r *OutboxRepository) ClaimPending(ctx context.Context, lease time.Duration, limit int) ([]models.OutboxEvent, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, `
+		WITH claimed AS (
+			UPDATE outbox_events
+			SET next_attempt_at = NOW() + make_interval(secs => $1)
+			WHERE id IN (
+				SELECT id FROM outbox_events
+				WHERE published_at IS NULL AND failed_at IS NULL AND next_attempt_at <= NOW()
+				ORDER BY id ASC
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, event_type, aggregate_id, payload, attempts, created_at
+		)
+		SELECT id, event_type, aggregate_id, payload, attempts, created_at FROM claimed ORDER BY id ASC
+	`, lease.Seconds(), limit)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to claim outbox events", err)
+	}
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	for rows.Next() {
+		var event models.OutboxEvent
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.Type, &event.AggregateID, &payload, &event.Attempts, &event.CreatedAt); err != nil {
+			return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to parse outbox event", err)
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to claim outbox events", err)
+	}
+	return events, nil
+}
+
+// 指定したイベントを処理済みの後続処理の名前を取得する
+func (r *OutboxRepository) ListConsumers(ctx context.Context, eventID int64) (map[string]bool, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, "SELECT consumer FROM outbox_consumptions WHERE event_id = $1", eventID)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch outbox consumptions : EventID=%d", eventID), err)
+	}
+	defer rows.Close()
+
+	consumers := map[string]bool{}
+	for rows.Next() {
+		var consumer string
+		if err := rows.Scan(&consumer); err != nil {
+			return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to parse outbox consumption : EventID=%d", eventID), err)
+		}
+		consumers[consumer] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch outbox consumptions : EventID=%d", eventID), err)
+	}
+	return consumers, nil
+}
+
+// 後続処理がイベントを処理したことを記録する
+func (r *OutboxRepository) MarkConsumed(ctx context.Context, consumer string, eventID int64) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx, "INSERT INTO outbox_consumptions (consumer, event_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", consumer, eventID)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to insert outbox consumption : EventID=%d", eventID), err)
+	}
+	return nil
+}
+
+// すべての後続処理が終わったイベントを配信済みにする
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id int64) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx, "UPDATE outbox_events SET published_at = CURRENT_TIMESTAMP, attempts = attempts + 1, last_error = NULL WHERE id = $1", id)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to update outbox event : EventID=%d", id), err)
+	}
+	return nil
+}
+
+// 後続処理の失敗を記録する(nextAttemptAt がnilの場合は再送を諦めて failed_at を設定する)
+func (r *OutboxRepository) RecordFailure(ctx context.Context, id int64, errMsg string, nextAttemptAt *time.Time) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE outbox_events
+		SET attempts = attempts + 1, last_error = $2, next_attempt_at = COALESCE($3, next_attempt_at),
+			failed_at = CASE WHEN $3::timestamp IS NULL THEN CURRENT_TIMESTAMP ELSE NULL END
+		WHERE id = $1
+	`, id, errMsg, nextAttemptAt)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to update outbox event : EventID=%d", id), err)
+	}
+	return nil
+}
+
+// 指定した日時より前に配信済みになったイベントを削除する(処理済みの記録もCASCADEで削除される)
+func (r *OutboxRepository) DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := executor(ctx, r.db).ExecContext(ctx, "DELETE FROM outbox_events WHERE published_at < $1", before)
+	if err != nil {
+		return 0, apperror.NewAppError(apperror.TypeInternalServer, "Failed to delete outbox events", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, apperror.NewAppError(apperror.TypeInternalServer, "Failed to confirm operation", err)
+	}
+	return count, nil
+}
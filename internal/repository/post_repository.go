@@ -3,7 +3,6 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 
 	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
@@ -12,20 +11,21 @@ import (
 
 // 投稿用のリポジトリ
 type PostRepository struct {
-	db *sql.DB
+	db DBExecutor
 }
 
 // 投稿用リポジトリのインスタンスを生成
-func NewPostRepository(db *sql.DB) *PostRepository {
+func NewPostRepository(db DBExecutor) *PostRepository {
 	return &PostRepository{db: db}
 }
 
 // 指定したIDから投稿を見つける(存在しない場合はnilを返したいのでポインタを返す)
+// リードレプリカが設定されている場合はレプリカから読み取る
 func (r *PostRepository) FindByID(ctx context.Context, id int) (*models.Post, error) {
 	var post models.Post
-	err := r.db.QueryRowContext(ctx, "SELECT id, title, content, user_id, created_at FROM posts WHERE id = $1", id).Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.CreatedAt)
+	err := readExecutor(ctx, r.db).QueryRowContext(ctx, "SELECT id, title, content, user_id, created_at FROM posts WHERE id = $1", id).Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.CreatedAt)
 	if err == sql.ErrNoRows {
-		return nil, apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Post not found : PostID=%d", id), err)
+		return nil, apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Post not found : PostID=%d", id), err).WithSubCode(apperror.CodePostNotFound)
 	} else if err != nil {
 		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Database error : PostID=%d", id), err)
 	}
@@ -34,18 +34,44 @@ func (r *PostRepository) FindByID(ctx context.Context, id int) (*models.Post, er
 
 // 指定したUserIDから投稿を見つける
 func (r *PostRepository) ListByUserID(ctx context.Context, userID int) ([]models.Post, error) {
-	return r.listPosts(ctx, "SELECT id, title, content, user_id, created_at FROM posts WHERE user_id = $1", userID)
+	return r.listPosts(ctx, executor(ctx, r.db), "SELECT id, title, content, user_id, created_at FROM posts WHERE user_id = $1", userID)
 }
 
-// 全ての投稿を見つける
+// 全ての投稿を見つける(リードレプリカが設定されている場合はレプリカから読み取る)
 func (r *PostRepository) ListAll(ctx context.Context) ([]models.Post, error) {
-	return r.listPosts(ctx, "SELECT id, title, content, user_id, created_at FROM posts ORDER BY created_at DESC")
+	return r.listPosts(ctx, readExecutor(ctx, r.db), "SELECT id, title, content, user_id, created_at FROM posts ORDER BY created_at DESC")
+}
+
+// フォロー中のユーザーの投稿を新しい順に取得する(カーソルより古いものを取得する)
+// フォロー数が多くても投稿者ごとにインデックスから最大limit件だけ読むようにLATERALで絞り込む
+func (r *PostRepository) ListFeed(ctx context.Context, userID int, cursor *models.PageCursor, limit int) ([]models.Post, error) {
+	condition := ""
+	args := []any{userID, limit}
+	if cursor != nil {
+		condition = "AND (p.created_at, p.id) < ($3, $4)"
+		args = append(args, cursor.CreatedAt, cursor.ID)
+	}
+	query := fmt.Sprintf(`
+		SELECT p.id, p.title, p.content, p.user_id, p.created_at
+		FROM follows f
+		CROSS JOIN LATERAL (
+			SELECT p.id, p.title, p.content, p.user_id, p.created_at
+			FROM posts p
+			WHERE p.user_id = f.followee_id %s
+			ORDER BY p.created_at DESC, p.id DESC
+			LIMIT $2
+		) p
+		WHERE f.follower_id = $1
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $2
+	`, condition)
+	return r.listPosts(ctx, executor(ctx, r.db), query, args...)
 }
 
 // 指定したクエリを実行して投稿を見つける
-func (r *PostRepository) listPosts(ctx context.Context, query string, args ...any) ([]models.Post, error) {
+func (r *PostRepository) listPosts(ctx context.Context, db DBExecutor, query string, args ...any) ([]models.Post, error) {
 	// 指定されたクエリを実行する
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to fetch posts", err)
 	}
@@ -71,39 +97,28 @@ func (r *PostRepository) listPosts(ctx context.Context, query string, args ...an
 
 // 新しい投稿を作成する
 func (r *PostRepository) Create(ctx context.Context, post *models.Post) error {
-	// トランザクションを開始する
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to start transaction", err)
-	}
-	defer func() {
-		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
-			fmt.Printf("Failed to rollback transaction: %v\n", err)
+	return withTx(ctx, r.db, func(ctx context.Context) error {
+		// 投稿 INSERT実行
+		err := executor(ctx, r.db).QueryRowContext(ctx, "INSERT INTO posts (title, content, user_id) VALUES ($1, $2, $3) RETURNING id, created_at", post.Title, post.Content, post.UserID).Scan(&post.ID, &post.CreatedAt)
+		if err != nil {
+			return apperror.NewAppError(apperror.TypeInternalServer, "Failed to insert post", err)
 		}
-	}()
-	// 投稿 INSERT実行
-	err = tx.QueryRowContext(ctx, "INSERT INTO posts (title, content, user_id) VALUES ($1, $2, $3) RETURNING id", post.Title, post.Content, post.UserID).Scan(&post.ID)
-	if err != nil {
-		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to insert post", err)
-	}
-	// 投稿統計 INSERT実行
-	_, err = tx.ExecContext(ctx, "INSERT INTO post_stats (post_id) VALUES ($1)", post.ID)
-	if err != nil {
-		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to insert post stats", err)
-	}
-	// トランザクションをコミットする
-	if err := tx.Commit(); err != nil {
-		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to commit transaction", err)
-	}
-	return nil
+		// 投稿統計 INSERT実行
+		_, err = executor(ctx, r.db).ExecContext(ctx, "INSERT INTO post_stats (post_id) VALUES ($1)", post.ID)
+		if err != nil {
+			return apperror.NewAppError(apperror.TypeInternalServer, "Failed to insert post stats", err)
+		}
+		// 投稿の作成イベントをアウトボックスに書き込む
+		return insertOutboxEvent(ctx, r.db, models.DomainEventPostCreated, post.ID, post)
+	})
 }
 
 // 指定した投稿のIDからユーザーIDを見つける
 func (r *PostRepository) FindUserIDByPostID(ctx context.Context, postID int) (int, error) {
 	var userID int
-	err := r.db.QueryRowContext(ctx, "SELECT user_id FROM posts WHERE id = $1", postID).Scan(&userID)
+	err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT user_id FROM posts WHERE id = $1", postID).Scan(&userID)
 	if err == sql.ErrNoRows {
-		return 0, apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Post not found : PostID=%d", postID), err)
+		return 0, apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Post not found : PostID=%d", postID), err).WithSubCode(apperror.CodePostNotFound)
 	} else if err != nil {
 		return 0, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Database error : PostID=%d", postID), err)
 	}
@@ -112,33 +127,43 @@ func (r *PostRepository) FindUserIDByPostID(ctx context.Context, postID int) (in
 
 // 指定したIDの投稿を更新する
 func (r *PostRepository) Update(ctx context.Context, id int, post *models.Post) error {
-	// UPDATE実行
-	result, err := r.db.ExecContext(ctx, "UPDATE posts SET title = $1, content = $2 WHERE id = $3", post.Title, post.Content, id)
-	if err != nil {
-		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to update post", err)
-	}
-	// 更新行数の確認
-	return checkRowAffected(result, fmt.Sprintf("Post not found : PostID=%d", id))
+	return withTx(ctx, r.db, func(ctx context.Context) error {
+		// UPDATE実行(アウトボックスに書き込むために更新後の投稿を取得する)
+		updated := models.Post{ID: id, Title: post.Title, Content: post.Content}
+		err := executor(ctx, r.db).QueryRowContext(ctx, "UPDATE posts SET title = $1, content = $2 WHERE id = $3 RETURNING user_id, created_at", post.Title, post.Content, id).Scan(&updated.UserID, &updated.CreatedAt)
+		if err == sql.ErrNoRows {
+			return apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Post not found : PostID=%d", id), err).WithSubCode(apperror.CodePostNotFound)
+		} else if err != nil {
+			return apperror.NewAppError(apperror.TypeInternalServer, "Failed to update post", err)
+		}
+		// 投稿の更新イベントをアウトボックスに書き込む
+		return insertOutboxEvent(ctx, r.db, models.DomainEventPostUpdated, id, updated)
+	})
 }
 
 // 指定したIDの投稿を削除する
 func (r *PostRepository) Delete(ctx context.Context, id int) error {
-	// DELETE実行
-	result, err := r.db.ExecContext(ctx, "DELETE FROM posts WHERE id = $1", id)
-	if err != nil {
-		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to delete post", err)
-	}
-	// 削除行数の確認
-	return checkRowAffected(result, fmt.Sprintf("Post not found : PostID=%d", id))
+	return withTx(ctx, r.db, func(ctx context.Context) error {
+		// DELETE実行
+		deleted := models.PostDeletedEvent{ID: id}
+		err := executor(ctx, r.db).QueryRowContext(ctx, "DELETE FROM posts WHERE id = $1 RETURNING user_id", id).Scan(&deleted.UserID)
+		if err == sql.ErrNoRows {
+			return apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Post not found : PostID=%d", id), err).WithSubCode(apperror.CodePostNotFound)
+		} else if err != nil {
+			return apperror.NewAppError(apperror.TypeInternalServer, "Failed to delete post", err)
+		}
+		// 投稿の削除イベントをアウトボックスに書き込む
+		return insertOutboxEvent(ctx, r.db, models.DomainEventPostDeleted, id, deleted)
+	})
 }
 
-// SQLの実行結果から影響を受けた行数を確認する関数
-func checkRowAffected(result sql.Result, notFoundMessage string) error {
+// SQLの実行結果から影響を受けた行数を確認する関数(0件の場合は subCode を付けた NotFound を返す)
+func checkRowAffected(result sql.Result, notFoundMessage string, subCode string) error {
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to confirm operation", err)
 	} else if rowsAffected == 0 {
-		return apperror.NewAppError(apperror.TypeNotFound, notFoundMessage, nil)
+		return apperror.NewAppError(apperror.TypeNotFound, notFoundMessage, nil).WithSubCode(subCode)
 	}
 	return nil
 }
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 読み取り専用のクエリをリードレプリカに振り分けるDB
+// 書き込み・トランザクション・振り分け対象外のクエリはプライマリで実行し、readExecutor を使うリポジトリのメソッドだけをレプリカで実行する
+// 正常なレプリカが無い場合と、直前に書き込んだユーザーのリクエスト(レプリカの遅延で書き込みが見えない可能性がある)はプライマリで実行する
+type ReplicaRouter struct {
+	primary        *sql.DB
+	replicas       []*replica
+	next           atomic.Uint64
+	stickyKey      func(ctx context.Context) (int, bool) // 書き込んだユーザーを識別する(認証済みのユーザーID)
+	stickyWindow   time.Duration
+	healthInterval time.Duration
+
+	mu          sync.Mutex
+	lastWriteAt map[int]time.Time // ユーザーごとの最後の書き込み時刻
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// リードレプリカの接続と状態
+type replica struct {
+	name    string // ログに出す名前(接続文字列はパスワードを含むため使わない)
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// 書き込みの直後にプライマリで読み取る期間を管理するユーザー数の目安(超えたら期限切れの記録を削除する)
+const replicaStickyPruneSize = 1024
+
+// レプリカの正常性を確認するPingのタイムアウト
+const replicaPingTimeout = 2 * time.Second
+
+// リードレプリカに振り分けるDBを作成する
+// stickyKey でユーザーを識別できるリクエストでは、そのユーザーが書き込んでから stickyWindow の間はプライマリから読み取る
+func NewReplicaRouter(primary *sql.DB, replicas []*sql.DB, stickyKey func(ctx context.Context) (int, bool), stickyWindow time.Duration, healthInterval time.Duration) *ReplicaRouter {
+	router := &ReplicaRouter{
+		primary:        primary,
+		stickyKey:      stickyKey,
+		stickyWindow:   stickyWindow,
+		healthInterval: healthInterval,
+		lastWriteAt:    map[int]time.Time{},
+		stop:           make(chan struct{}),
+	}
+	for i, db := range replicas {
+		r := &replica{name: fmt.Sprintf("replica-%d", i+1), db: db}
+		r.healthy.Store(true)
+		router.replicas = append(router.replicas, r)
+	}
+	return router
+}
+
+// レプリカの正常性の確認を開始する
+// 起動時に一度確認し、以降は healthInterval ごとに確認する(Pingに失敗したレプリカには振り分けない)
+func (r *ReplicaRouter) Start() {
+	if len(r.replicas) == 0 {
+		return
+	}
+	r.checkHealth()
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.healthInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.checkHealth()
+			}
+		}
+	}()
+}
+
+// 正常性の確認を停止してレプリカの接続を閉じる(プライマリの接続は呼び出し元で閉じる)
+func (r *ReplicaRouter) Close() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+		r.wg.Wait()
+		for _, replica := range r.replicas {
+			if err := replica.db.Close(); err != nil {
+				log.Printf("failed to close %s: %v", replica.name, err)
+			}
+		}
+	})
+}
+
+// すべてのレプリカにPingを送って状態を更新する(状態が変わった場合はログに出す)
+func (r *ReplicaRouter) checkHealth() {
+	for _, replica := range r.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), replicaPingTimeout)
+		err := replica.db.PingContext(ctx)
+		cancel()
+		healthy := err == nil
+		if replica.healthy.Swap(healthy) != healthy {
+			if healthy {
+				log.Printf("%s is healthy again", replica.name)
+			} else {
+				log.Printf("%s is unhealthy, reading from primary: %v", replica.name, err)
+			}
+		}
+	}
+}
+
+// プライマリで書き込む
+func (r *ReplicaRouter) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	r.markWrite(ctx)
+	return r.primary.ExecContext(ctx, query, args...)
+}
+
+// プライマリで実行する(振り分け対象外の読み取りと INSERT ... RETURNING など)
+// INSERT ... RETURNING などの書き込みの場合は ExecContext と同じく書き込み時刻を記録する
+func (r *ReplicaRouter) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if isWriteQuery(query) {
+		r.markWrite(ctx)
+	}
+	return r.primary.QueryContext(ctx, query, args...)
+}
+
+// プライマリで実行する(振り分け対象外の読み取りと INSERT ... RETURNING など)
+// INSERT ... RETURNING などの書き込みの場合は ExecContext と同じく書き込み時刻を記録する
+func (r *ReplicaRouter) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if isWriteQuery(query) {
+		r.markWrite(ctx)
+	}
+	return r.primary.QueryRowContext(ctx, query, args...)
+}
+
+// プライマリでトランザクションを開始する(トランザクション内の書き込みの後の読み取りもプライマリで行う)
+func (r *ReplicaRouter) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	r.markWrite(ctx)
+	return r.primary.BeginTx(ctx, opts)
+}
+
+// 読み取りに使うDBを返す
+// 直前に書き込んだユーザーと正常なレプリカが無い場合はプライマリを返し、それ以外はレプリカを順番に返す
+func (r *ReplicaRouter) reader(ctx context.Context) DBExecutor {
+	if len(r.replicas) == 0 || r.recentlyWrote(ctx) {
+		return r.primary
+	}
+	start := r.next.Add(1)
+	for i := range r.replicas {
+		replica := r.replicas[(start+uint64(i))%uint64(len(r.replicas))]
+		if replica.healthy.Load() {
+			return replica.db
+		}
+	}
+	return r.primary
+}
+
+// 書き込みを含むSQLか判定する(WITH 句の中の書き込みも対象にする)
+// SELECT ... FOR UPDATE も書き込みとみなすが、プライマリから読み取る期間が延びるだけで整合性には影響しない
+var writeQueryPattern = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE)\b`)
+
+func isWriteQuery(query string) bool {
+	return writeQueryPattern.MatchString(query)
+}
+
+// リクエストのユーザーの書き込み時刻を記録する
+func (r *ReplicaRouter) markWrite(ctx context.Context) {
+	if r.stickyKey == nil {
+		return
+	}
+	userID, ok := r.stickyKey(ctx)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastWriteAt[userID] = now
+	if len(r.lastWriteAt) > replicaStickyPruneSize {
+		for id, at := range r.lastWriteAt {
+			if now.Sub(at) > r.stickyWindow {
+				delete(r.lastWriteAt, id)
+			}
+		}
+	}
+}
+
+// リクエストのユーザーが stickyWindow 以内に書き込んだか判定する
+func (r *ReplicaRouter) recentlyWrote(ctx context.Context) bool {
+	if r.stickyKey == nil {
+		return false
+	}
+	userID, ok := r.stickyKey(ctx)
+	if !ok {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	at, ok := r.lastWriteAt[userID]
+	return ok && time.Since(at) <= r.stickyWindow
+}
@@ -0,0 +1,460 @@
+// Package repotest はリポジトリのインターフェースの契約テストを提供する。
+// PostgreSQLの実装とインメモリの実装の両方に同じテストを実行し、振る舞いとAppErrorの種別が一致することを確認する。
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository"
+)
+
+// 契約テストの対象となるリポジトリの組み合わせ(全てのリポジトリが同じデータを共有している必要がある)
+type Backend struct {
+	Users    repository.UserStore
+	Posts    repository.PostStore
+	Comments repository.CommentStore
+	Likes    repository.LikeStore
+	Follows  repository.FollowStore
+}
+
+// 存在しないIDとして使う値
+const missingID = 999999
+
+// 全ての契約テストを実行する(newBackendはサブテストごとに空の状態で呼び出される)
+// 既存のデータが残っているバックエンドでも通るように、テスト内で作成したデータのみを確認する
+func Run(t *testing.T, newBackend func(t *testing.T) Backend) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, newBackend(t)) })
+	t.Run("Posts", func(t *testing.T) { testPosts(t, newBackend(t)) })
+	t.Run("Feed", func(t *testing.T) { testFeed(t, newBackend(t)) })
+	t.Run("Comments", func(t *testing.T) { testComments(t, newBackend(t)) })
+	t.Run("Likes", func(t *testing.T) { testLikes(t, newBackend(t)) })
+	t.Run("Follows", func(t *testing.T) { testFollows(t, newBackend(t)) })
+	t.Run("DeletePostCascades", func(t *testing.T) { testDeletePostCascades(t, newBackend(t)) })
+}
+
+// ユーザーの作成・重複・取得を確認する
+func testUsers(t *testing.T, b Backend) {
+	ctx := context.Background()
+
+	id := createUser(t, b, "contract_alice")
+	_, err := b.Users.Create(ctx, "contract_alice", "other")
+	assertErrorType(t, err, apperror.TypeConflict)
+
+	// 使用済みのユーザー名はエラーにせずfalseを返す
+	if _, created, err := b.Users.CreateIfAvailable(ctx, "contract_alice", "other"); err != nil || created {
+		t.Errorf("使用済みのユーザー名で作成された: created=%v, err=%v", created, err)
+	}
+	otherID, created, err := b.Users.CreateIfAvailable(ctx, "contract_bob", "hashed")
+	if err != nil || !created || otherID == id {
+		t.Errorf("未使用のユーザー名で作成されない: id=%d, created=%v, err=%v", otherID, created, err)
+	}
+
+	gotID, hashed, err := b.Users.FindAuthByUsername(ctx, "contract_alice")
+	if err != nil || gotID != id || hashed != "hashed" {
+		t.Errorf("認証情報が一致しない: id=%d, hashed=%q, err=%v", gotID, hashed, err)
+	}
+	_, _, err = b.Users.FindAuthByUsername(ctx, "contract_nobody")
+	assertErrorType(t, err, apperror.TypeUnauthorized)
+
+	username, err := b.Users.FindUsernameByID(ctx, id)
+	if err != nil || username != "contract_alice" {
+		t.Errorf("ユーザー名が一致しない: username=%q, err=%v", username, err)
+	}
+	_, err = b.Users.FindUsernameByID(ctx, missingID)
+	assertErrorType(t, err, apperror.TypeNotFound)
+
+	// 存在しないユーザーはエラーにせず有効でないとする
+	if active, err := b.Users.IsActive(ctx, id); err != nil || !active {
+		t.Errorf("作成したユーザーが有効でない: active=%v, err=%v", active, err)
+	}
+	if active, err := b.Users.IsActive(ctx, missingID); err != nil || active {
+		t.Errorf("存在しないユーザーが有効: active=%v, err=%v", active, err)
+	}
+}
+
+// 投稿の作成・取得・更新・削除を確認する
+func testPosts(t *testing.T, b Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "contract_author")
+
+	first := createPost(t, b, userID, "1件目")
+	second := createPost(t, b, userID, "2件目")
+	if first.ID == 0 || first.CreatedAt.IsZero() || second.ID == first.ID {
+		t.Fatalf("採番されたIDと作成日時が不正: %+v, %+v", first, second)
+	}
+
+	found, err := b.Posts.FindByID(ctx, first.ID)
+	if err != nil || found.Title != "1件目" || found.UserID != userID || !found.CreatedAt.Equal(first.CreatedAt) {
+		t.Errorf("取得した投稿が一致しない: %+v, err=%v", found, err)
+	}
+	_, err = b.Posts.FindByID(ctx, missingID)
+	assertErrorType(t, err, apperror.TypeNotFound)
+
+	ownerID, err := b.Posts.FindUserIDByPostID(ctx, second.ID)
+	if err != nil || ownerID != userID {
+		t.Errorf("投稿者が一致しない: userID=%d, err=%v", ownerID, err)
+	}
+	_, err = b.Posts.FindUserIDByPostID(ctx, missingID)
+	assertErrorType(t, err, apperror.TypeNotFound)
+
+	posts, err := b.Posts.ListByUserID(ctx, userID)
+	if err != nil {
+		t.Fatal("ユーザーの投稿の取得失敗:", err)
+	}
+	assertSameIDs(t, postIDs(posts), []int{first.ID, second.ID})
+
+	// 全件は新しい順に並ぶ
+	all, err := b.Posts.ListAll(ctx)
+	if err != nil {
+		t.Fatal("全投稿の取得失敗:", err)
+	}
+	if index(postIDs(all), second.ID) > index(postIDs(all), first.ID) {
+		t.Errorf("全投稿が新しい順に並んでいない: %v", postIDs(all))
+	}
+
+	// 更新はタイトルと本文のみ変更する
+	if err := b.Posts.Update(ctx, first.ID, &models.Post{Title: "更新", Content: "更新後"}); err != nil {
+		t.Fatal("投稿の更新失敗:", err)
+	}
+	updated, err := b.Posts.FindByID(ctx, first.ID)
+	if err != nil || updated.Title != "更新" || updated.Content != "更新後" || updated.UserID != userID {
+		t.Errorf("更新後の投稿が一致しない: %+v, err=%v", updated, err)
+	}
+	assertErrorType(t, b.Posts.Update(ctx, missingID, &models.Post{Title: "更新", Content: "更新後"}), apperror.TypeNotFound)
+
+	if err := b.Posts.Delete(ctx, first.ID); err != nil {
+		t.Fatal("投稿の削除失敗:", err)
+	}
+	_, err = b.Posts.FindByID(ctx, first.ID)
+	assertErrorType(t, err, apperror.TypeNotFound)
+	assertErrorType(t, b.Posts.Delete(ctx, first.ID), apperror.TypeNotFound)
+}
+
+// フォロー中のユーザーの投稿のみが新しい順にカーソルで取得できることを確認する
+func testFeed(t *testing.T, b Backend) {
+	ctx := context.Background()
+	readerID := createUser(t, b, "contract_reader")
+	followeeID := createUser(t, b, "contract_followee")
+	strangerID := createUser(t, b, "contract_stranger")
+	if err := b.Follows.Create(ctx, readerID, followeeID); err != nil {
+		t.Fatal("フォロー失敗:", err)
+	}
+
+	var want []int
+	for _, title := range []string{"1件目", "2件目", "3件目"} {
+		want = append([]int{createPost(t, b, followeeID, title).ID}, want...)
+	}
+	createPost(t, b, strangerID, "フォローしていない")
+	createPost(t, b, readerID, "自分の投稿")
+
+	page, err := b.Posts.ListFeed(ctx, readerID, nil, 2)
+	if err != nil {
+		t.Fatal("フィードの取得失敗:", err)
+	}
+	assertIDs(t, postIDs(page), want[:2])
+
+	last := page[len(page)-1]
+	page, err = b.Posts.ListFeed(ctx, readerID, &models.PageCursor{CreatedAt: last.CreatedAt, ID: last.ID}, 2)
+	if err != nil {
+		t.Fatal("フィードの取得失敗:", err)
+	}
+	assertIDs(t, postIDs(page), want[2:])
+
+	// フォローしていないユーザーのフィードは空
+	page, err = b.Posts.ListFeed(ctx, strangerID, nil, 10)
+	if err != nil || len(page) != 0 {
+		t.Errorf("フォローしていないのにフィードがある: %v, err=%v", postIDs(page), err)
+	}
+}
+
+// コメントの作成・取得・更新・削除を確認する
+func testComments(t *testing.T, b Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "contract_commenter")
+	post := createPost(t, b, userID, "コメント用")
+
+	first := &models.Comment{PostID: post.ID, UserID: userID, Content: "1件目"}
+	second := &models.Comment{PostID: post.ID, UserID: userID, Content: "2件目"}
+	for _, c := range []*models.Comment{first, second} {
+		if err := b.Comments.Create(ctx, c); err != nil {
+			t.Fatal("コメントの作成失敗:", err)
+		}
+	}
+	if first.ID == 0 || first.CreatedAt.IsZero() {
+		t.Fatalf("採番されたIDと作成日時が不正: %+v", first)
+	}
+
+	// 存在しない投稿へのコメントは外部キー違反として扱う
+	assertErrorType(t, b.Comments.Create(ctx, &models.Comment{PostID: missingID, UserID: userID, Content: "x"}), apperror.TypeInternalServer)
+
+	comments, err := b.Comments.ListByPostID(ctx, post.ID)
+	if err != nil {
+		t.Fatal("コメントの取得失敗:", err)
+	}
+	assertIDs(t, commentIDs(comments), []int{first.ID, second.ID})
+	comments, err = b.Comments.ListByUserID(ctx, userID)
+	if err != nil {
+		t.Fatal("コメントの取得失敗:", err)
+	}
+	assertIDs(t, commentIDs(comments), []int{first.ID, second.ID})
+
+	found, err := b.Comments.FindByID(ctx, first.ID)
+	if err != nil || found.Content != "1件目" || found.PostID != post.ID {
+		t.Errorf("取得したコメントが一致しない: %+v, err=%v", found, err)
+	}
+	_, err = b.Comments.FindByID(ctx, missingID)
+	assertErrorType(t, err, apperror.TypeNotFound)
+
+	ownerID, postID, err := b.Comments.FindOwnerByID(ctx, second.ID)
+	if err != nil || ownerID != userID || postID != post.ID {
+		t.Errorf("コメントの所有者が一致しない: userID=%d, postID=%d, err=%v", ownerID, postID, err)
+	}
+	_, _, err = b.Comments.FindOwnerByID(ctx, missingID)
+	assertErrorType(t, err, apperror.TypeNotFound)
+
+	if err := b.Comments.Update(ctx, first.ID, "更新後"); err != nil {
+		t.Fatal("コメントの更新失敗:", err)
+	}
+	if found, err := b.Comments.FindByID(ctx, first.ID); err != nil || found.Content != "更新後" {
+		t.Errorf("更新後のコメントが一致しない: %+v, err=%v", found, err)
+	}
+	assertErrorType(t, b.Comments.Update(ctx, missingID, "更新後"), apperror.TypeNotFound)
+
+	if err := b.Comments.Delete(ctx, first.ID); err != nil {
+		t.Fatal("コメントの削除失敗:", err)
+	}
+	assertErrorType(t, b.Comments.Delete(ctx, first.ID), apperror.TypeNotFound)
+}
+
+// いいねの追加・削除・集計を確認する
+func testLikes(t *testing.T, b Backend) {
+	ctx := context.Background()
+	authorID := createUser(t, b, "contract_liked")
+	likerID := createUser(t, b, "contract_liker")
+	post := createPost(t, b, authorID, "いいね用")
+
+	// いいね済みの場合は何もしない
+	for i := 0; i < 2; i++ {
+		if err := b.Likes.Create(ctx, likerID, post.ID); err != nil {
+			t.Fatal("いいね失敗:", err)
+		}
+	}
+	if err := b.Likes.Create(ctx, authorID, post.ID); err != nil {
+		t.Fatal("いいね失敗:", err)
+	}
+	assertErrorType(t, b.Likes.Create(ctx, likerID, missingID), apperror.TypeInternalServer)
+
+	count, err := b.Likes.CountByPostID(ctx, post.ID)
+	if err != nil || count != 2 {
+		t.Errorf("いいね数が一致しない: count=%d, err=%v", count, err)
+	}
+	userIDs, err := b.Likes.ListUserIDsByPostID(ctx, post.ID)
+	if err != nil {
+		t.Fatal("いいねの取得失敗:", err)
+	}
+	assertSameIDs(t, userIDs, []int{likerID, authorID})
+
+	likes, err := b.Likes.ListByUserID(ctx, likerID)
+	if err != nil || len(likes) != 1 || likes[0].PostID != post.ID || likes[0].ID == 0 {
+		t.Errorf("ユーザーのいいねが一致しない: %+v, err=%v", likes, err)
+	}
+
+	// いいねしていない場合の削除もエラーにしない
+	for i := 0; i < 2; i++ {
+		if err := b.Likes.Delete(ctx, likerID, post.ID); err != nil {
+			t.Fatal("いいねの削除失敗:", err)
+		}
+	}
+	if count, err := b.Likes.CountByPostID(ctx, post.ID); err != nil || count != 1 {
+		t.Errorf("削除後のいいね数が一致しない: count=%d, err=%v", count, err)
+	}
+}
+
+// フォローの追加・削除・一覧を確認する
+func testFollows(t *testing.T, b Backend) {
+	ctx := context.Background()
+	targetID := createUser(t, b, "contract_target")
+	var followerIDs []int
+	for _, name := range []string{"contract_f1", "contract_f2", "contract_f3"} {
+		id := createUser(t, b, name)
+		if err := b.Follows.Create(ctx, id, targetID); err != nil {
+			t.Fatal("フォロー失敗:", err)
+		}
+		followerIDs = append(followerIDs, id)
+	}
+	// フォロー済みの場合は何もしない
+	if err := b.Follows.Create(ctx, followerIDs[0], targetID); err != nil {
+		t.Fatal("フォロー済みのフォローでエラー:", err)
+	}
+	assertErrorType(t, b.Follows.Create(ctx, targetID, missingID), apperror.TypeNotFound)
+	assertErrorType(t, b.Follows.Create(ctx, targetID, targetID), apperror.TypeInternalServer)
+
+	// フォロワー一覧は新しい順にカーソルで取得する
+	page, err := b.Follows.ListFollowers(ctx, targetID, nil, 2)
+	if err != nil {
+		t.Fatal("フォロワーの取得失敗:", err)
+	}
+	assertIDs(t, followUserIDs(page), []int{followerIDs[2], followerIDs[1]})
+	if page[0].Username != "contract_f3" || page[0].FollowedAt.IsZero() {
+		t.Errorf("フォロワーの情報が一致しない: %+v", page[0])
+	}
+	last := page[len(page)-1]
+	page, err = b.Follows.ListFollowers(ctx, targetID, &models.PageCursor{CreatedAt: last.FollowedAt, ID: last.ID}, 2)
+	if err != nil {
+		t.Fatal("フォロワーの取得失敗:", err)
+	}
+	assertIDs(t, followUserIDs(page), []int{followerIDs[0]})
+
+	following, err := b.Follows.ListFollowing(ctx, followerIDs[0], nil, 10)
+	if err != nil {
+		t.Fatal("フォロー中の取得失敗:", err)
+	}
+	assertIDs(t, followUserIDs(following), []int{targetID})
+
+	ids, err := b.Follows.ListFollowerIDs(ctx, targetID)
+	if err != nil {
+		t.Fatal("フォロワーIDの取得失敗:", err)
+	}
+	assertSameIDs(t, ids, followerIDs)
+	ids, err = b.Follows.ListFolloweeIDs(ctx, followerIDs[1])
+	if err != nil {
+		t.Fatal("フォロー中IDの取得失敗:", err)
+	}
+	assertSameIDs(t, ids, []int{targetID})
+
+	// フォローしていない場合の解除もエラーにしない
+	for i := 0; i < 2; i++ {
+		if err := b.Follows.Delete(ctx, followerIDs[1], targetID); err != nil {
+			t.Fatal("フォロー解除失敗:", err)
+		}
+	}
+	ids, err = b.Follows.ListFollowerIDs(ctx, targetID)
+	if err != nil {
+		t.Fatal("フォロワーIDの取得失敗:", err)
+	}
+	assertSameIDs(t, ids, []int{followerIDs[0], followerIDs[2]})
+}
+
+// 投稿を削除するとコメントといいねも削除されることを確認する
+func testDeletePostCascades(t *testing.T, b Backend) {
+	ctx := context.Background()
+	userID := createUser(t, b, "contract_cascade")
+	post := createPost(t, b, userID, "削除用")
+	comment := &models.Comment{PostID: post.ID, UserID: userID, Content: "消える"}
+	if err := b.Comments.Create(ctx, comment); err != nil {
+		t.Fatal("コメントの作成失敗:", err)
+	}
+	if err := b.Likes.Create(ctx, userID, post.ID); err != nil {
+		t.Fatal("いいね失敗:", err)
+	}
+
+	if err := b.Posts.Delete(ctx, post.ID); err != nil {
+		t.Fatal("投稿の削除失敗:", err)
+	}
+	_, err := b.Comments.FindByID(ctx, comment.ID)
+	assertErrorType(t, err, apperror.TypeNotFound)
+	if likes, err := b.Likes.ListByUserID(ctx, userID); err != nil || len(likes) != 0 {
+		t.Errorf("削除した投稿のいいねが残っている: %+v, err=%v", likes, err)
+	}
+}
+
+// ユーザーを作成する
+func createUser(t *testing.T, b Backend, username string) int {
+	t.Helper()
+	id, err := b.Users.Create(context.Background(), username, "hashed")
+	if err != nil {
+		t.Fatalf("ユーザーの作成失敗: %v", err)
+	}
+	return id
+}
+
+// 投稿を作成する
+func createPost(t *testing.T, b Backend, userID int, title string) *models.Post {
+	t.Helper()
+	post := &models.Post{Title: title, Content: title + "の本文", UserID: userID}
+	if err := b.Posts.Create(context.Background(), post); err != nil {
+		t.Fatalf("投稿の作成失敗: %v", err)
+	}
+	return post
+}
+
+// エラーが指定した種別のAppErrorであることを確認する
+func assertErrorType(t *testing.T, err error, want apperror.Type) {
+	t.Helper()
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) {
+		t.Errorf("期待するエラー種別 %s, 実際は %v", want, err)
+		return
+	}
+	if appErr.Type != want {
+		t.Errorf("期待するエラー種別 %s, 実際は %s (%v)", want, appErr.Type, err)
+	}
+}
+
+// IDの並び順まで一致することを確認する
+func assertIDs(t *testing.T, got []int, want []int) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("期待するID %v, 実際は %v", want, got)
+		return
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("期待するID %v, 実際は %v", want, got)
+			return
+		}
+	}
+}
+
+// 並び順を問わずIDが一致することを確認する
+func assertSameIDs(t *testing.T, got []int, want []int) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("期待するID %v, 実際は %v", want, got)
+		return
+	}
+	for _, id := range want {
+		if index(got, id) < 0 {
+			t.Errorf("期待するID %v, 実際は %v", want, got)
+			return
+		}
+	}
+}
+
+// スライス内の位置を返す(存在しない場合は-1)
+func index(ids []int, id int) int {
+	for i, v := range ids {
+		if v == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func postIDs(posts []models.Post) []int {
+	ids := []int{}
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	return ids
+}
+
+func commentIDs(comments []models.Comment) []int {
+	ids := []int{}
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
+	}
+	return ids
+}
+
+func followUserIDs(users []models.FollowUser) []int {
+	ids := []int{}
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
+)
+
+// コンテキストにトランザクションを保存するためのキー
+type txContextKey struct{}
+
+// txBeginner はトランザクションを開始できるDB(sql.DB)を表す。
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// 複数のリポジトリにまたがる処理を1つのトランザクションで実行するための構造体
+// トランザクションはコンテキストに保存し、各リポジトリは executor でコンテキストから取り出して使う
+type TxManager struct {
+	db DBExecutor
+}
+
+// トランザクションマネージャーのインスタンスを生成
+func NewTxManager(db DBExecutor) *TxManager {
+	return &TxManager{db: db}
+}
+
+// fn をトランザクション内で実行する(fn がエラーを返した場合はロールバックする)
+// fn に渡したコンテキストを使ったリポジトリの操作はすべて同じトランザクションで実行される
+// シリアライゼーション失敗・デッドロックの場合は fn を最初から実行し直すため、fn の中ではDB以外の副作用を起こさない
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withTx(ctx, m.db, fn)
+}
+
+// コンテキストにトランザクションがあればそれを、無ければ db を返す
+func executor(ctx context.Context, db DBExecutor) DBExecutor {
+	if tx, ok := ctx.Value(txContextKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// 読み取り専用のクエリに使うDBを返す
+// コンテキストにトランザクションがあればそれを、db が ReplicaRouter の場合はリードレプリカ(またはプライマリ)を返す
+func readExecutor(ctx context.Context, db DBExecutor) DBExecutor {
+	if tx, ok := ctx.Value(txContextKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	if router, ok := db.(*ReplicaRouter); ok {
+		return router.reader(ctx)
+	}
+	return db
+}
+
+// fn をトランザクション内で実行する
+// コンテキストに既にトランザクションがある場合(WithinTx の中から呼ばれた場合)はそのトランザクションに参加し、
+// 再試行は一番外側のトランザクションで行う
+func withTx(ctx context.Context, db DBExecutor, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txContextKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+	beginner, ok := db.(txBeginner)
+	if !ok {
+		// sql.Tx が渡された場合は呼び出し元のトランザクションの中でそのまま実行する
+		return fn(ctx)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := runTx(ctx, beginner, fn)
+		if err == nil || !isRetryableTxError(err) || attempt >= config.TxMaxAttempts {
+			return err
+		}
+		log.Printf("Retrying transaction : Attempt=%d : %v", attempt, err)
+
+		// 少し待ってから再試行する(コンテキストが終了した場合は中断する)
+		select {
+		case <-ctx.Done():
+			return apperror.NewAppError(apperror.TypeTimeout, "Transaction retry canceled", ctx.Err())
+		case <-time.After(time.Duration(attempt) * config.TxRetryDelay):
+		}
+	}
+}
+
+// トランザクションを1回実行する
+func runTx(ctx context.Context, beginner txBeginner, fn func(ctx context.Context) error) error {
+	// トランザクションを開始する
+	tx, err := beginner.BeginTx(ctx, nil)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to start transaction", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			fmt.Printf("Failed to rollback transaction: %v\n", err)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txContextKey{}, tx)); err != nil {
+		return err
+	}
+
+	// トランザクションをコミットする
+	if err := tx.Commit(); err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to commit transaction", err)
+	}
+	return nil
+}
+
+// 再試行すれば成功する可能性のあるエラーか判定する(40001: シリアライゼーション失敗, 40P01: デッドロック)
+func isRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && (pqErr.Code == "40001" || pqErr.Code == "40P01")
+}
@@ -0,0 +1,99 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository"
+	"github.com/yusuke-hoguro/BlogApi/testutils"
+)
+
+// WithinTx の中の複数のリポジトリの変更がまとめてロールバックされることを確認する
+func TestWithinTxRollsBackAllRepositories(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	txManager := repository.NewTxManager(db)
+	postRepo := repository.NewPostRepository(db)
+	likeRepo := repository.NewLikeRepository(db)
+
+	errAbort := errors.New("abort")
+	post := &models.Post{Title: "トランザクション", Content: "ロールバックされる投稿", UserID: 1}
+	err := txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := postRepo.Create(ctx, post); err != nil {
+			return err
+		}
+		if err := likeRepo.Create(ctx, 2, post.ID); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("期待するエラー %v, 実際は %v", errAbort, err)
+	}
+
+	// 投稿・いいね・アウトボックスのイベントのいずれも残らない
+	if _, err := postRepo.FindByID(ctx, post.ID); err == nil {
+		t.Error("ロールバックした投稿が残っている")
+	}
+	var count int
+	if err := db.QueryRow("SELECT (SELECT COUNT(*) FROM likes WHERE post_id = $1) + (SELECT COUNT(*) FROM outbox_events)", post.ID).Scan(&count); err != nil {
+		t.Fatal("件数の取得失敗:", err)
+	}
+	if count != 0 {
+		t.Errorf("ロールバックした変更が %d 件残っている", count)
+	}
+}
+
+// シリアライゼーション失敗の場合は最初から実行し直し、それ以外のエラーは再試行しないことを確認する
+func TestWithinTxRetriesSerializationFailure(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	txManager := repository.NewTxManager(db)
+	postRepo := repository.NewPostRepository(db)
+
+	// 1回目はシリアライゼーション失敗にする
+	calls := 0
+	var post *models.Post
+	err := txManager.WithinTx(ctx, func(ctx context.Context) error {
+		calls++
+		post = &models.Post{Title: "再試行", Content: "再試行される投稿", UserID: 1}
+		if err := postRepo.Create(ctx, post); err != nil {
+			return err
+		}
+		if calls == 1 {
+			return apperror.NewAppError(apperror.TypeInternalServer, "Failed to update post", &pq.Error{Code: "40001"})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("トランザクションが失敗:", err)
+	}
+	if calls != 2 {
+		t.Errorf("期待する実行回数 2, 実際は %d", calls)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM posts WHERE title = '再試行'").Scan(&count); err != nil {
+		t.Fatal("件数の取得失敗:", err)
+	}
+	if count != 1 {
+		t.Errorf("期待する投稿数 1, 実際は %d", count)
+	}
+
+	// 再試行できないエラーは1回で終わる
+	calls = 0
+	err = txManager.WithinTx(ctx, func(ctx context.Context) error {
+		calls++
+		return &pq.Error{Code: "23505"}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("再試行できないエラーが再試行された: calls=%d err=%v", calls, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/lib/pq"
 	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
@@ -22,29 +23,66 @@ func NewUserRepository(db DBExecutor) *UserRepository {
 // ユーザーを作成する
 func (r *UserRepository) Create(ctx context.Context, username string, hashedPassword string) (int, error) {
 	var id int
-	err := r.db.QueryRowContext(ctx, "INSERT INTO users (username, password) VALUES ($1, $2) RETURNING id", username, hashedPassword).Scan(&id)
+	err := executor(ctx, r.db).QueryRowContext(ctx, "INSERT INTO users (username, password) VALUES ($1, $2) RETURNING id", username, hashedPassword).Scan(&id)
 	if err != nil {
 		if isUniqueViolation(err, "users_username_key") {
-			return 0, apperror.NewAppError(apperror.TypeConflict, "User already exists : Username="+username, err)
+			return 0, apperror.NewAppError(apperror.TypeConflict, "User already exists : Username="+username, err).WithSubCode(apperror.CodeUsernameTaken)
 		}
 		return 0, apperror.NewAppError(apperror.TypeInternalServer, "Failed to insert user : Username="+username, err)
 	}
 	return id, nil
 }
 
+// ユーザー名が未使用の場合のみユーザーを作成する(使用済みの場合はfalseを返す)
+// 重複時もトランザクションを中断させないようにON CONFLICTで回避する
+func (r *UserRepository) CreateIfAvailable(ctx context.Context, username string, hashedPassword string) (int, bool, error) {
+	var id int
+	err := executor(ctx, r.db).QueryRowContext(ctx, "INSERT INTO users (username, password) VALUES ($1, $2) ON CONFLICT (username) DO NOTHING RETURNING id", username, hashedPassword).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, apperror.NewAppError(apperror.TypeInternalServer, "Failed to insert user : Username="+username, err)
+	}
+	return id, true, nil
+}
+
 // ユーザー名から認証情報を取得する
 func (r *UserRepository) FindAuthByUsername(ctx context.Context, username string) (int, string, error) {
 	var id int
 	var hashedPassword string
-	err := r.db.QueryRowContext(ctx, "SELECT id, password FROM users WHERE username = $1", username).Scan(&id, &hashedPassword)
+	err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT id, password FROM users WHERE username = $1", username).Scan(&id, &hashedPassword)
 	if err == sql.ErrNoRows {
-		return 0, "", apperror.NewAppError(apperror.TypeUnauthorized, "Invalid username or password : Username="+username, err)
+		return 0, "", apperror.NewAppError(apperror.TypeUnauthorized, "Invalid username or password : Username="+username, err).WithSubCode(apperror.CodeInvalidCredentials)
 	} else if err != nil {
 		return 0, "", apperror.NewAppError(apperror.TypeInternalServer, "Database error : Username="+username, err)
 	}
 	return id, hashedPassword, nil
 }
 
+// 指定したIDのユーザー名を取得する
+func (r *UserRepository) FindUsernameByID(ctx context.Context, id int) (string, error) {
+	var username string
+	err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT username FROM users WHERE id = $1", id).Scan(&username)
+	if err == sql.ErrNoRows {
+		return "", apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("User not found : UserID=%d", id), err).WithSubCode(apperror.CodeUserNotFound)
+	} else if err != nil {
+		return "", apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Database error : UserID=%d", id), err)
+	}
+	return username, nil
+}
+
+// ユーザーが存在し、削除(匿名化)されていないか確認する
+func (r *UserRepository) IsActive(ctx context.Context, id int) (bool, error) {
+	var active bool
+	err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT deleted_at IS NULL FROM users WHERE id = $1", id).Scan(&active)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Database error : UserID=%d", id), err)
+	}
+	return active, nil
+}
+
 func isUniqueViolation(err error, constraint string) bool {
 	var pqErr *pq.Error
 	return errors.As(err, &pqErr) && string(pqErr.Code) == "23505" && pqErr.Constraint == constraint
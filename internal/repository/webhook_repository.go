@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// Webhook用のリポジトリ
+type WebhookRepository struct {
+	db DBExecutor
+}
+
+// Webhook用リポジトリのインスタンスを生成
+func NewWebhookRepository(db DBExecutor) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// Webhookを作成する
+func (r *WebhookRepository) Create(ctx context.Context, webhook *models.Webhook, secret string) error {
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO webhooks (user_id, url, events, secret)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, webhook.UserID, webhook.URL, pq.Array(webhook.Events), secret).Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to insert webhook : UserID=%d", webhook.UserID), err)
+	}
+	return nil
+}
+
+// 指定したユーザーのWebhook一覧を取得する
+func (r *WebhookRepository) ListByUserID(ctx context.Context, userID int) ([]models.Webhook, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, `
+		SELECT id, user_id, url, events, created_at
+		FROM webhooks
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch webhooks : UserID=%d", userID), err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var webhook models.Webhook
+		if err := rows.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, pq.Array(&webhook.Events), &webhook.CreatedAt); err != nil {
+			return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to parse webhook : UserID=%d", userID), err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch webhooks : UserID=%d", userID), err)
+	}
+	return webhooks, nil
+}
+
+// 指定したユーザーのWebhookを削除する(配信履歴はCASCADEで削除される)
+func (r *WebhookRepository) Delete(ctx context.Context, userID int, id int) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to delete webhook : WebhookID=%d", id), err)
+	}
+	return checkRowAffected(result, fmt.Sprintf("Webhook not found : WebhookID=%d", id), apperror.CodeWebhookNotFound)
+}
+
+// 指定したユーザーのWebhookが存在するか確認する
+func (r *WebhookRepository) EnsureOwner(ctx context.Context, userID int, id int) error {
+	var exists bool
+	err := executor(ctx, r.db).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM webhooks WHERE id = $1 AND user_id = $2)", id, userID).Scan(&exists)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Database error : WebhookID=%d", id), err)
+	}
+	if !exists {
+		return apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Webhook not found : WebhookID=%d", id), nil).WithSubCode(apperror.CodeWebhookNotFound)
+	}
+	return nil
+}
+
+// 指定したユーザーのWebhookのうち、イベントを購読しているものを取得する
+func (r *WebhookRepository) ListTargets(ctx context.Context, userID int, event string) ([]models.WebhookTarget, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, "SELECT id, url, secret FROM webhooks WHERE user_id = $1 AND $2 = ANY(events)", userID, event)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch webhooks : UserID=%d", userID), err)
+	}
+	defer rows.Close()
+
+	targets := []models.WebhookTarget{}
+	for rows.Next() {
+		var target models.WebhookTarget
+		if err := rows.Scan(&target.ID, &target.URL, &target.Secret); err != nil {
+			return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to parse webhook : UserID=%d", userID), err)
+		}
+		targets = append(targets, target)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch webhooks : UserID=%d", userID), err)
+	}
+	return targets, nil
+}
+
+// 配信を作成して配信IDを返す(next_attempt_at までに配信されない場合は再送の対象になる。作成済みの場合はfalseを返す)
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, webhookID int, outboxEventID int64, event string, payload []byte, nextAttemptAt time.Time) (int64, bool, error) {
+	// 同じイベントの配信が作成済みの場合は何もしない(アウトボックスから再配信された場合)
+	var id int64
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, outbox_event_id, event, payload, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (webhook_id, outbox_event_id) DO NOTHING
+		RETURNING id
+	`, webhookID, outboxEventID, event, string(payload), nextAttemptAt).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to insert webhook delivery : WebhookID=%d", webhookID), err)
+	}
+	return id, true, nil
+}
+
+// 配信待ちの配信と送信先を取得する(配信済み・dead の場合はNotFoundを返す)
+func (r *WebhookRepository) FindPendingDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, *models.WebhookTarget, error) {
+	var delivery models.WebhookDelivery
+	var target models.WebhookTarget
+	var payload string
+	err := executor(ctx, r.db).QueryRowContext(ctx, `
+		SELECT d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.created_at, w.id, w.url, w.secret
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.id = $1 AND d.status = 'pending'
+	`, id).Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &payload, &delivery.Status, &delivery.Attempts, &delivery.CreatedAt,
+		&target.ID, &target.URL, &target.Secret)
+	if err == sql.ErrNoRows {
+		return nil, nil, apperror.NewAppError(apperror.TypeNotFound, fmt.Sprintf("Pending webhook delivery not found : DeliveryID=%d", id), err).WithSubCode(apperror.CodeDeliveryNotFound)
+	} else if err != nil {
+		return nil, nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Database error : DeliveryID=%d", id), err)
+	}
+	delivery.Payload = []byte(payload)
+	return &delivery, &target, nil
+}
+
+// 配信の試行結果を記録する
+// 成功した場合は succeeded、再送する場合は pending のまま next_attempt_at を設定し、上限を超えた場合は dead にする
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, id int64, status string, statusCode *int, errMsg *string, nextAttemptAt *time.Time) error {
+	_, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = attempts + 1, last_status_code = $3, last_error = $4, next_attempt_at = $5,
+			delivered_at = CASE WHEN $2 = 'succeeded' THEN CURRENT_TIMESTAMP ELSE delivered_at END
+		WHERE id = $1
+	`, id, status, statusCode, errMsg, nextAttemptAt)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to update webhook delivery : DeliveryID=%d", id), err)
+	}
+	return nil
+}
+
+// 再送時刻を過ぎた配信を取得し、他のインスタンスで重複して処理しないように lease の間は対象外にする
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, lease time.Duration, limit int) ([]int64, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = NOW() + make_interval(secs => $1)
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at ASC
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id
+	`, lease.Seconds(), limit)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to claim webhook deliveries", err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to parse webhook delivery", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to claim webhook deliveries", err)
+	}
+	return ids, nil
+}
+
+// 指定したWebhookの配信履歴を新しい順に取得する
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID int, cursor *models.PageCursor, limit int) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT id, webhook_id, event, payload, status, attempts, last_status_code, last_error, next_attempt_at, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1`
+	args := []any{webhookID}
+	if cursor != nil {
+		query += " AND (created_at, id) < ($2, $3)"
+		args = append(args, cursor.CreatedAt, cursor.ID)
+	}
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit)
+
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch webhook deliveries : WebhookID=%d", webhookID), err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var payload string
+		if err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &payload, &delivery.Status, &delivery.Attempts,
+			&delivery.LastStatusCode, &delivery.LastError, &delivery.NextAttemptAt, &delivery.CreatedAt, &delivery.DeliveredAt); err != nil {
+			return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to parse webhook delivery : WebhookID=%d", webhookID), err)
+		}
+		delivery.Payload = []byte(payload)
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to fetch webhook deliveries : WebhookID=%d", webhookID), err)
+	}
+	return deliveries, nil
+}
+
+// 配信をやり直すために配信待ちに戻す(試行回数もリセットする)
+func (r *WebhookRepository) ResetDelivery(ctx context.Context, webhookID int, id int64, nextAttemptAt time.Time) error {
+	result, err := executor(ctx, r.db).ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = $3
+		WHERE id = $1 AND webhook_id = $2
+	`, id, webhookID, nextAttemptAt)
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to reset webhook delivery : DeliveryID=%d", id), err)
+	}
+	return checkRowAffected(result, fmt.Sprintf("Webhook delivery not found : DeliveryID=%d", id), apperror.CodeDeliveryNotFound)
+}
@@ -9,17 +9,22 @@ import (
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"github.com/yusuke-hoguro/BlogApi/internal/app"
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
 	"github.com/yusuke-hoguro/BlogApi/internal/handler"
 	"github.com/yusuke-hoguro/BlogApi/internal/middleware"
+	"github.com/yusuke-hoguro/BlogApi/internal/service"
 	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
 )
 
 // ハンドラー関数の設定を行う
-func RegisterRoutes(r *mux.Router, db *sql.DB, auditPool *workerpool.AuditWorkerPool, services *app.Services) {
+// cfg はライブイベント・WebSocketの接続の設定に使う
+func RegisterRoutes(r *mux.Router, db *sql.DB, auditPool *workerpool.AuditWorkerPool, services *app.Services, health *service.HealthService, cfg *config.Config) {
 	// Swagger UI
 	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 	// ヘルスチェック用
 	r.HandleFunc("/api/healthz", handler.HealthzHandler(auditPool)).Methods(http.MethodGet, http.MethodHead) // ヘルスチェック用
+	r.HandleFunc("/api/livez", handler.LivezHandler()).Methods(http.MethodGet, http.MethodHead)              // liveness(プロセスの応答のみ)
+	r.HandleFunc("/api/readyz", handler.ReadyzHandler(health)).Methods(http.MethodGet, http.MethodHead)      // readiness(依存先を確認する)
 	// 投稿関係の処理
 	r.HandleFunc("/api/posts", handler.GetAllPostsHandler(services.Post, auditPool)).Methods(http.MethodGet)                                   // 全投稿取得用
 	r.HandleFunc("/api/posts/{id}", handler.GetPostsByIDHandler(services.Post, auditPool)).Methods(http.MethodGet)                             // 個別投稿取得用
@@ -30,6 +35,13 @@ func RegisterRoutes(r *mux.Router, db *sql.DB, auditPool *workerpool.AuditWorker
 	// ユーザー認証系
 	r.HandleFunc("/api/signup", handler.SignupHandler(services.User, auditPool)).Methods(http.MethodPost) // ユーザー登録用
 	r.HandleFunc("/api/login", handler.LoginHandler(services.User, auditPool)).Methods(http.MethodPost)   // ログイン用
+	// 外部IDプロバイダー連携
+	r.HandleFunc("/api/auth/{provider}/start", middleware.OptionalAuthMiddleware(handler.OAuthStartHandler(services.OAuth, auditPool))).Methods(http.MethodGet) // 認可フロー開始
+	r.HandleFunc("/api/auth/{provider}/callback", handler.OAuthCallbackHandler(services.OAuth, auditPool)).Methods(http.MethodGet)                              // 認可フローのコールバック
+	// APIキー管理
+	r.HandleFunc("/api/apikeys", middleware.AuthMiddleware(handler.CreateAPIKeyHandler(services.APIKey, auditPool))).Methods(http.MethodPost)        // APIキー発行
+	r.HandleFunc("/api/apikeys", middleware.AuthMiddleware(handler.ListAPIKeysHandler(services.APIKey, auditPool))).Methods(http.MethodGet)          // APIキー一覧取得
+	r.HandleFunc("/api/apikeys/{id}", middleware.AuthMiddleware(handler.RevokeAPIKeyHandler(services.APIKey, auditPool))).Methods(http.MethodDelete) // APIキー失効
 	// コメント関係
 	r.HandleFunc("/api/posts/{id}/comments", handler.GetCommentsByPostIDHandler(services.Comment, auditPool)).Methods(http.MethodGet)                     // 投稿のコメント取得
 	r.HandleFunc("/api/posts/{id}/comments", middleware.AuthMiddleware(handler.PostCommentHandler(services.Comment, auditPool))).Methods(http.MethodPost) // 投稿のコメント投稿
@@ -40,4 +52,40 @@ func RegisterRoutes(r *mux.Router, db *sql.DB, auditPool *workerpool.AuditWorker
 	r.HandleFunc("/api/posts/{id}/like", middleware.AuthMiddleware(handler.LikePostHandler(services.Like, auditPool))).Methods(http.MethodPost)     // 投稿にいいねをつける
 	r.HandleFunc("/api/posts/{id}/likes", handler.GetLikesHandler(services.Like, auditPool)).Methods(http.MethodGet)                                // 投稿のいいねを取得する
 	r.HandleFunc("/api/posts/{id}/like", middleware.AuthMiddleware(handler.UnlikePostHandler(services.Like, auditPool))).Methods(http.MethodDelete) // 投稿のいいねを削除する
+	// アカウント削除・データエクスポート
+	r.HandleFunc("/api/me", middleware.AuthMiddleware(handler.RequestAccountDeletionHandler(services.Account, auditPool))).Methods(http.MethodDelete)          // アカウント削除の予約
+	r.HandleFunc("/api/me/deletion", middleware.AuthMiddleware(handler.GetAccountDeletionHandler(services.Account, auditPool))).Methods(http.MethodGet)        // アカウント削除の予約状況
+	r.HandleFunc("/api/me/deletion", middleware.AuthMiddleware(handler.CancelAccountDeletionHandler(services.Account, auditPool))).Methods(http.MethodDelete)  // アカウント削除の取り消し
+	r.HandleFunc("/api/me/export", middleware.AuthMiddleware(handler.RequestDataExportHandler(services.Account, auditPool))).Methods(http.MethodPost)          // データエクスポートの依頼
+	r.HandleFunc("/api/me/export", middleware.AuthMiddleware(handler.GetDataExportHandler(services.Account, auditPool))).Methods(http.MethodGet)               // データエクスポートの状態
+	r.HandleFunc("/api/me/export/download", middleware.AuthMiddleware(handler.DownloadDataExportHandler(services.Account, auditPool))).Methods(http.MethodGet) // データエクスポートのダウンロード
+	// フォロー・フィード
+	r.HandleFunc("/api/users/{id}/follow", middleware.AuthMiddleware(handler.FollowUserHandler(services.Follow, auditPool))).Methods(http.MethodPost)     // ユーザーをフォローする
+	r.HandleFunc("/api/users/{id}/follow", middleware.AuthMiddleware(handler.UnfollowUserHandler(services.Follow, auditPool))).Methods(http.MethodDelete) // ユーザーのフォローを解除する
+	r.HandleFunc("/api/users/{id}/followers", handler.GetFollowersHandler(services.Follow, auditPool)).Methods(http.MethodGet)                            // フォロワー一覧
+	r.HandleFunc("/api/users/{id}/following", handler.GetFollowingHandler(services.Follow, auditPool)).Methods(http.MethodGet)                            // フォロー中一覧
+	r.HandleFunc("/api/feed", middleware.AuthMiddleware(handler.GetFeedHandler(services.Follow, auditPool))).Methods(http.MethodGet)                      // ホームフィード
+	// 通知
+	r.HandleFunc("/api/notifications", middleware.AuthMiddleware(handler.GetNotificationsHandler(services.Notification, auditPool))).Methods(http.MethodGet)                          // 通知一覧
+	r.HandleFunc("/api/notifications/unread_count", middleware.AuthMiddleware(handler.GetUnreadNotificationCountHandler(services.Notification, auditPool))).Methods(http.MethodGet)   // 未読の通知件数
+	r.HandleFunc("/api/notifications/read", middleware.AuthMiddleware(handler.MarkAllNotificationsReadHandler(services.Notification, auditPool))).Methods(http.MethodPost)            // 通知をすべて既読にする
+	r.HandleFunc("/api/notifications/{id}/read", middleware.AuthMiddleware(handler.MarkNotificationReadHandler(services.Notification, auditPool))).Methods(http.MethodPost)           // 通知を既読にする
+	r.HandleFunc("/api/notifications/preferences", middleware.AuthMiddleware(handler.GetNotificationPreferencesHandler(services.Notification, auditPool))).Methods(http.MethodGet)    // 通知設定の取得
+	r.HandleFunc("/api/notifications/preferences", middleware.AuthMiddleware(handler.UpdateNotificationPreferencesHandler(services.Notification, auditPool))).Methods(http.MethodPut) // 通知設定の更新
+	// 投稿のライブイベント
+	r.Handle("/api/posts/{id}/events", middleware.Streaming(handler.PostEventsHandler(services.PostEvent, cfg.Events, auditPool))).Methods(http.MethodGet) // コメント・いいねの変化をSSEで配信する
+	// ユーザーごとのWebSocket
+	r.Handle("/api/ws", middleware.Streaming(middleware.WebSocketAuthMiddleware(handler.WebSocketHandler(services.Realtime, cfg.WebSocket, auditPool)))).Methods(http.MethodGet) // 通知と接続状態を配信する
+	// Webhook
+	r.HandleFunc("/api/webhooks", middleware.AuthMiddleware(handler.CreateWebhookHandler(services.Webhook, auditPool))).Methods(http.MethodPost)                                           // Webhookの登録
+	r.HandleFunc("/api/webhooks", middleware.AuthMiddleware(handler.ListWebhooksHandler(services.Webhook, auditPool))).Methods(http.MethodGet)                                             // Webhookの一覧
+	r.HandleFunc("/api/webhooks/{id}", middleware.AuthMiddleware(handler.DeleteWebhookHandler(services.Webhook, auditPool))).Methods(http.MethodDelete)                                    // Webhookの削除
+	r.HandleFunc("/api/webhooks/{id}/deliveries", middleware.AuthMiddleware(handler.GetWebhookDeliveriesHandler(services.Webhook, auditPool))).Methods(http.MethodGet)                     // 配信履歴
+	r.HandleFunc("/api/webhooks/{id}/deliveries/{deliveryID}/redeliver", middleware.AuthMiddleware(handler.RedeliverWebhookHandler(services.Webhook, auditPool))).Methods(http.MethodPost) // 再配信
+}
+
+// 運用向けのエンドポイントを登録する(server.debug_endpoints が有効な場合のみ呼び出す)
+// 認証を行わないため、外部に公開しない環境でのみ有効にする
+func RegisterDebugRoutes(r *mux.Router, db *sql.DB) {
+	r.HandleFunc("/api/debug/dbstats", handler.DBStatsHandler(db)).Methods(http.MethodGet) // コネクションプールの状態
 }
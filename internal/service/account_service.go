@@ -0,0 +1,237 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository"
+)
+
+// 1回の定期処理で扱う件数の上限
+const (
+	accountDeletionBatchSize = 100
+	dataExportBatchSize      = 10
+)
+
+// アカウント削除・データエクスポート用サービスの構造体
+type AccountService struct {
+	txManager    *repository.TxManager
+	accountRepo  *repository.AccountRepository
+	exportRepo   *repository.DataExportRepository
+	userRepo     repository.UserStore
+	postRepo     repository.PostStore
+	commentRepo  repository.CommentStore
+	likeRepo     repository.LikeStore
+	auditLogRepo *repository.AuditLogRepository
+}
+
+// アカウント削除・データエクスポート用サービスのインスタンスを生成する関数
+func NewAccountService(
+	txManager *repository.TxManager,
+	accountRepo *repository.AccountRepository,
+	exportRepo *repository.DataExportRepository,
+	userRepo repository.UserStore,
+	postRepo repository.PostStore,
+	commentRepo repository.CommentStore,
+	likeRepo repository.LikeStore,
+	auditLogRepo *repository.AuditLogRepository,
+) *AccountService {
+	return &AccountService{
+		txManager:    txManager,
+		accountRepo:  accountRepo,
+		exportRepo:   exportRepo,
+		userRepo:     userRepo,
+		postRepo:     postRepo,
+		commentRepo:  commentRepo,
+		likeRepo:     likeRepo,
+		auditLogRepo: auditLogRepo,
+	}
+}
+
+// アカウント削除を予約する(猶予期間が過ぎるまでは取り消せる)
+func (s *AccountService) RequestDeletion(ctx context.Context, userID int, mode string) (*models.AccountDeletion, error) {
+	return s.accountRepo.ScheduleDeletion(ctx, userID, mode, time.Now().Add(config.AccountDeletionGracePeriod))
+}
+
+// アカウント削除の予約を取得する
+func (s *AccountService) GetDeletion(ctx context.Context, userID int) (*models.AccountDeletion, error) {
+	return s.accountRepo.FindDeletion(ctx, userID)
+}
+
+// アカウント削除の予約を取り消す
+func (s *AccountService) CancelDeletion(ctx context.Context, userID int) error {
+	return s.accountRepo.CancelDeletion(ctx, userID)
+}
+
+// 猶予期間が過ぎたアカウント削除を実行する(定期処理から呼び出す)
+func (s *AccountService) ProcessDueDeletions(ctx context.Context) error {
+	userIDs, err := s.accountRepo.ListDueDeletionUserIDs(ctx, accountDeletionBatchSize)
+	if err != nil {
+		return err
+	}
+
+	// 1件の失敗で他のユーザーの削除が止まらないようにエラーをまとめて返す
+	var errs []error
+	for _, userID := range userIDs {
+		mode, executed, err := s.executeDeletion(ctx, userID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if executed {
+			log.Printf("account deletion executed: UserID=%d Mode=%s", userID, mode)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 猶予期間が過ぎたアカウント削除予約を確認して削除を実行する
+// 予約のロックと削除を1つのトランザクションで行い、取り消しと同時に実行されないようにする
+// 取り消し済み・他のインスタンスで処理中の場合は何もせずにfalseを返す
+func (s *AccountService) executeDeletion(ctx context.Context, userID int) (string, bool, error) {
+	var mode string
+	var executed bool
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		mode, executed, err = s.accountRepo.LockDueDeletion(ctx, userID)
+		if err != nil || !executed {
+			return err
+		}
+		switch mode {
+		case models.AccountDeletionModeAnonymize:
+			return s.accountRepo.AnonymizeUser(ctx, userID)
+		case models.AccountDeletionModePurge:
+			return s.accountRepo.PurgeUser(ctx, userID)
+		default:
+			return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Unknown account deletion mode : UserID=%d Mode=%s", userID, mode), nil)
+		}
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return mode, executed, nil
+}
+
+// データエクスポートを受け付ける(処理待ち・処理中のものがあればそれを返す)
+func (s *AccountService) RequestExport(ctx context.Context, userID int) (*models.DataExport, error) {
+	latest, err := s.exportRepo.FindLatestByUserID(ctx, userID)
+	var appErr *apperror.AppError
+	if err != nil && !(errors.As(err, &appErr) && appErr.Type == apperror.TypeNotFound) {
+		return nil, err
+	}
+	if latest != nil && (latest.Status == models.DataExportStatusPending || latest.Status == models.DataExportStatusRunning) {
+		return latest, nil
+	}
+	return s.exportRepo.Create(ctx, userID)
+}
+
+// 最新のデータエクスポートの状態を取得する
+func (s *AccountService) GetLatestExport(ctx context.Context, userID int) (*models.DataExport, error) {
+	return s.exportRepo.FindLatestByUserID(ctx, userID)
+}
+
+// 完了したデータエクスポートのアーカイブ(ZIP)を取得する
+func (s *AccountService) DownloadExport(ctx context.Context, userID int) ([]byte, error) {
+	latest, err := s.exportRepo.FindLatestByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if latest.Status != models.DataExportStatusCompleted {
+		return nil, apperror.NewAppError(apperror.TypeConflict, fmt.Sprintf("Data export is not ready : UserID=%d Status=%s", userID, latest.Status), nil).WithSubCode(apperror.CodeExportNotReady)
+	}
+	return s.exportRepo.FindArchive(ctx, latest.ID)
+}
+
+// 処理待ちのデータエクスポートを生成する(定期処理から呼び出す)
+func (s *AccountService) ProcessPendingExports(ctx context.Context) error {
+	// 期限切れのアーカイブを先に削除する
+	if err := s.exportRepo.DeleteExpired(ctx); err != nil {
+		return err
+	}
+
+	for i := 0; i < dataExportBatchSize; i++ {
+		export, err := s.exportRepo.ClaimPending(ctx, config.DataExportStaleAfter)
+		if err != nil {
+			return err
+		}
+		if export == nil {
+			return nil
+		}
+
+		archive, err := s.buildExportArchive(ctx, export.UserID)
+		if err != nil {
+			log.Printf("data export failed: ExportID=%d UserID=%d: %v", export.ID, export.UserID, err)
+			// 内部エラーの詳細は利用者に返さない
+			if err := s.exportRepo.Fail(ctx, export.ID, "Failed to generate export"); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := s.exportRepo.Complete(ctx, export.ID, archive, time.Now().Add(config.DataExportTTL)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ユーザーの投稿・コメント・いいね・監視イベントをJSONファイルにまとめたZIPを生成する
+func (s *AccountService) buildExportArchive(ctx context.Context, userID int) ([]byte, error) {
+	username, err := s.userRepo.FindUsernameByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := s.postRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.commentRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	likes, err := s.likeRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	auditLogs, err := s.auditLogRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{name: "user.json", data: map[string]any{"id": userID, "username": username, "exported_at": time.Now().UTC()}},
+		{name: "posts.json", data: posts},
+		{name: "comments.json", data: comments},
+		{name: "likes.json", data: likes},
+		{name: "audit_logs.json", data: auditLogs},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to create export file : "+file.name, err)
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to encode export file : "+file.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to create export archive", err)
+	}
+	return buf.Bytes(), nil
+}
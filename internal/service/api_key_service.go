@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository"
+)
+
+// APIキーの書式: blog_<prefix>_<secret>
+const apiKeyTokenPrefix = "blog_"
+
+// APIキー用サービスの構造体
+type APIKeyService struct {
+	repo *repository.APIKeyRepository
+}
+
+// APIキー用サービスのインスタンスを生成する関数
+func NewAPIKeyService(repo *repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{repo: repo}
+}
+
+// APIキーを発行する(キー本体は戻り値でのみ返し、DBにはハッシュを保存する)
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, userID int, req models.APIKeyRequest) (*models.APIKeyCreatedResponse, error) {
+	prefixBytes := make([]byte, 6)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to generate api key : UserID=%d", userID), err)
+	}
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to generate api key : UserID=%d", userID), err)
+	}
+	prefix := hex.EncodeToString(prefixBytes)
+	rawKey := apiKeyTokenPrefix + prefix + "_" + base64.RawURLEncoding.EncodeToString(secretBytes)
+
+	key := models.APIKey{
+		UserID: userID,
+		Name:   req.Name,
+		Prefix: prefix,
+		Scopes: req.Scopes,
+	}
+	if err := s.repo.Create(ctx, &key, hashAPIKey(rawKey)); err != nil {
+		return nil, err
+	}
+	return &models.APIKeyCreatedResponse{APIKey: key, Key: rawKey}, nil
+}
+
+// 指定したユーザーのAPIキー一覧を取得する
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, userID int) ([]models.APIKey, error) {
+	return s.repo.ListByUserID(ctx, userID)
+}
+
+// 指定したユーザーのAPIキーを失効させる
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, userID int, keyID int) error {
+	return s.repo.Revoke(ctx, userID, keyID)
+}
+
+// APIキーを検証して利用されたキーを返す(middleware.APIKeyAuthenticator を満たす)
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, rawKey string) (*models.APIKey, error) {
+	prefix, ok := apiKeyPrefix(rawKey)
+	if !ok {
+		return nil, apperror.NewAppError(apperror.TypeUnauthorized, "Invalid api key format", nil).WithSubCode(apperror.CodeInvalidAPIKey)
+	}
+
+	key, keyHash, err := s.repo.FindActiveByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	// タイミング攻撃を避けるために定数時間で比較する
+	if subtle.ConstantTimeCompare([]byte(keyHash), []byte(hashAPIKey(rawKey))) != 1 {
+		return nil, apperror.NewAppError(apperror.TypeUnauthorized, "Invalid api key : Prefix="+prefix, nil).WithSubCode(apperror.CodeInvalidAPIKey)
+	}
+
+	// 最終利用日時の更新失敗は認証失敗にしない
+	if err := s.repo.TouchLastUsed(ctx, key.ID); err != nil {
+		log.Printf("failed to update api key last used: %v", err)
+	}
+	return key, nil
+}
+
+// APIキーからプレフィックス部分を取り出す
+func apiKeyPrefix(rawKey string) (string, bool) {
+	if !strings.HasPrefix(rawKey, apiKeyTokenPrefix) {
+		return "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(rawKey, apiKeyTokenPrefix), "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// APIキーのハッシュを算出する(十分なエントロピーがあるためソルト無しのSHA-256で保存する)
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository"
+	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
+)
+
+// 監視イベント用サービスの構造体
+type AuditLogService struct {
+	repo *repository.AuditLogRepository
+}
+
+// 監視イベント用サービスのインスタンスを生成する関数
+func NewAuditLogService(repo *repository.AuditLogRepository) *AuditLogService {
+	return &AuditLogService{repo: repo}
+}
+
+// 監視イベントを保存する(workerpool.AuditHandler として監視ワーカープールに登録する)
+func (s *AuditLogService) Record(ctx context.Context, event workerpool.AuditEvent) error {
+	return s.repo.Create(ctx, models.AuditLog{
+		Action:       event.Action,
+		UserID:       event.UserID,
+		PostID:       event.PostID,
+		APIKeyID:     event.APIKeyID,
+		APIKeyPrefix: event.APIKeyPrefix,
+	})
+}
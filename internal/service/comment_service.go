@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"log"
 
 	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
 	"github.com/yusuke-hoguro/BlogApi/internal/models"
@@ -11,12 +12,13 @@ import (
 
 // コメント用サービスの構造体
 type CommentService struct {
-	repo *repository.CommentRepository
+	repo   repository.CommentStore
+	events *PostEventService
 }
 
 // コメント用サービスのインスタンスを生成する関数
-func NewCommentService(repo *repository.CommentRepository) *CommentService {
-	return &CommentService{repo: repo}
+func NewCommentService(repo repository.CommentStore, events *PostEventService) *CommentService {
+	return &CommentService{repo: repo, events: events}
 }
 
 // 指定した投稿IDのコメントを取得する
@@ -33,7 +35,11 @@ func (s *CommentService) GetCommentByID(ctx context.Context, commentID int) (*mo
 func (s *CommentService) CreateComment(ctx context.Context, postID int, userID int, comment *models.Comment) error {
 	comment.PostID = postID
 	comment.UserID = userID
-	return s.repo.Create(ctx, comment)
+	if err := s.repo.Create(ctx, comment); err != nil {
+		return err
+	}
+	s.events.PublishComment(PostEventCommentCreated, comment)
+	return nil
 }
 
 // リクエストのユーザーとコメント所有者を確認する
@@ -43,17 +49,31 @@ func (s *CommentService) EnsureCommentOwner(ctx context.Context, userID int, com
 		return 0, err
 	}
 	if commentOwnerID != userID {
-		return 0, apperror.NewAppError(apperror.TypeForbidden, fmt.Sprintf("Forbidden : CommentID=%d", commentID), nil)
+		return 0, apperror.NewAppError(apperror.TypeForbidden, fmt.Sprintf("Forbidden : CommentID=%d", commentID), nil).WithSubCode(apperror.CodeNotOwner)
 	}
 	return postID, nil
 }
 
 // コメントの削除処理を実施する
-func (s *CommentService) DeleteComment(ctx context.Context, commentID int) error {
-	return s.repo.Delete(ctx, commentID)
+func (s *CommentService) DeleteComment(ctx context.Context, postID int, commentID int) error {
+	if err := s.repo.Delete(ctx, commentID); err != nil {
+		return err
+	}
+	s.events.PublishCommentDeleted(postID, commentID)
+	return nil
 }
 
 // コメントの更新処理を実施する
 func (s *CommentService) UpdateComment(ctx context.Context, commentID int, content string) error {
-	return s.repo.Update(ctx, commentID, content)
+	if err := s.repo.Update(ctx, commentID, content); err != nil {
+		return err
+	}
+	// 更新後のコメントを配信する(取得に失敗しても更新は成功として扱う)
+	comment, err := s.repo.FindByID(ctx, commentID)
+	if err != nil {
+		log.Printf("Failed to publish updated comment : CommentID=%d : %v", commentID, err)
+		return nil
+	}
+	s.events.PublishComment(PostEventCommentUpdated, comment)
+	return nil
 }
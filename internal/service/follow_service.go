@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository"
+)
+
+// フォロー・フィード用サービスの構造体
+type FollowService struct {
+	followRepo repository.FollowStore
+	postRepo   repository.PostStore
+}
+
+// フォロー・フィード用サービスのインスタンスを生成する関数
+func NewFollowService(followRepo repository.FollowStore, postRepo repository.PostStore) *FollowService {
+	return &FollowService{followRepo: followRepo, postRepo: postRepo}
+}
+
+// ユーザーをフォローする
+func (s *FollowService) Follow(ctx context.Context, followerID int, followeeID int) error {
+	if followerID == followeeID {
+		return apperror.NewAppError(apperror.TypeBadRequest, fmt.Sprintf("Cannot follow yourself : UserID=%d", followerID), nil).WithSubCode(apperror.CodeCannotFollowSelf)
+	}
+	return s.followRepo.Create(ctx, followerID, followeeID)
+}
+
+// ユーザーのフォローを解除する
+func (s *FollowService) Unfollow(ctx context.Context, followerID int, followeeID int) error {
+	return s.followRepo.Delete(ctx, followerID, followeeID)
+}
+
+// 指定したユーザーのフォロワー一覧を取得する
+func (s *FollowService) GetFollowers(ctx context.Context, userID int, cursor string, limit int) (*models.FollowListResponse, error) {
+	return s.listFollowUsers(ctx, s.followRepo.ListFollowers, userID, cursor, limit)
+}
+
+// 指定したユーザーのフォロー中一覧を取得する
+func (s *FollowService) GetFollowing(ctx context.Context, userID int, cursor string, limit int) (*models.FollowListResponse, error) {
+	return s.listFollowUsers(ctx, s.followRepo.ListFollowing, userID, cursor, limit)
+}
+
+// フォロー中のユーザーの投稿をフィードとして取得する
+func (s *FollowService) GetFeed(ctx context.Context, userID int, cursor string, limit int) (*models.FeedResponse, error) {
+	pageCursor, err := decodePageCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// 次のページがあるか判定するために1件多く取得する
+	posts, err := s.postRepo.ListFeed(ctx, userID, pageCursor, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.FeedResponse{Posts: posts}
+	if len(posts) > limit {
+		response.Posts = posts[:limit]
+		last := response.Posts[limit-1]
+		response.NextCursor = encodePageCursor(models.PageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	return response, nil
+}
+
+// フォロー一覧をカーソル位置から取得する共通処理
+func (s *FollowService) listFollowUsers(
+	ctx context.Context,
+	list func(context.Context, int, *models.PageCursor, int) ([]models.FollowUser, error),
+	userID int,
+	cursor string,
+	limit int,
+) (*models.FollowListResponse, error) {
+	pageCursor, err := decodePageCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// 次のページがあるか判定するために1件多く取得する
+	users, err := list(ctx, userID, pageCursor, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.FollowListResponse{Users: users}
+	if len(users) > limit {
+		response.Users = users[:limit]
+		last := response.Users[limit-1]
+		response.NextCursor = encodePageCursor(models.PageCursor{CreatedAt: last.FollowedAt, ID: last.ID})
+	}
+	return response, nil
+}
+
+// カーソルを「作成日時(UnixNano)_ID」の文字列にしてURLセーフなBase64に変換する
+func encodePageCursor(cursor models.PageCursor) string {
+	raw := strconv.FormatInt(cursor.CreatedAt.UnixNano(), 10) + "_" + strconv.Itoa(cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// カーソル文字列を解析する(空の場合は先頭ページとしてnilを返す)
+func decodePageCursor(cursor string) (*models.PageCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	invalid := apperror.NewAppError(apperror.TypeBadRequest, "Invalid cursor : Cursor="+cursor, nil).WithSubCode(apperror.CodeInvalidQuery)
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	parts := strings.SplitN(string(raw), "_", 2)
+	if len(parts) != 2 {
+		return nil, invalid
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, invalid
+	}
+	// DBのTIMESTAMPはタイムゾーンを持たないため取得時と同じUTCで比較する
+	return &models.PageCursor{CreatedAt: time.Unix(0, nanos).UTC(), ID: id}, nil
+}
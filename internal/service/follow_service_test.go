@@ -0,0 +1,55 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository/memory"
+	"github.com/yusuke-hoguro/BlogApi/internal/service"
+)
+
+// インメモリのリポジトリでフィードを次のページのカーソルを辿って最後まで取得できることを確認する
+func TestFollowServiceFeedPagination(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	followSvc := service.NewFollowService(store.Follows(), store.Posts())
+
+	readerID, _ := store.Users().Create(ctx, "reader", "hashed")
+	authorID, _ := store.Users().Create(ctx, "author", "hashed")
+	if err := followSvc.Follow(ctx, readerID, authorID); err != nil {
+		t.Fatal("フォロー失敗:", err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := store.Posts().Create(ctx, &models.Post{Title: "投稿", Content: "本文", UserID: authorID}); err != nil {
+			t.Fatal("投稿の作成失敗:", err)
+		}
+	}
+
+	var seen []int
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		feed, err := followSvc.GetFeed(ctx, readerID, cursor, 2)
+		if err != nil {
+			t.Fatal("フィードの取得失敗:", err)
+		}
+		for _, post := range feed.Posts {
+			seen = append(seen, post.ID)
+		}
+		if feed.NextCursor == "" {
+			break
+		}
+		cursor = feed.NextCursor
+	}
+
+	// 新しい順に重複・欠落なく全件取得できる
+	want := []int{5, 4, 3, 2, 1}
+	if len(seen) != len(want) {
+		t.Fatalf("期待する投稿 %v, 実際は %v", want, seen)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("期待する投稿 %v, 実際は %v", want, seen)
+		}
+	}
+}
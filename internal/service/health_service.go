@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// readinessで確認する依存先
+// Check はタイムアウトを設定した ctx で呼び出し、リクエストを受け付けられない場合にエラーを返す
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// ヘルスチェック用サービスの構造体
+// シャットダウンが始まったら(SetDraining)依存先を確認せずに失敗を返し、ロードバランサーに振り分けを止めさせる
+type HealthService struct {
+	checks   []HealthCheck
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+// ヘルスチェック用サービスのインスタンスを生成する関数
+func NewHealthService(timeout time.Duration, checks ...HealthCheck) *HealthService {
+	return &HealthService{checks: checks, timeout: timeout}
+}
+
+// シャットダウンが始まったことを記録する(以降のreadinessは失敗する)
+func (s *HealthService) SetDraining() {
+	s.draining.Store(true)
+}
+
+// リクエストを受け付けられる状態か確認する
+// 依存先は並行して確認し、それぞれ timeout で打ち切る(1つでも失敗したら unavailable)
+func (s *HealthService) Ready(ctx context.Context) (models.ReadinessResponse, bool) {
+	if s.draining.Load() {
+		return models.ReadinessResponse{Status: "draining"}, false
+	}
+
+	results := make([]models.HealthCheckResult, len(s.checks))
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = s.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	response := models.ReadinessResponse{Status: "ok", Checks: results}
+	for _, result := range results {
+		if result.Status != "ok" {
+			response.Status = "unavailable"
+		}
+	}
+	return response, response.Status == "ok"
+}
+
+// 依存先を1つ確認する
+func (s *HealthService) run(parent context.Context, check HealthCheck) models.HealthCheckResult {
+	ctx, cancel := context.WithTimeout(parent, s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	// Check がタイムアウトを無視した場合もエラーにする
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	result := models.HealthCheckResult{Name: check.Name, Status: "ok", DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = "fail"
+		result.Error = err.Error()
+	}
+	return result
+}
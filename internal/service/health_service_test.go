@@ -0,0 +1,51 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/service"
+)
+
+// 依存先の失敗・タイムアウトとシャットダウン中にreadinessが失敗することを確認する
+func TestHealthServiceReady(t *testing.T) {
+	ctx := context.Background()
+	ok := service.HealthCheck{Name: "ok", Check: func(ctx context.Context) error { return nil }}
+	failing := service.HealthCheck{Name: "failing", Check: func(ctx context.Context) error { return errors.New("down") }}
+	slow := service.HealthCheck{Name: "slow", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	if report, ready := service.NewHealthService(time.Second, ok).Ready(ctx); !ready || report.Status != "ok" || len(report.Checks) != 1 {
+		t.Errorf("正常な依存先で失敗する: %+v", report)
+	}
+
+	health := service.NewHealthService(50*time.Millisecond, ok, failing, slow)
+	report, ready := health.Ready(ctx)
+	if ready || report.Status != "unavailable" {
+		t.Fatalf("依存先の失敗が反映されない: %+v", report)
+	}
+	want := map[string]string{"ok": "ok", "failing": "fail", "slow": "fail"}
+	for _, check := range report.Checks {
+		if want[check.Name] != check.Status {
+			t.Errorf("%s の結果が想定と異なる: %+v", check.Name, check)
+		}
+		if check.Name == "slow" && check.Error != context.DeadlineExceeded.Error() {
+			t.Errorf("タイムアウトで打ち切られない: %+v", check)
+		}
+	}
+
+	// シャットダウンが始まったら依存先を確認せずに失敗する
+	calls := 0
+	draining := service.NewHealthService(time.Second, service.HealthCheck{Name: "counted", Check: func(ctx context.Context) error {
+		calls++
+		return nil
+	}})
+	draining.SetDraining()
+	if report, ready := draining.Ready(ctx); ready || report.Status != "draining" || calls != 0 {
+		t.Errorf("シャットダウン中の結果が想定と異なる: %+v (calls=%d)", report, calls)
+	}
+}
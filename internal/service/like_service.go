@@ -9,22 +9,31 @@ import (
 
 // いいね用サービスの構造体
 type LikeService struct {
-	repo *repository.LikeRepository
+	repo   repository.LikeStore
+	events *PostEventService
 }
 
 // いいね用サービスのインスタンスを生成する関数
-func NewLikeService(repo *repository.LikeRepository) *LikeService {
-	return &LikeService{repo: repo}
+func NewLikeService(repo repository.LikeStore, events *PostEventService) *LikeService {
+	return &LikeService{repo: repo, events: events}
 }
 
 // 投稿にいいねを追加する
 func (s *LikeService) LikePost(ctx context.Context, userID int, postID int) error {
-	return s.repo.Create(ctx, userID, postID)
+	if err := s.repo.Create(ctx, userID, postID); err != nil {
+		return err
+	}
+	s.events.PublishLikeCount(ctx, postID)
+	return nil
 }
 
 // 投稿のいいねを削除する
 func (s *LikeService) UnlikePost(ctx context.Context, userID int, postID int) error {
-	return s.repo.Delete(ctx, userID, postID)
+	if err := s.repo.Delete(ctx, userID, postID); err != nil {
+		return err
+	}
+	s.events.PublishLikeCount(ctx, postID)
+	return nil
 }
 
 // 投稿のいいね情報を取得する
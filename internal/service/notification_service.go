@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository"
+)
+
+// 通知用サービスの構造体
+type NotificationService struct {
+	repo     *repository.NotificationRepository
+	postRepo repository.PostStore
+	realtime *RealtimeService
+}
+
+// 通知用サービスのインスタンスを生成する関数
+func NewNotificationService(repo *repository.NotificationRepository, postRepo repository.PostStore, realtime *RealtimeService) *NotificationService {
+	return &NotificationService{repo: repo, postRepo: postRepo, realtime: realtime}
+}
+
+// アウトボックスのイベントから通知を作成する(OutboxHandler としてアウトボックスのリレーに登録する)
+func (s *NotificationService) HandleOutboxEvent(ctx context.Context, event models.OutboxEvent) error {
+	var notificationType, groupKey string
+	var recipientID, postID, actorID int
+	switch event.Type {
+	case models.DomainEventPostLiked, models.DomainEventCommentCreated:
+		if event.Type == models.DomainEventPostLiked {
+			var liked models.PostLikedEvent
+			if err := json.Unmarshal(event.Payload, &liked); err != nil {
+				return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to decode outbox event : EventID=%d", event.ID), err)
+			}
+			postID, actorID = liked.PostID, liked.UserID
+			notificationType, groupKey = models.NotificationTypeLike, fmt.Sprintf("like:post:%d", postID)
+		} else {
+			var comment models.Comment
+			if err := json.Unmarshal(event.Payload, &comment); err != nil {
+				return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to decode outbox event : EventID=%d", event.ID), err)
+			}
+			postID, actorID = comment.PostID, comment.UserID
+			notificationType, groupKey = models.NotificationTypeComment, fmt.Sprintf("comment:post:%d", postID)
+		}
+		ownerID, err := s.postRepo.FindUserIDByPostID(ctx, postID)
+		if err != nil {
+			// 通知する前に投稿が削除された場合は何もしない
+			return ignoreNotFound(err)
+		}
+		recipientID = ownerID
+	case models.DomainEventUserFollowed:
+		var followed models.UserFollowedEvent
+		if err := json.Unmarshal(event.Payload, &followed); err != nil {
+			return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to decode outbox event : EventID=%d", event.ID), err)
+		}
+		recipientID, actorID = followed.FolloweeID, followed.FollowerID
+		notificationType, groupKey = models.NotificationTypeFollow, "follow"
+	default:
+		return nil
+	}
+
+	// 自分自身の操作は通知しない
+	if recipientID == 0 || recipientID == actorID {
+		return nil
+	}
+
+	// 受け取りを停止している種類の通知は作成しない
+	prefs, err := s.repo.FindPreferences(ctx, recipientID)
+	if err != nil {
+		return err
+	}
+	if !notificationEnabled(prefs, notificationType) {
+		return nil
+	}
+	id, err := s.repo.Upsert(ctx, recipientID, notificationType, postID, groupKey, actorID)
+	if err != nil {
+		return err
+	}
+
+	// 接続中のWebSocketへまとめた後の通知を送る
+	notification, err := s.repo.FindByID(ctx, recipientID, id)
+	if err != nil {
+		return err
+	}
+	notification.Message = notificationMessage(notification)
+	s.realtime.SendToUser(recipientID, RealtimeMessageNotification, notification)
+	return nil
+}
+
+// 通知一覧を取得する
+func (s *NotificationService) GetNotifications(ctx context.Context, userID int, unreadOnly bool, cursor string, limit int) (*models.NotificationListResponse, error) {
+	pageCursor, err := decodePageCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// 次のページがあるか判定するために1件多く取得する
+	notifications, err := s.repo.List(ctx, userID, unreadOnly, pageCursor, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	unreadCount, err := s.repo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.NotificationListResponse{Notifications: notifications, UnreadCount: unreadCount}
+	if len(notifications) > limit {
+		response.Notifications = notifications[:limit]
+		last := response.Notifications[limit-1]
+		response.NextCursor = encodePageCursor(models.PageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	for i := range response.Notifications {
+		response.Notifications[i].Message = notificationMessage(&response.Notifications[i])
+	}
+	return response, nil
+}
+
+// 未読の通知件数を取得する
+func (s *NotificationService) GetUnreadCount(ctx context.Context, userID int) (int, error) {
+	return s.repo.CountUnread(ctx, userID)
+}
+
+// 指定した通知を既読にする
+func (s *NotificationService) MarkRead(ctx context.Context, userID int, id int) error {
+	return s.repo.MarkRead(ctx, userID, id)
+}
+
+// 未読の通知をすべて既読にする
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID int) error {
+	return s.repo.MarkAllRead(ctx, userID)
+}
+
+// 通知設定を取得する
+func (s *NotificationService) GetPreferences(ctx context.Context, userID int) (*models.NotificationPreferences, error) {
+	return s.repo.FindPreferences(ctx, userID)
+}
+
+// 通知設定を更新する(指定されなかった項目は現在の設定のまま)
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID int, req *models.NotificationPreferencesRequest) (*models.NotificationPreferences, error) {
+	prefs, err := s.repo.FindPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Likes != nil {
+		prefs.Likes = *req.Likes
+	}
+	if req.Comments != nil {
+		prefs.Comments = *req.Comments
+	}
+	if req.Follows != nil {
+		prefs.Follows = *req.Follows
+	}
+	if err := s.repo.UpsertPreferences(ctx, userID, prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// 通知の種類ごとの受け取り設定を判定する
+func notificationEnabled(prefs *models.NotificationPreferences, notificationType string) bool {
+	switch notificationType {
+	case models.NotificationTypeLike:
+		return prefs.Likes
+	case models.NotificationTypeComment:
+		return prefs.Comments
+	case models.NotificationTypeFollow:
+		return prefs.Follows
+	default:
+		return false
+	}
+}
+
+// 通知の表示用メッセージを生成する(複数人の操作は「○○ and N others」にまとめる)
+func notificationMessage(n *models.Notification) string {
+	var action string
+	switch n.Type {
+	case models.NotificationTypeLike:
+		action = "liked your post"
+	case models.NotificationTypeComment:
+		action = "commented on your post"
+	case models.NotificationTypeFollow:
+		action = "started following you"
+	}
+
+	actor := n.LatestActorName
+	if actor == "" {
+		actor = "Someone"
+	}
+	switch others := n.ActorCount - 1; {
+	case others <= 0:
+		return fmt.Sprintf("%s %s", actor, action)
+	case others == 1:
+		return fmt.Sprintf("%s and 1 other %s", actor, action)
+	default:
+		return fmt.Sprintf("%s and %d others %s", actor, others, action)
+	}
+}
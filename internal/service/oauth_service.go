@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/oidc"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 認可フローのstateの有効期限
+const oauthStateTTL = 10 * time.Minute
+
+// 外部IDプロバイダーでのログイン結果
+type OAuthLoginResult struct {
+	Token   string
+	UserID  int
+	Created bool // 新規ユーザーを作成した場合はtrue
+	Linked  bool // ログイン中のユーザーにアカウントを紐付けた場合はtrue
+}
+
+// 外部IDプロバイダーログイン用サービスの構造体
+type OAuthService struct {
+	txManager    *repository.TxManager
+	userRepo     repository.UserStore
+	identityRepo *repository.IdentityRepository
+	stateRepo    *repository.OAuthStateRepository
+
+	mu        sync.RWMutex
+	providers map[string]*oidc.Client
+}
+
+// 外部IDプロバイダーログイン用サービスのインスタンスを生成する関数
+func NewOAuthService(txManager *repository.TxManager, userRepo repository.UserStore, identityRepo *repository.IdentityRepository, stateRepo *repository.OAuthStateRepository) *OAuthService {
+	return &OAuthService{
+		txManager:    txManager,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		stateRepo:    stateRepo,
+		providers:    make(map[string]*oidc.Client),
+	}
+}
+
+// 外部IDプロバイダーを登録する
+func (s *OAuthService) RegisterProvider(client *oidc.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.providers[client.Name()] = client
+}
+
+// 登録済みの外部IDプロバイダーを取得する
+func (s *OAuthService) provider(name string) (*oidc.Client, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	client, ok := s.providers[name]
+	if !ok {
+		return nil, apperror.NewAppError(apperror.TypeNotFound, "Provider not found : Provider="+name, nil).WithSubCode(apperror.CodeProviderNotFound)
+	}
+	return client, nil
+}
+
+// 認可フローを開始して、リダイレクト先URLとstateを返す
+// linkUserIDが0以外の場合はコールバック時にそのユーザーへアカウントを紐付ける
+func (s *OAuthService) StartLogin(ctx context.Context, providerName string, linkUserID int) (string, string, error) {
+	client, err := s.provider(providerName)
+	if err != nil {
+		return "", "", err
+	}
+
+	// state, nonce, PKCEのcode_verifierを生成する
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", apperror.NewAppError(apperror.TypeInternalServer, "Failed to generate state : Provider="+providerName, err)
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", apperror.NewAppError(apperror.TypeInternalServer, "Failed to generate nonce : Provider="+providerName, err)
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return "", "", apperror.NewAppError(apperror.TypeInternalServer, "Failed to generate code verifier : Provider="+providerName, err)
+	}
+
+	// コールバックで照合するためにstateを保存する
+	err = s.stateRepo.Create(ctx, &models.OAuthState{
+		State:        state,
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(oauthStateTTL),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := client.AuthCodeURL(ctx, state, nonce, oidc.CodeChallengeS256(verifier))
+	if err != nil {
+		return "", "", apperror.NewAppError(apperror.TypeInternalServer, "Failed to build authorization url : Provider="+providerName, err)
+	}
+	return authURL, state, nil
+}
+
+// コールバックを処理して、ユーザーを特定・作成・紐付けしたうえでJWTを発行する
+func (s *OAuthService) CompleteLogin(ctx context.Context, providerName string, state string, code string) (*OAuthLoginResult, error) {
+	client, err := s.provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	// stateを照合する(1回限り有効)
+	savedState, err := s.stateRepo.Consume(ctx, state)
+	if err != nil {
+		var appErr *apperror.AppError
+		if errors.As(err, &appErr) && appErr.Type == apperror.TypeNotFound {
+			return nil, apperror.NewAppError(apperror.TypeBadRequest, "Invalid or expired state : Provider="+providerName, err).WithSubCode(apperror.CodeOAuthFailed)
+		}
+		return nil, err
+	}
+	if savedState.Provider != providerName {
+		return nil, apperror.NewAppError(apperror.TypeBadRequest, "State does not match provider : Provider="+providerName, nil).WithSubCode(apperror.CodeOAuthFailed)
+	}
+
+	// 認可コードをトークンに交換してIDトークンを検証する
+	token, err := client.Exchange(ctx, code, savedState.CodeVerifier)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeUnauthorized, "Failed to exchange authorization code : Provider="+providerName, err).WithSubCode(apperror.CodeOAuthFailed)
+	}
+	claims, err := client.VerifyIDToken(ctx, token.IDToken, savedState.Nonce)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeUnauthorized, "Failed to verify id token : Provider="+providerName, err).WithSubCode(apperror.CodeOAuthFailed)
+	}
+
+	result, err := s.resolveUser(ctx, providerName, claims, savedState.LinkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// プロジェクト独自のJWTを発行する
+	result.Token, err = GenerateJWT(result.UserID)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to generate token : Provider="+providerName, err)
+	}
+	return result, nil
+}
+
+// IDトークンのクレームからユーザーを特定する(紐付け・新規作成を含む)
+func (s *OAuthService) resolveUser(ctx context.Context, providerName string, claims *oidc.Claims, linkUserID int) (*OAuthLoginResult, error) {
+	// 既に紐付いているユーザーを探す
+	userID, err := s.identityRepo.FindUserID(ctx, providerName, claims.Subject)
+	if err == nil {
+		// 別のユーザーに紐付いているアカウントは紐付け直さない
+		if linkUserID != 0 && linkUserID != userID {
+			return nil, apperror.NewAppError(apperror.TypeConflict, "Identity already linked to another user : Provider="+providerName, nil).WithSubCode(apperror.CodeIdentityLinked)
+		}
+		return &OAuthLoginResult{UserID: userID}, nil
+	}
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) || appErr.Type != apperror.TypeNotFound {
+		return nil, err
+	}
+
+	identity := &models.UserIdentity{
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+
+	// ログイン中のユーザーから開始した場合は既存ユーザーに紐付ける
+	if linkUserID != 0 {
+		identity.UserID = linkUserID
+		if err := s.identityRepo.Create(ctx, identity); err != nil {
+			return nil, err
+		}
+		return &OAuthLoginResult{UserID: linkUserID, Linked: true}, nil
+	}
+
+	// 未登録の場合はパスワードログインできないユーザーを新規作成する
+	// (メールアドレスだけで既存ユーザーに自動で紐付けるとアカウント乗っ取りにつながるため行わない)
+	password, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to generate password : Provider="+providerName, err)
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, "Failed to hash password : Provider="+providerName, err)
+	}
+	// ユーザーの作成とアカウントの紐付けは1つのトランザクションで行う
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// ユーザー名は候補の先頭から順に未使用のものを採用する
+		identity.UserID = 0
+		for _, username := range usernameCandidates(providerName, claims) {
+			userID, created, err := s.userRepo.CreateIfAvailable(ctx, username, string(hashedPassword))
+			if err != nil {
+				return err
+			}
+			if created {
+				identity.UserID = userID
+				break
+			}
+		}
+		if identity.UserID == 0 {
+			return apperror.NewAppError(apperror.TypeConflict, fmt.Sprintf("No available username : Provider=%s", providerName), nil).WithSubCode(apperror.CodeUsernameTaken)
+		}
+		return s.identityRepo.Create(ctx, identity)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &OAuthLoginResult{UserID: identity.UserID, Created: true}, nil
+}
+
+// 新規ユーザー作成時のユーザー名の候補を返す
+func usernameCandidates(providerName string, claims *oidc.Claims) []string {
+	candidates := []string{}
+	if claims.PreferredUsername != "" {
+		candidates = append(candidates, claims.PreferredUsername)
+	}
+	if claims.Email != "" && claims.EmailVerified {
+		candidates = append(candidates, claims.Email)
+	}
+	// 最後の候補はプロバイダー内で一意なsubjectを使う
+	return append(candidates, fmt.Sprintf("%s:%s", providerName, claims.Subject))
+}
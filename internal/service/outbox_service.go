@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository"
+)
+
+// アウトボックスのイベントを処理する後続処理の関数
+// 同じイベントが複数回渡される場合があるため(at-least-once)、処理は冪等にする
+type OutboxHandler func(ctx context.Context, event models.OutboxEvent) error
+
+// 後続処理の名前と関数
+type outboxConsumer struct {
+	name   string
+	handle OutboxHandler
+}
+
+// アウトボックス用サービスの構造体
+// リレーのgoroutineが配信待ちのイベントを取り出し、登録された後続処理へ順に渡す
+type OutboxService struct {
+	repo      *repository.OutboxRepository
+	cfg       config.OutboxConfig
+	consumers []outboxConsumer
+	stopCh    chan struct{}
+	done      chan struct{}
+	stopOnce  sync.Once
+}
+
+// アウトボックス用サービスのインスタンスを生成する関数
+func NewOutboxService(repo *repository.OutboxRepository, cfg config.OutboxConfig) *OutboxService {
+	return &OutboxService{repo: repo, cfg: cfg, stopCh: make(chan struct{}), done: make(chan struct{})}
+}
+
+// 後続処理を登録する(Start の前に呼ぶ)
+// name は処理済みの記録(冪等キー)に使うため、登録後に変更しない
+func (s *OutboxService) RegisterConsumer(name string, handle OutboxHandler) {
+	s.consumers = append(s.consumers, outboxConsumer{name: name, handle: handle})
+}
+
+// リレーのgoroutineを開始する
+func (s *OutboxService) Start() {
+	go s.run()
+}
+
+// リレーのgoroutineを停止する(処理中のイベントの後続処理が終わるまで待つ)
+func (s *OutboxService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+		<-s.done
+	})
+}
+
+// リレーの処理ループ
+func (s *OutboxService) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.cfg.RelayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopCh:
+			log.Println("outbox relay: stopped")
+			return
+		case <-ticker.C:
+			// 取り出したイベントが上限に達した場合は続けて取り出す
+			for {
+				count, err := s.Relay(context.Background())
+				if err != nil {
+					log.Printf("outbox relay: failed: %v", err)
+				}
+				if err != nil || count < s.cfg.BatchSize || s.stopping() {
+					break
+				}
+			}
+		}
+	}
+}
+
+// 停止が要求されたか判定する
+func (s *OutboxService) stopping() bool {
+	select {
+	case <-s.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// 配信待ちのイベントを取り出して後続処理へ渡し、取り出した件数を返す
+// 後続処理が失敗したイベントは指数バックオフで再送し、上限を超えたら再送を止める
+func (s *OutboxService) Relay(ctx context.Context) (int, error) {
+	events, err := s.repo.ClaimPending(ctx, s.cfg.ClaimLease, s.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		if err := s.dispatch(ctx, event); err != nil {
+			errMsg := err.Error()
+			attempts := event.Attempts + 1
+			if attempts >= s.cfg.MaxAttempts {
+				log.Printf("outbox relay: gave up event: event_id=%d type=%s: %s", event.ID, event.Type, errMsg)
+				err = s.repo.RecordFailure(ctx, event.ID, errMsg, nil)
+			} else {
+				log.Printf("outbox relay: failed to dispatch event: event_id=%d type=%s attempts=%d: %s", event.ID, event.Type, attempts, errMsg)
+				nextAttemptAt := time.Now().Add(retryDelay(s.cfg.RetryBaseDelay, s.cfg.RetryMaxDelay, attempts))
+				err = s.repo.RecordFailure(ctx, event.ID, errMsg, &nextAttemptAt)
+			}
+			if err != nil {
+				log.Printf("outbox relay: failed to record failure: event_id=%d: %v", event.ID, err)
+			}
+			continue
+		}
+		if err := s.repo.MarkPublished(ctx, event.ID); err != nil {
+			// 配信済みにできなかったイベントは lease の後に再び取り出されるが、処理済みの後続処理は呼ばれない
+			log.Printf("outbox relay: failed to mark event published: event_id=%d: %v", event.ID, err)
+		}
+	}
+	return len(events), nil
+}
+
+// イベントをまだ処理していない後続処理へ渡す
+func (s *OutboxService) dispatch(ctx context.Context, event models.OutboxEvent) error {
+	consumed, err := s.repo.ListConsumers(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, consumer := range s.consumers {
+		if consumed[consumer.name] {
+			continue
+		}
+		handlerCtx, cancel := context.WithTimeout(ctx, s.cfg.HandlerTimeout)
+		err := consumer.handle(handlerCtx, event)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", consumer.name, err))
+			continue
+		}
+		// 処理済みを記録して、再送時に同じ後続処理を呼ばないようにする
+		if err := s.repo.MarkConsumed(ctx, consumer.name, event.ID); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", consumer.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 保持期間を過ぎた配信済みのイベントを削除する(workerpool.PeriodicJob として定期的に実行する)
+func (s *OutboxService) PrunePublished(ctx context.Context) error {
+	count, err := s.repo.DeletePublishedBefore(ctx, time.Now().Add(-s.cfg.Retention))
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Printf("outbox: deleted %d published events", count)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository"
+	"github.com/yusuke-hoguro/BlogApi/internal/service"
+	"github.com/yusuke-hoguro/BlogApi/testutils"
+)
+
+// 失敗した後続処理だけが再送され、処理済みの後続処理は二度呼ばれないことを確認する
+func TestOutboxRelayRetriesFailedConsumer(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	// いいねを追加するとアウトボックスにイベントが書き込まれる(いいね済みの場合は書き込まれない)
+	likeRepo := repository.NewLikeRepository(db)
+	for i := 0; i < 2; i++ {
+		if err := likeRepo.Create(ctx, 1, 2); err != nil {
+			t.Fatal("いいねの追加失敗:", err)
+		}
+	}
+
+	// 1回目だけ失敗する後続処理と、常に成功する後続処理を登録する
+	var flakyCalls, stableCalls int
+	relay := service.NewOutboxService(repository.NewOutboxRepository(db), config.Default().Outbox)
+	relay.RegisterConsumer("flaky", func(ctx context.Context, event models.OutboxEvent) error {
+		flakyCalls++
+		if flakyCalls == 1 {
+			return errors.New("temporary failure")
+		}
+		return nil
+	})
+	relay.RegisterConsumer("stable", func(ctx context.Context, event models.OutboxEvent) error {
+		if event.Type != models.DomainEventPostLiked || event.AggregateID != 2 {
+			t.Errorf("イベントが想定と異なる: %+v", event)
+		}
+		stableCalls++
+		return nil
+	})
+
+	count, err := relay.Relay(ctx)
+	if err != nil || count != 1 {
+		t.Fatalf("イベントの取り出しが想定と異なる: count=%d err=%v", count, err)
+	}
+	var attempts int
+	var lastError string
+	if err := db.QueryRow("SELECT attempts, last_error FROM outbox_events").Scan(&attempts, &lastError); err != nil {
+		t.Fatal("イベントの取得失敗:", err)
+	}
+	if attempts != 1 || lastError == "" {
+		t.Errorf("失敗が記録されていない: attempts=%d last_error=%s", attempts, lastError)
+	}
+
+	// 再送時刻を過ぎるまでは取り出されない
+	if count, err := relay.Relay(ctx); err != nil || count != 0 {
+		t.Fatalf("再送時刻前に取り出された: count=%d err=%v", count, err)
+	}
+
+	// 再送時刻を過ぎると失敗した後続処理だけが呼ばれ、配信済みになる
+	if _, err := db.Exec("UPDATE outbox_events SET next_attempt_at = NOW()"); err != nil {
+		t.Fatal("イベントの更新失敗:", err)
+	}
+	if count, err := relay.Relay(ctx); err != nil || count != 1 {
+		t.Fatalf("イベントの取り出しが想定と異なる: count=%d err=%v", count, err)
+	}
+	if flakyCalls != 2 || stableCalls != 1 {
+		t.Errorf("後続処理の呼び出し回数が想定と異なる: flaky=%d stable=%d", flakyCalls, stableCalls)
+	}
+	var published bool
+	if err := db.QueryRow("SELECT published_at IS NOT NULL FROM outbox_events").Scan(&published); err != nil {
+		t.Fatal("イベントの取得失敗:", err)
+	}
+	if !published {
+		t.Error("イベントが配信済みになっていない")
+	}
+}
+
+// 変更がロールバックされた場合はアウトボックスにもイベントが残らないことを確認する
+func TestOutboxEventIsAtomicWithChange(t *testing.T) {
+	db := testutils.SetupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	// 存在しないユーザーのフォローは失敗し、イベントも書き込まれない
+	followRepo := repository.NewFollowRepository(db)
+	if err := followRepo.Create(ctx, 1, 9999); err == nil {
+		t.Fatal("存在しないユーザーのフォローが成功した")
+	}
+	// 存在しない投稿の更新も同様
+	postRepo := repository.NewPostRepository(db)
+	if err := postRepo.Update(ctx, 9999, &models.Post{Title: "title", Content: "content"}); err == nil {
+		t.Fatal("存在しない投稿の更新が成功した")
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM outbox_events").Scan(&count); err != nil {
+		t.Fatal("イベントの取得失敗:", err)
+	}
+	if count != 0 {
+		t.Errorf("失敗した変更のイベントが書き込まれた: %d件", count)
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/pubsub"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository"
+)
+
+// 投稿のライブイベントの種類
+const (
+	PostEventCommentCreated = "comment_created"
+	PostEventCommentUpdated = "comment_updated"
+	PostEventCommentDeleted = "comment_deleted"
+	PostEventLikeCount      = "like_count"
+)
+
+// 投稿のライブイベント用サービスの構造体
+type PostEventService struct {
+	hub      *pubsub.Hub
+	postRepo repository.PostStore
+	likeRepo repository.LikeStore
+}
+
+// 投稿のライブイベント用サービスのインスタンスを生成する関数
+func NewPostEventService(hub *pubsub.Hub, postRepo repository.PostStore, likeRepo repository.LikeStore) *PostEventService {
+	return &PostEventService{hub: hub, postRepo: postRepo, likeRepo: likeRepo}
+}
+
+// 投稿のライブイベントを購読する(投稿が存在しない場合はNotFoundを返す)
+func (s *PostEventService) Subscribe(ctx context.Context, postID int, lastEventID uint64) (*pubsub.Subscription, error) {
+	if _, err := s.postRepo.FindUserIDByPostID(ctx, postID); err != nil {
+		return nil, err
+	}
+	return s.hub.Subscribe(postTopic(postID), lastEventID), nil
+}
+
+// コメントの作成・更新を配信する
+func (s *PostEventService) PublishComment(eventType string, comment *models.Comment) {
+	s.hub.Publish(postTopic(comment.PostID), eventType, comment)
+}
+
+// コメントの削除を配信する
+func (s *PostEventService) PublishCommentDeleted(postID int, commentID int) {
+	s.hub.Publish(postTopic(postID), PostEventCommentDeleted, models.CommentDeletedEvent{ID: commentID, PostID: postID})
+}
+
+// 最新のいいね数を配信する(配信に失敗してもいいねの操作は成功として扱う)
+func (s *PostEventService) PublishLikeCount(ctx context.Context, postID int) {
+	count, err := s.likeRepo.CountByPostID(ctx, postID)
+	if err != nil {
+		log.Printf("Failed to publish like count : PostID=%d : %v", postID, err)
+		return
+	}
+	s.hub.Publish(postTopic(postID), PostEventLikeCount, models.LikeCountEvent{PostID: postID, LikeCount: count})
+}
+
+// 投稿ごとのトピック名
+func postTopic(postID int) string {
+	return fmt.Sprintf("post:%d", postID)
+}
+
+// 保持期間を過ぎたイベントの履歴を削除する(workerpool.PeriodicJob として定期的に実行する)
+func (s *PostEventService) PruneHistory(ctx context.Context) error {
+	return s.hub.Prune(ctx)
+}
+
+// すべての購読を終了する(http.Server.RegisterOnShutdown に登録して接続中のストリームを終わらせる)
+func (s *PostEventService) Close() {
+	s.hub.Close()
+}
@@ -11,11 +11,11 @@ import (
 
 // 投稿用サービスの構造体
 type PostService struct {
-	repo *repository.PostRepository
+	repo repository.PostStore
 }
 
 // 投稿用サービスのインスタンスを生成する関数
-func NewPostService(repo *repository.PostRepository) *PostService {
+func NewPostService(repo repository.PostStore) *PostService {
 	return &PostService{repo: repo}
 }
 
@@ -28,7 +28,7 @@ func (s *PostService) EnsurePostOwner(ctx context.Context, userID int, postID in
 	}
 	// リクエストを投げたユーザーが記事の投稿者でない場合はエラー
 	if postUserID != userID {
-		return apperror.NewAppError(apperror.TypeForbidden, fmt.Sprintf("Forbidden : PostID=%d", postID), nil)
+		return apperror.NewAppError(apperror.TypeForbidden, fmt.Sprintf("Forbidden : PostID=%d", postID), nil).WithSubCode(apperror.CodeNotOwner)
 	}
 	return nil
 }
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/pubsub"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository"
+)
+
+// WebSocketで送るメッセージの種類
+const (
+	RealtimeMessageNotification = "notification"
+	RealtimeMessagePresence     = "presence"
+	RealtimeMessageSnapshot     = "presence_snapshot"
+)
+
+// ユーザーごとのリアルタイム配信用サービスの構造体
+type RealtimeService struct {
+	hub        *pubsub.UserHub
+	followRepo repository.FollowStore
+}
+
+// ユーザーごとのリアルタイム配信用サービスのインスタンスを生成する関数
+func NewRealtimeService(hub *pubsub.UserHub, followRepo repository.FollowStore) *RealtimeService {
+	return &RealtimeService{hub: hub, followRepo: followRepo}
+}
+
+// 接続を登録する
+// 接続した本人にはオンラインのフォロー中ユーザーを送り、最初の接続の場合はフォロワーにオンラインを通知する
+func (s *RealtimeService) Connect(ctx context.Context, userID int) *pubsub.Client {
+	client, first := s.hub.Register(userID)
+
+	// 接続状態の配信に失敗しても接続は続ける
+	followeeIDs, err := s.followRepo.ListFolloweeIDs(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to send presence snapshot : UserID=%d : %v", userID, err)
+	} else {
+		s.hub.SendToClient(client, pubsub.Message{
+			Type: RealtimeMessageSnapshot,
+			Data: models.PresenceSnapshot{OnlineUserIDs: s.hub.Online(followeeIDs)},
+		})
+	}
+
+	if first {
+		s.broadcastPresence(ctx, userID, true)
+	}
+	return client
+}
+
+// 接続の登録を解除する(最後の接続だった場合はフォロワーにオフラインを通知する)
+func (s *RealtimeService) Disconnect(ctx context.Context, client *pubsub.Client) {
+	if online := s.hub.Unregister(client); !online {
+		s.broadcastPresence(ctx, client.UserID, false)
+	}
+}
+
+// 指定したユーザーのすべての接続へメッセージを送る
+func (s *RealtimeService) SendToUser(userID int, messageType string, data any) {
+	s.hub.SendToUser(userID, pubsub.Message{Type: messageType, Data: data})
+}
+
+// すべての接続を終了する(http.Server.RegisterOnShutdown に登録して接続中のWebSocketを閉じる)
+func (s *RealtimeService) Close() {
+	s.hub.Close()
+}
+
+// 接続中のフォロワーに接続状態の変化を送る
+func (s *RealtimeService) broadcastPresence(ctx context.Context, userID int, online bool) {
+	followerIDs, err := s.followRepo.ListFollowerIDs(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to broadcast presence : UserID=%d : %v", userID, err)
+		return
+	}
+	message := pubsub.Message{Type: RealtimeMessagePresence, Data: models.PresenceEvent{UserID: userID, Online: online}}
+	for _, followerID := range s.hub.Online(followerIDs) {
+		s.hub.SendToUser(followerID, message)
+	}
+}
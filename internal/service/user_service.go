@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt"
@@ -14,11 +16,11 @@ import (
 
 // ユーザー用サービスの構造体
 type UserService struct {
-	repo *repository.UserRepository
+	repo repository.UserStore
 }
 
 // ユーザー用サービスのインスタンスを生成する関数
-func NewUserService(repo *repository.UserRepository) *UserService {
+func NewUserService(repo repository.UserStore) *UserService {
 	return &UserService{repo: repo}
 }
 
@@ -45,7 +47,7 @@ func (s *UserService) Login(ctx context.Context, user models.User) (string, int,
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(user.Password)); err != nil {
-		return "", 0, apperror.NewAppError(apperror.TypeUnauthorized, "Invalid username or password : Username="+user.Username, err)
+		return "", 0, apperror.NewAppError(apperror.TypeUnauthorized, "Invalid username or password : Username="+user.Username, err).WithSubCode(apperror.CodeInvalidCredentials)
 	}
 
 	token, err := GenerateJWT(id)
@@ -56,6 +58,19 @@ func (s *UserService) Login(ctx context.Context, user models.User) (string, int,
 	return token, id, nil
 }
 
+// 認証済みのユーザーが存在し、削除されていないことを確認する
+// 削除前に発行したJWTは有効期限まで署名の検証に通るため、リクエストごとに確認する
+func (s *UserService) VerifyActiveUser(ctx context.Context, userID int) error {
+	active, err := s.repo.IsActive(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !active {
+		return apperror.NewAppError(apperror.TypeUnauthorized, fmt.Sprintf("User not found or deleted : UserID=%d", userID), nil).WithSubCode(apperror.CodeInvalidToken)
+	}
+	return nil
+}
+
 // JWTトークンを発行する
 func GenerateJWT(userID int) (string, error) {
 	// payloadの生成
@@ -67,5 +82,9 @@ func GenerateJWT(userID int) (string, error) {
 	// JWTを生成する
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	// 署名付きトークン生成
-	return token.SignedString(middleware.JwtKey)
+	key := middleware.JWTKey()
+	if len(key) == 0 {
+		return "", errors.New("jwt key is not configured")
+	}
+	return token.SignedString(key)
 }
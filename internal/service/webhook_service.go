@@ -0,0 +1,316 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/config"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+	"github.com/yusuke-hoguro/BlogApi/internal/repository"
+	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
+)
+
+// Webhookの署名用シークレットの書式: whsec_<secret>
+const webhookSecretPrefix = "whsec_"
+
+// 配信失敗時に記録するエラーメッセージの最大長
+const maxWebhookErrorLength = 500
+
+// Webhook用サービスの構造体
+type WebhookService struct {
+	repo     *repository.WebhookRepository
+	postRepo repository.PostStore
+	pool     *workerpool.WebhookWorkerPool
+	client   *http.Client
+	cfg      config.WebhookConfig
+}
+
+// Webhook用サービスのインスタンスを生成する関数
+func NewWebhookService(
+	repo *repository.WebhookRepository,
+	postRepo repository.PostStore,
+	pool *workerpool.WebhookWorkerPool,
+	client *http.Client,
+	cfg config.WebhookConfig,
+) *WebhookService {
+	return &WebhookService{repo: repo, postRepo: postRepo, pool: pool, client: client, cfg: cfg}
+}
+
+// Webhookの送信用HTTPクライアントを生成する
+// allow_private_networks がfalseの場合は、内部ネットワークへのリクエストに使われないようにプライベートアドレスへの接続を拒否する
+func NewWebhookHTTPClient(cfg config.WebhookConfig) *http.Client {
+	dialer := &net.Dialer{Timeout: cfg.RequestTimeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() || ip.IsLoopback() {
+				return fmt.Errorf("webhook destination is not allowed: %s", host)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   cfg.RequestTimeout,
+		Transport: transport,
+		// リダイレクトは追わずに失敗として扱う
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// 配信ワーカープールを開始する
+func (s *WebhookService) Start() {
+	s.pool.Start(s.Deliver)
+}
+
+// 配信ワーカープールを停止する(キューに残っている配信を処理してから停止する)
+func (s *WebhookService) Stop() {
+	s.pool.Stop()
+}
+
+// Webhookを作成する(署名用のシークレットは戻り値でのみ返す)
+func (s *WebhookService) CreateWebhook(ctx context.Context, userID int, req models.WebhookRequest) (*models.WebhookCreatedResponse, error) {
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return nil, apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to generate webhook secret : UserID=%d", userID), err)
+	}
+	secret := webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(secretBytes)
+
+	webhook := models.Webhook{UserID: userID, URL: req.URL, Events: req.Events}
+	if err := s.repo.Create(ctx, &webhook, secret); err != nil {
+		return nil, err
+	}
+	return &models.WebhookCreatedResponse{Webhook: webhook, Secret: secret}, nil
+}
+
+// 指定したユーザーのWebhook一覧を取得する
+func (s *WebhookService) ListWebhooks(ctx context.Context, userID int) ([]models.Webhook, error) {
+	return s.repo.ListByUserID(ctx, userID)
+}
+
+// 指定したユーザーのWebhookを削除する
+func (s *WebhookService) DeleteWebhook(ctx context.Context, userID int, webhookID int) error {
+	return s.repo.Delete(ctx, userID, webhookID)
+}
+
+// Webhookの配信履歴を取得する
+func (s *WebhookService) ListDeliveries(ctx context.Context, userID int, webhookID int, cursor string, limit int) (*models.WebhookDeliveryListResponse, error) {
+	if err := s.repo.EnsureOwner(ctx, userID, webhookID); err != nil {
+		return nil, err
+	}
+	pageCursor, err := decodePageCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// 次のページがあるか判定するために1件多く取得する
+	deliveries, err := s.repo.ListDeliveries(ctx, webhookID, pageCursor, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.WebhookDeliveryListResponse{Deliveries: deliveries}
+	if len(deliveries) > limit {
+		response.Deliveries = deliveries[:limit]
+		last := response.Deliveries[limit-1]
+		response.NextCursor = encodePageCursor(models.PageCursor{CreatedAt: last.CreatedAt, ID: int(last.ID)})
+	}
+	return response, nil
+}
+
+// 配信をやり直す(dead になった配信や成功した配信も再送できる)
+func (s *WebhookService) Redeliver(ctx context.Context, userID int, webhookID int, deliveryID int64) error {
+	if err := s.repo.EnsureOwner(ctx, userID, webhookID); err != nil {
+		return err
+	}
+	if err := s.repo.ResetDelivery(ctx, webhookID, deliveryID, time.Now().Add(s.cfg.ClaimLease)); err != nil {
+		return err
+	}
+	s.enqueue(ctx, deliveryID)
+	return nil
+}
+
+// アウトボックスのイベントからWebhookの配信を作成する(OutboxHandler としてアウトボックスのリレーに登録する)
+func (s *WebhookService) HandleOutboxEvent(ctx context.Context, event models.OutboxEvent) error {
+	var ownerID int
+	switch event.Type {
+	case models.WebhookEventPostCreated, models.WebhookEventPostUpdated, models.WebhookEventPostDeleted:
+		// 投稿のイベントは投稿者のWebhookに送る
+		var post struct {
+			UserID int `json:"user_id"`
+		}
+		if err := json.Unmarshal(event.Payload, &post); err != nil {
+			return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to decode outbox event : EventID=%d", event.ID), err)
+		}
+		ownerID = post.UserID
+	case models.WebhookEventCommentCreated:
+		// コメントのイベントはコメントされた投稿の投稿者のWebhookに送る
+		var comment models.Comment
+		if err := json.Unmarshal(event.Payload, &comment); err != nil {
+			return apperror.NewAppError(apperror.TypeInternalServer, fmt.Sprintf("Failed to decode outbox event : EventID=%d", event.ID), err)
+		}
+		postOwnerID, err := s.postRepo.FindUserIDByPostID(ctx, comment.PostID)
+		if err != nil {
+			return ignoreNotFound(err)
+		}
+		ownerID = postOwnerID
+	default:
+		return nil
+	}
+
+	targets, err := s.repo.ListTargets(ctx, ownerID, event.Type)
+	if err != nil || len(targets) == 0 {
+		return err
+	}
+	payload, err := json.Marshal(models.WebhookPayload{Event: event.Type, CreatedAt: event.CreatedAt.UTC(), Data: event.Payload})
+	if err != nil {
+		return apperror.NewAppError(apperror.TypeInternalServer, "Failed to encode webhook payload : Event="+event.Type, err)
+	}
+
+	// 配信をDBに保存してからキューに入れる(キューが溢れても再送の定期処理で配信される)
+	var errs []error
+	for _, target := range targets {
+		deliveryID, created, err := s.repo.CreateDelivery(ctx, target.ID, event.ID, event.Type, payload, time.Now().Add(s.cfg.ClaimLease))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if created {
+			s.enqueue(ctx, deliveryID)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 配信を1件送信して結果を記録する(workerpool.WebhookDeliverer として配信ワーカープールから呼ばれる)
+func (s *WebhookService) Deliver(ctx context.Context, deliveryID int64) error {
+	delivery, target, err := s.repo.FindPendingDelivery(ctx, deliveryID)
+	if err != nil {
+		// 他のワーカーで配信済みの場合は何もしない
+		return ignoreNotFound(err)
+	}
+
+	statusCode, sendErr := s.send(ctx, delivery, target)
+	if sendErr == nil {
+		return s.repo.RecordAttempt(ctx, deliveryID, models.WebhookDeliveryStatusSucceeded, &statusCode, nil, nil)
+	}
+
+	// 失敗した場合は指数バックオフで再送し、上限を超えたら dead にする
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+	errMsg := sendErr.Error()
+	if len(errMsg) > maxWebhookErrorLength {
+		errMsg = errMsg[:maxWebhookErrorLength]
+	}
+	attempts := delivery.Attempts + 1
+	if attempts >= s.cfg.MaxAttempts {
+		log.Printf("Webhook delivery dead-lettered : DeliveryID=%d WebhookID=%d : %s", deliveryID, target.ID, errMsg)
+		return s.repo.RecordAttempt(ctx, deliveryID, models.WebhookDeliveryStatusDead, code, &errMsg, nil)
+	}
+	nextAttemptAt := time.Now().Add(retryDelay(s.cfg.RetryBaseDelay, s.cfg.RetryMaxDelay, attempts))
+	return s.repo.RecordAttempt(ctx, deliveryID, models.WebhookDeliveryStatusPending, code, &errMsg, &nextAttemptAt)
+}
+
+// 再送時刻を過ぎた配信をキューに入れる(workerpool.PeriodicJob として定期的に実行する)
+func (s *WebhookService) ProcessDueDeliveries(ctx context.Context) error {
+	deliveryIDs, err := s.repo.ClaimDueDeliveries(ctx, s.cfg.ClaimLease, s.cfg.QueueSize)
+	if err != nil {
+		return err
+	}
+	for _, deliveryID := range deliveryIDs {
+		s.enqueue(ctx, deliveryID)
+	}
+	return nil
+}
+
+// 配信IDをキューに入れる(入れられなかった配信は webhook.claim_lease の後に再送の定期処理で拾う)
+func (s *WebhookService) enqueue(ctx context.Context, deliveryID int64) {
+	if err := s.pool.Enqueue(ctx, deliveryID); err != nil {
+		log.Printf("Failed to enqueue webhook delivery : DeliveryID=%d : %v", deliveryID, err)
+	}
+}
+
+// 署名を付けて送信先へPOSTする(2xx以外は失敗として扱う)
+func (s *WebhookService) send(ctx context.Context, delivery *models.WebhookDelivery, target *models.WebhookTarget) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "BlogApi-Webhook/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", SignWebhookPayload(target.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// 接続を再利用できるようにレスポンスボディを読み捨てる
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Webhookの署名を生成する
+// 送信先では X-Webhook-Timestamp と リクエストボディを "." でつないだ文字列のHMAC-SHA256を比較して検証する
+func SignWebhookPayload(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// 試行回数に応じた再送までの待ち時間(base から2倍ずつ増やし、maxDelay で打ち止めにする指数バックオフ)
+func retryDelay(base time.Duration, maxDelay time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return delay
+}
+
+// NotFoundのエラーは無視する(非同期処理の間に対象が削除された場合など)
+func ignoreNotFound(err error) error {
+	var appErr *apperror.AppError
+	if errors.As(err, &appErr) && appErr.Type == apperror.TypeNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,131 @@
+package validation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/models"
+)
+
+// 入力項目の検証結果を集める構造体
+// 最初のエラーで止めずにすべての項目を検証し、項目ごとに最初のエラーだけを記録する
+// 項目はJSON Pointer(RFC 6901)で指定する(例: /title、/events/1、リクエスト全体は "")
+type Validator struct {
+	fields []models.FieldError
+	failed map[string]bool
+}
+
+// 文字列の項目を検証するルール(メソッドをつなげて書き、前のルールでエラーになった場合は以降を検証しない)
+type StringRule struct {
+	v     *Validator
+	path  string
+	label string
+	value string
+}
+
+// Validator を作成する
+func New() *Validator {
+	return &Validator{failed: map[string]bool{}}
+}
+
+// 文字数を数える(バイト数ではなくUnicodeのコードポイント数で数える)
+func Length(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// JSON Pointer を組み立てる(文字列は "~" と "/" をエスケープし、数値は配列の添字として扱う)
+func Pointer(tokens ...any) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteByte('/')
+		switch t := token.(type) {
+		case int:
+			b.WriteString(strconv.Itoa(t))
+		default:
+			b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(fmt.Sprint(t)))
+		}
+	}
+	return b.String()
+}
+
+// 文字列の項目の検証を始める(label はメッセージに使う項目名)
+func (v *Validator) String(path, label, value string) *StringRule {
+	return &StringRule{v: v, path: path, label: label, value: value}
+}
+
+// 条件を満たさない場合にエラーを記録する(既にエラーがある項目は記録しない)
+func (v *Validator) Check(path string, ok bool, code, message string) {
+	if ok || v.failed[path] {
+		return
+	}
+	v.failed[path] = true
+	v.fields = append(v.fields, models.FieldError{Field: path, Code: code, Message: message})
+}
+
+// 項目にエラーが記録されていないか
+func (v *Validator) Valid() bool {
+	return len(v.fields) == 0
+}
+
+// 記録したエラーを返す
+func (v *Validator) Errors() []models.FieldError {
+	return v.fields
+}
+
+// 記録したエラーをまとめた AppError を返す(エラーが無い場合は nil)
+func (v *Validator) Err() *apperror.AppError {
+	if v.Valid() {
+		return nil
+	}
+	messages := make([]string, 0, len(v.fields))
+	for _, field := range v.fields {
+		messages = append(messages, field.Message)
+	}
+	return apperror.NewAppError(apperror.TypeBadRequest, strings.Join(messages, "; "), nil).
+		WithSubCode(apperror.CodeValidationFailed).
+		WithFields(v.fields...)
+}
+
+// 空(空白のみを含む)でないこと
+func (r *StringRule) Required() *StringRule {
+	r.v.Check(r.path, strings.TrimSpace(r.value) != "", apperror.FieldRequired, r.label+" is required")
+	return r
+}
+
+// 文字数が n 以上であること
+func (r *StringRule) MinLength(n int) *StringRule {
+	r.v.Check(r.path, Length(r.value) >= n, apperror.FieldTooShort, fmt.Sprintf("%s must be at least %d characters long", r.label, n))
+	return r
+}
+
+// 文字数が n 以下であること
+func (r *StringRule) MaxLength(n int) *StringRule {
+	r.v.Check(r.path, Length(r.value) <= n, apperror.FieldTooLong, fmt.Sprintf("%s must be %d characters or less", r.label, n))
+	return r
+}
+
+// いずれかの値であること
+func (r *StringRule) OneOf(values ...string) *StringRule {
+	ok := false
+	for _, value := range values {
+		if r.value == value {
+			ok = true
+			break
+		}
+	}
+	r.v.Check(r.path, ok, apperror.FieldInvalid, fmt.Sprintf("%s must be one of %s", r.label, strings.Join(values, ", ")))
+	return r
+}
+
+// 任意の条件を満たすこと(message にはクライアントに返す説明を書く)
+func (r *StringRule) Must(ok func(string) bool, message string) *StringRule {
+	// 前のルールでエラーになった場合は条件を評価しない
+	if r.v.failed[r.path] {
+		return r
+	}
+	r.v.Check(r.path, ok(r.value), apperror.FieldInvalid, message)
+	return r
+}
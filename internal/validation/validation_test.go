@@ -0,0 +1,101 @@
+package validation_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/apperror"
+	"github.com/yusuke-hoguro/BlogApi/internal/validation"
+)
+
+// すべての項目を検証し、項目ごとに最初のエラーだけを記録することを確認する
+func TestValidatorCollectsAllFields(t *testing.T) {
+	v := validation.New()
+	v.String("/title", "Title", " ").Required().MaxLength(3)
+	v.String("/content", "Content", "abcd").Required().MaxLength(3)
+	v.String("/name", "Name", "ok").Required().MaxLength(3)
+
+	fields := v.Errors()
+	if len(fields) != 2 {
+		t.Fatalf("errors = %+v", fields)
+	}
+	if fields[0].Field != "/title" || fields[0].Code != apperror.FieldRequired || fields[0].Message != "Title is required" {
+		t.Errorf("errors[0] = %+v", fields[0])
+	}
+	if fields[1].Field != "/content" || fields[1].Code != apperror.FieldTooLong || fields[1].Message != "Content must be 3 characters or less" {
+		t.Errorf("errors[1] = %+v", fields[1])
+	}
+
+	err := v.Err()
+	if err == nil || err.Type != apperror.TypeBadRequest || err.SubCode != apperror.CodeValidationFailed || len(err.Fields) != 2 {
+		t.Fatalf("Err() = %+v", err)
+	}
+	if err.PublicMessage() != "Title is required; Content must be 3 characters or less" {
+		t.Errorf("PublicMessage() = %q", err.PublicMessage())
+	}
+}
+
+// 文字数をバイト数ではなく文字数で数えることを確認する
+func TestStringRuleLength(t *testing.T) {
+	v := validation.New()
+	v.String("/a", "A", strings.Repeat("あ", 5)).MaxLength(5)
+	v.String("/b", "B", "🍣🍣").MinLength(2)
+	v.String("/c", "C", strings.Repeat("あ", 6)).MaxLength(5)
+	v.String("/d", "D", "ab").MinLength(3)
+
+	fields := v.Errors()
+	if len(fields) != 2 || fields[0].Field != "/c" || fields[1].Field != "/d" || fields[1].Code != apperror.FieldTooShort {
+		t.Errorf("errors = %+v", fields)
+	}
+}
+
+// OneOf・Must・Check の結果を確認する
+func TestStringRuleOneOfAndMust(t *testing.T) {
+	v := validation.New()
+	v.String("/mode", "Mode", "delete").OneOf("anonymize", "purge")
+	// 前のルールでエラーになった場合は Must を評価しない
+	v.String("/url", "URL", "").Required().Must(func(string) bool {
+		t.Error("Must が評価された")
+		return false
+	}, "URL is invalid")
+	v.Check("", false, apperror.FieldRequired, "At least one field is required")
+	v.Check("", false, apperror.FieldInvalid, "記録されない")
+
+	fields := v.Errors()
+	if len(fields) != 3 {
+		t.Fatalf("errors = %+v", fields)
+	}
+	if fields[0].Code != apperror.FieldInvalid || fields[0].Message != "Mode must be one of anonymize, purge" {
+		t.Errorf("errors[0] = %+v", fields[0])
+	}
+	if fields[2].Field != "" || fields[2].Code != apperror.FieldRequired {
+		t.Errorf("errors[2] = %+v", fields[2])
+	}
+}
+
+// エラーが無い場合は nil を返すことを確認する
+func TestValidatorValid(t *testing.T) {
+	v := validation.New()
+	v.String("/title", "Title", "タイトル").Required().MaxLength(100)
+	if !v.Valid() || v.Err() != nil {
+		t.Errorf("Valid() = %v, Err() = %v", v.Valid(), v.Err())
+	}
+}
+
+// JSON Pointer のエスケープと配列の添字を確認する
+func TestPointer(t *testing.T) {
+	tests := []struct {
+		tokens []any
+		want   string
+	}{
+		{[]any{"title"}, "/title"},
+		{[]any{"events", 2}, "/events/2"},
+		{[]any{"a/b", "m~n"}, "/a~1b/m~0n"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := validation.Pointer(tt.tokens...); got != tt.want {
+			t.Errorf("Pointer(%v) = %q, want %q", tt.tokens, got, tt.want)
+		}
+	}
+}
@@ -6,26 +6,43 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"time"
 )
 
 var ErrQueueFull = errors.New("job queue is full")
 var ErrQueueClosed = errors.New("job queue is closed")
 
+// 監視イベントごとの後続処理のタイムアウト
+const auditHandlerTimeout = 5 * time.Second
+
 // 監視イベントの構造体
 type AuditEvent struct {
-	Action string
-	UserID int
-	PostID int
+	Action       string
+	UserID       int
+	PostID       int
+	TargetUserID int // 操作対象のユーザー(フォローされたユーザーなど)
+	CommentID    int
+	APIKeyID     int    // APIキーで認証された操作の場合に使用したキー
+	APIKeyPrefix string // キーのプレフィックス(キーの一覧と突き合わせるために記録する)
 }
 
+// 監視イベントを受け取って後続処理(保存など)を行う関数
+type AuditHandler func(ctx context.Context, event AuditEvent) error
+
 // 監視ワーカープールの構造体
 type AuditWorkerPool struct {
 	jobCh       chan AuditEvent
 	workerCount int
-	stopOnce    sync.Once
+	handlers    []AuditHandler
+	abort       chan struct{} // 停止の期限を過ぎた場合に閉じる(ワーカーはキューの残りを処理せずに終了する)
+	abortOnce   sync.Once
 	wg          sync.WaitGroup
 	mu          sync.RWMutex
 	closed      bool
+	workerMu    sync.Mutex
+	workers     []chan struct{} // 起動中のワーカーごとの停止用チャンネル(Resizeで減らす場合に閉じる)
+	nextID      int
+	started     bool
 }
 
 // 新規監視ワーカープールの作成
@@ -33,14 +50,74 @@ func NewAuditWorkerPool(wokercount int, queueSize int) *AuditWorkerPool {
 	return &AuditWorkerPool{
 		jobCh:       make(chan AuditEvent, queueSize),
 		workerCount: wokercount,
+		abort:       make(chan struct{}),
 	}
 }
 
+// 監視イベントの後続処理を追加する(Startの前に呼び出す)
+func (p *AuditWorkerPool) AddHandler(handler AuditHandler) {
+	p.handlers = append(p.handlers, handler)
+}
+
 // 監視ワーカープールの開始
 func (p *AuditWorkerPool) Start() {
-	for i := 1; i <= p.workerCount; i++ {
+	p.workerMu.Lock()
+	defer p.workerMu.Unlock()
+	p.started = true
+	p.resize(p.workerCount)
+}
+
+// ワーカーの数を変更する(設定の再読み込みで使う)
+// 減らす場合は処理中のイベントを終えたワーカーから停止し、キューに残ったイベントは残りのワーカーが処理する
+func (p *AuditWorkerPool) Resize(workerCount int) {
+	if workerCount <= 0 {
+		return
+	}
+	p.workerMu.Lock()
+	defer p.workerMu.Unlock()
+	p.workerCount = workerCount
+	// 開始前は Start で、停止後はワーカーを起動しない
+	p.mu.RLock()
+	closed := p.closed
+	p.mu.RUnlock()
+	if !p.started || closed {
+		return
+	}
+	p.resize(workerCount)
+}
+
+// 起動中のワーカーの数を返す
+func (p *AuditWorkerPool) WorkerCount() int {
+	p.workerMu.Lock()
+	defer p.workerMu.Unlock()
+	if !p.started {
+		return 0
+	}
+	return len(p.workers)
+}
+
+// イベントを処理できる状態か返す(開始済みで停止していない場合にtrue)
+func (p *AuditWorkerPool) Running() bool {
+	p.workerMu.Lock()
+	defer p.workerMu.Unlock()
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.started && !p.closed
+}
+
+// ワーカーを起動・停止して指定した数にする(workerMuを取得して呼び出す)
+func (p *AuditWorkerPool) resize(workerCount int) {
+	for len(p.workers) < workerCount {
+		p.nextID++
+		quit := make(chan struct{})
+		p.workers = append(p.workers, quit)
 		p.wg.Add(1)
-		go p.worker(i)
+		go p.worker(p.nextID, quit)
+	}
+	for len(p.workers) > workerCount {
+		last := len(p.workers) - 1
+		close(p.workers[last])
+		p.workers = p.workers[:last]
 	}
 }
 
@@ -66,31 +143,75 @@ func (p *AuditWorkerPool) Enqueue(ctx context.Context, event AuditEvent) error {
 	}
 }
 
-// 監視ワーカープールの停止
+// 監視ワーカープールの停止(キューに残っているイベントをすべて処理するまで待つ)
 func (p *AuditWorkerPool) Stop() {
-	p.stopOnce.Do(func() {
-		p.mu.Lock()
-		if !p.closed {
-			p.closed = true
-			close(p.jobCh)
-		}
-		p.mu.Unlock()
+	p.Shutdown(context.Background())
+}
+
+// 監視ワーカープールを停止する
+// キューに残っているイベントを処理し終えるまで待ち、ctx の期限を過ぎた場合は残りのイベントを破棄してその件数を返す
+// (処理中のイベントは後続処理のタイムアウトまで処理を続ける)
+func (p *AuditWorkerPool) Shutdown(ctx context.Context) (dropped int, err error) {
+	// Resizeと同時に呼ばれても停止後にワーカーを起動しないようにする
+	p.workerMu.Lock()
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobCh)
+	}
+	p.mu.Unlock()
+	p.workerMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
 		p.wg.Wait()
-	})
+		close(done)
+	}()
+	select {
+	case <-done:
+		return 0, nil
+	case <-ctx.Done():
+	}
+
+	// ワーカーに残りのイベントを取らせずに、キューに残ったイベントを数えて破棄する
+	p.abortOnce.Do(func() { close(p.abort) })
+	for event := range p.jobCh {
+		dropped++
+		log.Printf("audit worker pool: dropped event on shutdown (%s)", event)
+	}
+	return dropped, ctx.Err()
 }
 
 // ワーカーの処理ループ
-func (p *AuditWorkerPool) worker(id int) {
+func (p *AuditWorkerPool) worker(id int, quit <-chan struct{}) {
 	defer p.wg.Done()
 
-	for event := range p.jobCh {
-		// イベントの処理を実施
-		if err := processAuditEvent(id, event); err != nil {
-			log.Printf("audit worker %d: failed to process event: %v", id, err)
+	for {
+		// 停止の期限を過ぎた場合はキューにイベントが残っていても終了する
+		select {
+		case <-p.abort:
+			return
+		default:
 		}
-	}
 
-	log.Printf("audit worker %d: job channel closed", id)
+		select {
+		case <-quit:
+			log.Printf("audit worker %d: stopped by resize", id)
+			return
+		case <-p.abort:
+			return
+		case event, ok := <-p.jobCh:
+			if !ok {
+				log.Printf("audit worker %d: job channel closed", id)
+				return
+			}
+			// イベントの処理を実施
+			if err := processAuditEvent(id, event); err != nil {
+				log.Printf("audit worker %d: failed to process event: %v", id, err)
+			}
+			p.runHandlers(id, event)
+		}
+	}
 }
 
 // 監視イベントの処理関数
@@ -100,7 +221,28 @@ func processAuditEvent(workerID int, event AuditEvent) error {
 	return nil
 }
 
+// 登録された後続処理を順番に実行する(失敗してもログを出して次の処理を続ける)
+func (p *AuditWorkerPool) runHandlers(workerID int, event AuditEvent) {
+	for _, handler := range p.handlers {
+		ctx, cancel := context.WithTimeout(context.Background(), auditHandlerTimeout)
+		if err := handler(ctx, event); err != nil {
+			log.Printf("audit worker %d: handler failed: %v (%s)", workerID, err, event)
+		}
+		cancel()
+	}
+}
+
 // 監視イベントを文字列に変換する関数
 func (e AuditEvent) String() string {
-	return fmt.Sprintf("action=%s user_id=%d post_id=%d", e.Action, e.UserID, e.PostID)
+	s := fmt.Sprintf("action=%s user_id=%d post_id=%d", e.Action, e.UserID, e.PostID)
+	if e.TargetUserID != 0 {
+		s += fmt.Sprintf(" target_user_id=%d", e.TargetUserID)
+	}
+	if e.CommentID != 0 {
+		s += fmt.Sprintf(" comment_id=%d", e.CommentID)
+	}
+	if e.APIKeyID != 0 {
+		s += fmt.Sprintf(" api_key_id=%d api_key_prefix=%s", e.APIKeyID, e.APIKeyPrefix)
+	}
+	return s
 }
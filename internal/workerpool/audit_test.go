@@ -0,0 +1,105 @@
+package workerpool_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yusuke-hoguro/BlogApi/internal/workerpool"
+)
+
+// ワーカーの数を増減してもキューのイベントがすべて処理されることを確認する
+func TestAuditWorkerPoolResize(t *testing.T) {
+	pool := workerpool.NewAuditWorkerPool(2, 100)
+	var processed atomic.Int32
+	pool.AddHandler(func(ctx context.Context, event workerpool.AuditEvent) error {
+		processed.Add(1)
+		return nil
+	})
+
+	// 開始前の変更は Start で反映される
+	pool.Resize(3)
+	if got := pool.WorkerCount(); got != 0 || pool.Running() {
+		t.Errorf("開始前にワーカーが起動している: %d", got)
+	}
+	pool.Start()
+	if !pool.Running() {
+		t.Error("開始後に Running が false になる")
+	}
+	if got := pool.WorkerCount(); got != 3 {
+		t.Errorf("ワーカーの数が想定と異なる: get %d, want 3", got)
+	}
+
+	for _, size := range []int{5, 1, 0} {
+		pool.Resize(size)
+		for i := 0; i < 10; i++ {
+			if err := pool.Enqueue(context.Background(), workerpool.AuditEvent{Action: "test"}); err != nil {
+				t.Fatal("イベントの追加失敗:", err)
+			}
+		}
+	}
+	// 0以下の指定は無視する
+	if got := pool.WorkerCount(); got != 1 {
+		t.Errorf("ワーカーの数が想定と異なる: get %d, want 1", got)
+	}
+
+	pool.Stop()
+	if pool.Running() {
+		t.Error("停止後に Running が true になる")
+	}
+	if got := processed.Load(); got != 30 {
+		t.Errorf("処理されたイベントの数が想定と異なる: get %d, want 30", got)
+	}
+	// 停止後の変更ではワーカーを起動しない
+	pool.Resize(4)
+	if err := pool.Enqueue(context.Background(), workerpool.AuditEvent{}); err != workerpool.ErrQueueClosed {
+		t.Errorf("停止後のイベントの追加がエラーにならない: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		pool.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("停止が完了しない")
+	}
+}
+
+// 停止の期限を過ぎた場合はキューに残ったイベントを破棄して件数を返すことを確認する
+func TestAuditWorkerPoolShutdownDeadline(t *testing.T) {
+	pool := workerpool.NewAuditWorkerPool(1, 10)
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	var processed atomic.Int32
+	pool.AddHandler(func(ctx context.Context, event workerpool.AuditEvent) error {
+		started <- struct{}{}
+		<-release
+		processed.Add(1)
+		return nil
+	})
+	pool.Start()
+	for i := 0; i < 5; i++ {
+		if err := pool.Enqueue(context.Background(), workerpool.AuditEvent{Action: "test"}); err != nil {
+			t.Fatal("イベントの追加失敗:", err)
+		}
+	}
+	// 1件目を処理中の状態で停止する
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	dropped, err := pool.Shutdown(ctx)
+	if err != context.DeadlineExceeded || dropped != 4 {
+		t.Errorf("破棄したイベントが想定と異なる: dropped=%d err=%v", dropped, err)
+	}
+
+	// 処理中のイベントは最後まで処理し、それ以降のイベントは処理しない
+	close(release)
+	pool.Stop()
+	if got := processed.Load(); got != 1 {
+		t.Errorf("処理されたイベントの数が想定と異なる: get %d, want 1", got)
+	}
+}
@@ -0,0 +1,67 @@
+package workerpool
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// 定期実行するジョブの構造体
+type PeriodicJob struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// 定期実行ジョブを管理する構造体
+type Scheduler struct {
+	jobs     []PeriodicJob
+	cancel   context.CancelFunc
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// 新規スケジューラーの作成
+func NewScheduler(jobs ...PeriodicJob) *Scheduler {
+	return &Scheduler{jobs: jobs}
+}
+
+// スケジューラーの開始(ジョブごとにgoroutineを起動する)
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.run(ctx, job)
+	}
+}
+
+// スケジューラーの停止(実行中のジョブの終了を待つ)
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() {
+		if s.cancel != nil {
+			s.cancel()
+		}
+		s.wg.Wait()
+	})
+}
+
+// ジョブの実行ループ
+func (s *Scheduler) run(ctx context.Context, job PeriodicJob) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("scheduler: job %s stopped", job.Name)
+			return
+		case <-ticker.C:
+			if err := job.Run(ctx); err != nil {
+				log.Printf("scheduler: job %s failed: %v", job.Name, err)
+			}
+		}
+	}
+}
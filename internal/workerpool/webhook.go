@@ -0,0 +1,93 @@
+package workerpool
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// 配信1件あたりの処理のタイムアウト(送信先へのリクエストとDBの更新を含む)
+const webhookDeliveryTimeout = 30 * time.Second
+
+// Webhookの配信を1件処理する関数
+type WebhookDeliverer func(ctx context.Context, deliveryID int64) error
+
+// Webhook配信ワーカープールの構造体
+// キューには配信IDだけを入れ、配信内容はDBから取得する(キューが溢れた配信は再送の定期処理で拾う)
+type WebhookWorkerPool struct {
+	jobCh       chan int64
+	workerCount int
+	deliver     WebhookDeliverer
+	stopOnce    sync.Once
+	wg          sync.WaitGroup
+	mu          sync.RWMutex
+	closed      bool
+}
+
+// 新規Webhook配信ワーカープールの作成
+func NewWebhookWorkerPool(workerCount int, queueSize int) *WebhookWorkerPool {
+	return &WebhookWorkerPool{
+		jobCh:       make(chan int64, queueSize),
+		workerCount: workerCount,
+	}
+}
+
+// Webhook配信ワーカープールの開始
+func (p *WebhookWorkerPool) Start(deliver WebhookDeliverer) {
+	p.deliver = deliver
+	for i := 1; i <= p.workerCount; i++ {
+		p.wg.Add(1)
+		go p.worker(i)
+	}
+}
+
+// Webhook配信ワーカープールのキューに配信IDを追加
+func (p *WebhookWorkerPool) Enqueue(ctx context.Context, deliveryID int64) error {
+	// 読み取り用のロック取得
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	// ワーカープールが停止している場合はエラーを返す
+	if p.closed {
+		return ErrQueueClosed
+	}
+
+	// ジョブチャンネルに配信IDを送信（非ブロッキング）
+	select {
+	case p.jobCh <- deliveryID:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Webhook配信ワーカープールの停止(キューに残っている配信を処理してから停止する)
+func (p *WebhookWorkerPool) Stop() {
+	p.stopOnce.Do(func() {
+		p.mu.Lock()
+		if !p.closed {
+			p.closed = true
+			close(p.jobCh)
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+	})
+}
+
+// ワーカーの処理ループ
+func (p *WebhookWorkerPool) worker(id int) {
+	defer p.wg.Done()
+
+	for deliveryID := range p.jobCh {
+		ctx, cancel := context.WithTimeout(context.Background(), webhookDeliveryTimeout)
+		if err := p.deliver(ctx, deliveryID); err != nil {
+			log.Printf("webhook worker %d: failed to deliver: delivery_id=%d: %v", id, deliveryID, err)
+		}
+		cancel()
+	}
+
+	log.Printf("webhook worker %d: job channel closed", id)
+}
@@ -0,0 +1,19 @@
+-- ベースラインのテーブル削除(すべてのデータが削除される)
+DROP TABLE IF EXISTS outbox_consumptions;
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS follows;
+DROP TABLE IF EXISTS data_exports;
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS account_deletions;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS oauth_states;
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS post_stats;
+DROP TABLE IF EXISTS likes;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS posts;
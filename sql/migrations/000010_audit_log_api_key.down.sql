@@ -0,0 +1,3 @@
+-- 監視イベントのAPIキーの列を削除
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS api_key_prefix;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS api_key_id;
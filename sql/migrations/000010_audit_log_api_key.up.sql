@@ -0,0 +1,4 @@
+-- APIキーで認証された操作を追跡できるように、監視イベントに使用したキーを記録する列を追加
+-- (キーの失効・削除後も記録を残すため外部キーは付けない)
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS api_key_id INTEGER;
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS api_key_prefix TEXT;
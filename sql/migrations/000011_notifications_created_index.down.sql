@@ -0,0 +1,3 @@
+-- 通知の一覧のインデックスを更新日時のインデックスに戻す
+DROP INDEX IF EXISTS idx_notifications_user_created;
+CREATE INDEX IF NOT EXISTS idx_notifications_user_updated ON notifications(user_id, updated_at DESC, id DESC);
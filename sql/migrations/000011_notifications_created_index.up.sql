@@ -0,0 +1,3 @@
+-- 通知の一覧を作成日時でページングするため、更新日時のインデックスを作成日時のインデックスに置き換える
+DROP INDEX IF EXISTS idx_notifications_user_updated;
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created ON notifications(user_id, created_at DESC, id DESC);
@@ -0,0 +1,10 @@
+// マイグレーションのSQLファイルをバイナリに埋め込むパッケージ
+// 実行ディレクトリに関係なく cmd/migrate・cmd/api・テストから同じマイグレーションを読み込める
+package migrations
+
+import "embed"
+
+// sql/migrations 配下のマイグレーションファイル
+//
+//go:embed *.sql
+var FS embed.FS